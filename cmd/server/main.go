@@ -2,10 +2,12 @@ package main
 
 import (
 	"context"
+	"fmt"
 	"log"
 	"net/http"
 	"os"
 	"os/signal"
+	"strconv"
 	"strings"
 	"syscall"
 	"time"
@@ -15,6 +17,7 @@ import (
 	"github.com/example/pr-ai-teammate/internal/github"
 	"github.com/example/pr-ai-teammate/internal/orchestrator"
 	"github.com/example/pr-ai-teammate/internal/storage"
+	"github.com/example/pr-ai-teammate/internal/vulndb"
 )
 
 func main() {
@@ -25,24 +28,63 @@ func main() {
 
 	githubToken := os.Getenv("GITHUB_TOKEN")
 	githubClient := github.NewClient(githubToken)
-	reviewer := ai.NewReviewer(
-		os.Getenv("OPENAI_API_KEY"),
-		os.Getenv("OPENAI_BASE_URL"),
-		os.Getenv("OPENAI_MODEL"),
-	)
 	store, err := storage.NewStore(context.Background(), os.Getenv("DATABASE_URL"))
 	if err != nil {
 		log.Fatalf("store error: %v", err)
 	}
-	orchestratorService := orchestrator.NewService(githubClient, reviewer, store)
+	// Without any provider the AI pass is skipped, along with fetching its
+	// context.
+	var reviewer orchestrator.Reviewer
+	if providers := aiProviders(); len(providers) > 0 {
+		for i, provider := range providers {
+			providers[i] = ai.WithResilience(provider, ai.DefaultRetryPolicy(), ai.NewCircuitBreaker(5, time.Minute))
+		}
+		aiReviewer := ai.NewReviewer(os.Getenv("AI_PROVIDER"), providers...)
+		cacheTTL, err := aiCacheTTL()
+		if err != nil {
+			log.Fatalf("cache error: %v", err)
+		}
+		if cacheTTL > 0 {
+			aiReviewer.SetCache(store, cacheTTL)
+		}
+		reviewer = aiReviewer
+	}
+	var options []orchestrator.Option
+	if source := os.Getenv("VULN_DB_PATH"); source != "" {
+		db := vulndb.New(source)
+		if stats, err := db.Refresh(); err != nil {
+			log.Printf("vulnerability database: %v", err)
+		} else {
+			log.Printf("vulnerability database: %d advisories for %d packages from %s", stats.Advisories, stats.Packages, stats.Source)
+		}
+		options = append(options, orchestrator.WithVulnerabilityDB(db))
+	}
+	if path := os.Getenv("AI_PRICE_TABLE"); path != "" {
+		prices, err := ai.LoadPriceTable(path)
+		if err != nil {
+			log.Fatalf("price table error: %v", err)
+		}
+		options = append(options, orchestrator.WithPriceTable(prices))
+	}
+	budget, err := aiBudget()
+	if err != nil {
+		log.Fatalf("budget error: %v", err)
+	}
+	options = append(options, orchestrator.WithAIBudget(budget))
+	orchestratorService := orchestrator.NewService(githubClient, reviewer, store, options...)
 	webhookSecret := os.Getenv("GITHUB_WEBHOOK_SECRET")
 	handlers := api.NewHandlers(orchestratorService, webhookSecret)
+	handlers.SetAdminToken(os.Getenv("ADMIN_TOKEN"))
 
 	mux := http.NewServeMux()
 	mux.HandleFunc("/health", methodGuard(handlers.Health, http.MethodGet, http.MethodHead))
 	mux.HandleFunc("/health/", methodGuard(handlers.Health, http.MethodGet, http.MethodHead))
 	mux.HandleFunc("/webhook/github", methodGuard(handlers.WebhookGitHub, http.MethodPost))
 	mux.HandleFunc("/analyze/pr", methodGuard(handlers.AnalyzePR, http.MethodPost))
+	mux.HandleFunc("/analyze/findings", methodGuard(handlers.IngestFindings, http.MethodPost))
+	mux.HandleFunc("/admin/vulndb/refresh", methodGuard(handlers.RefreshVulnDB, http.MethodPost))
+	mux.HandleFunc("/admin/ai/usage", methodGuard(handlers.AIUsage, http.MethodGet))
+	mux.HandleFunc("/metrics", methodGuard(handlers.Metrics, http.MethodGet))
 	mux.HandleFunc("/", notFoundHandler)
 
 	server := &http.Server{
@@ -72,6 +114,62 @@ func main() {
 	}
 }
 
+// aiProviders configures every LLM backend with credentials or an endpoint
+// in the environment. Repositories pick one by name in their config.
+func aiProviders() []ai.Provider {
+	var providers []ai.Provider
+	if key := strings.TrimSpace(os.Getenv("OPENAI_API_KEY")); key != "" {
+		providers = append(providers, ai.NewOpenAIProvider(key, os.Getenv("OPENAI_BASE_URL"), os.Getenv("OPENAI_MODEL")))
+	}
+	if endpoint := os.Getenv("AZURE_OPENAI_ENDPOINT"); endpoint != "" {
+		providers = append(providers, ai.NewAzureProvider(endpoint, os.Getenv("AZURE_OPENAI_DEPLOYMENT"), os.Getenv("AZURE_OPENAI_API_VERSION"), os.Getenv("AZURE_OPENAI_API_KEY")))
+	}
+	if key := strings.TrimSpace(os.Getenv("ANTHROPIC_API_KEY")); key != "" {
+		providers = append(providers, ai.NewAnthropicProvider(key, os.Getenv("ANTHROPIC_BASE_URL"), os.Getenv("ANTHROPIC_MODEL")))
+	}
+	if baseURL := os.Getenv("OLLAMA_BASE_URL"); baseURL != "" {
+		providers = append(providers, ai.NewOllamaProvider(baseURL, os.Getenv("OLLAMA_MODEL")))
+	}
+	if baseURL := os.Getenv("LLAMACPP_BASE_URL"); baseURL != "" {
+		providers = append(providers, ai.NewLlamaCppProvider(baseURL, os.Getenv("LLAMACPP_MODEL")))
+	}
+	return providers
+}
+
+// aiCacheTTL reads how long AI responses are reused (AI_CACHE_TTL, a Go
+// duration, default one week). "0" disables the cache.
+func aiCacheTTL() (time.Duration, error) {
+	value := os.Getenv("AI_CACHE_TTL")
+	if value == "" {
+		return 7 * 24 * time.Hour, nil
+	}
+	ttl, err := time.ParseDuration(value)
+	if err != nil || ttl < 0 {
+		return 0, fmt.Errorf("AI_CACHE_TTL must be a non-negative duration such as 72h, got %q", value)
+	}
+	return ttl, nil
+}
+
+// aiBudget reads the default monthly AI budget in US dollars
+// (AI_MONTHLY_BUDGET_USD) and per-repository overrides
+// (AI_REPO_BUDGETS="owner/repo=50,owner/other=10").
+func aiBudget() (ai.Budget, error) {
+	var budget ai.Budget
+	if value := os.Getenv("AI_MONTHLY_BUDGET_USD"); value != "" {
+		monthly, err := strconv.ParseFloat(value, 64)
+		if err != nil || monthly < 0 {
+			return ai.Budget{}, fmt.Errorf("AI_MONTHLY_BUDGET_USD must be a non-negative number, got %q", value)
+		}
+		budget.MonthlyUSD = monthly
+	}
+	repos, err := ai.ParseRepoBudgets(os.Getenv("AI_REPO_BUDGETS"))
+	if err != nil {
+		return ai.Budget{}, fmt.Errorf("AI_REPO_BUDGETS: %w", err)
+	}
+	budget.Repos = repos
+	return budget, nil
+}
+
 func methodGuard(handler http.HandlerFunc, methods ...string) http.HandlerFunc {
 	allowed := make(map[string]struct{}, len(methods))
 	for _, method := range methods {
@@ -0,0 +1,116 @@
+package ai
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+const anthropicVersion = "2023-06-01"
+
+// AnthropicProvider speaks the Anthropic Messages API.
+type AnthropicProvider struct {
+	apiKey  string
+	baseURL string
+	model   string
+	client  *http.Client
+}
+
+func NewAnthropicProvider(apiKey string, baseURL string, model string) *AnthropicProvider {
+	if baseURL == "" {
+		baseURL = "https://api.anthropic.com"
+	}
+	if model == "" {
+		model = "claude-3-5-sonnet-latest"
+	}
+	return &AnthropicProvider{
+		apiKey:  strings.TrimSpace(apiKey),
+		baseURL: strings.TrimRight(baseURL, "/"),
+		model:   model,
+		client:  newHTTPClient(),
+	}
+}
+
+func (p *AnthropicProvider) Name() string     { return "anthropic" }
+func (p *AnthropicProvider) Model() string    { return p.model }
+func (p *AnthropicProvider) SelfHosted() bool { return false }
+
+func (p *AnthropicProvider) Complete(ctx context.Context, request CompletionRequest) (CompletionResponse, error) {
+	maxTokens := request.MaxTokens
+	if maxTokens <= 0 {
+		// The Messages API requires max_tokens.
+		maxTokens = 4096
+	}
+	payload := anthropicRequest{
+		Model:       p.model,
+		System:      request.System,
+		Messages:    []chatMessage{{Role: "user", Content: request.Prompt}},
+		MaxTokens:   maxTokens,
+		Temperature: request.Temperature,
+	}
+	headers := map[string]string{
+		"x-api-key":         p.apiKey,
+		"anthropic-version": anthropicVersion,
+	}
+	resp, err := postJSON(ctx, p.client, p.baseURL+"/v1/messages", headers, payload)
+	if err != nil {
+		return CompletionResponse{}, err
+	}
+	if resp.Status >= 300 {
+		var failure anthropicResponse
+		_ = json.Unmarshal(resp.Body, &failure)
+		return CompletionResponse{}, resp.failed("anthropic", failure.Error.Message)
+	}
+
+	var response anthropicResponse
+	if err := json.Unmarshal(resp.Body, &response); err != nil {
+		return CompletionResponse{}, fmt.Errorf("anthropic: decode response: %w", err)
+	}
+	if response.Type == "error" {
+		return CompletionResponse{}, resp.failed("anthropic", response.Error.Message)
+	}
+	var text []string
+	for _, block := range response.Content {
+		if block.Type == "text" {
+			text = append(text, block.Text)
+		}
+	}
+	if len(text) == 0 {
+		return CompletionResponse{}, fmt.Errorf("anthropic returned no text content")
+	}
+	return CompletionResponse{
+		Content: strings.Join(text, ""),
+		Model:   response.Model,
+		Usage: Usage{
+			InputTokens:  response.Usage.InputTokens,
+			OutputTokens: response.Usage.OutputTokens,
+		},
+	}, nil
+}
+
+type anthropicRequest struct {
+	Model       string        `json:"model"`
+	System      string        `json:"system,omitempty"`
+	Messages    []chatMessage `json:"messages"`
+	MaxTokens   int           `json:"max_tokens"`
+	Temperature float32       `json:"temperature"`
+}
+
+type anthropicResponse struct {
+	Type    string `json:"type"`
+	Model   string `json:"model"`
+	Content []struct {
+		Type string `json:"type"`
+		Text string `json:"text"`
+	} `json:"content"`
+	Usage struct {
+		InputTokens  int `json:"input_tokens"`
+		OutputTokens int `json:"output_tokens"`
+	} `json:"usage"`
+	Error struct {
+		Type    string `json:"type"`
+		Message string `json:"message"`
+	} `json:"error"`
+}
@@ -0,0 +1,26 @@
+package ai
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"time"
+)
+
+// Cache stores completions by the hash of everything that shaped them.
+type Cache interface {
+	GetAIResponse(ctx context.Context, key string) (CompletionResponse, bool, error)
+	PutAIResponse(ctx context.Context, key string, response CompletionResponse, expiresAt time.Time) error
+}
+
+// cacheKey hashes the provider, model, prompt version and request. The
+// prompt holds the whole review input (title, description, findings with
+// their line numbers, documents, touched files and diff), so only reruns of
+// the same content hit: redeliveries, retries and unchanged reopened PRs.
+func cacheKey(provider Provider, version string, request CompletionRequest) string {
+	hash := sha256.New()
+	fmt.Fprintf(hash, "%s\x00%s\x00%s\x00%g\x00%d\x00", provider.Name(), provider.Model(), version, request.Temperature, request.MaxTokens)
+	fmt.Fprintf(hash, "%d:%s\x00%d:%s", len(request.System), request.System, len(request.Prompt), request.Prompt)
+	return hex.EncodeToString(hash.Sum(nil))
+}
@@ -0,0 +1,43 @@
+package ai
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+type mapCache map[string]CompletionResponse
+
+func (c mapCache) GetAIResponse(ctx context.Context, key string) (CompletionResponse, bool, error) {
+	response, ok := c[key]
+	return response, ok, nil
+}
+
+func (c mapCache) PutAIResponse(ctx context.Context, key string, response CompletionResponse, expiresAt time.Time) error {
+	c[key] = response
+	return nil
+}
+
+func TestReviewerServesRepeatedPromptsFromCache(t *testing.T) {
+	provider := &scriptedProvider{}
+	reviewer := NewReviewer("", provider)
+	reviewer.SetCache(mapCache{}, time.Hour)
+	input := ReviewInput{Title: "Add cache", Diff: "diff --git a/cache.go b/cache.go"}
+
+	first, err := reviewer.Review(context.Background(), input)
+	if err != nil || first.Calls[0].Cached {
+		t.Fatalf("expected a provider call, got %+v, %v", first, err)
+	}
+	second, err := reviewer.Review(context.Background(), input)
+	if err != nil || !second.Calls[0].Cached || second.Summary != first.Summary || provider.calls != 1 {
+		t.Fatalf("expected a cache hit, got %+v after %d calls (%v)", second, provider.calls, err)
+	}
+	if second.Calls[0].Model != "scripted-1" {
+		t.Fatalf("expected the configured model name, got %q", second.Calls[0].Model)
+	}
+
+	input.Diff += "\n+changed"
+	if third, _ := reviewer.Review(context.Background(), input); third.Calls[0].Cached || provider.calls != 2 {
+		t.Fatalf("expected a changed diff to miss the cache")
+	}
+}
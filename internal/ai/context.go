@@ -0,0 +1,145 @@
+package ai
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/example/pr-ai-teammate/internal/analysis"
+)
+
+// DefaultContextTokens is the context budget used when ReviewInput.MaxTokens
+// is zero.
+const DefaultContextTokens = 12000
+
+// minSectionTokens is the smallest remainder worth truncating a document or
+// file into; below it the item is omitted instead.
+const minSectionTokens = 200
+
+// Document is a file shown to the reviewer in full: a repository document
+// such as README.md, or a file touched by the PR.
+type Document struct {
+	Path    string
+	Content string
+	// ChangedLines orders touched files; files with more changes come first.
+	ChangedLines int
+}
+
+// EstimateTokens approximates the token count of text at four bytes per
+// token, which is close enough for budgeting.
+func EstimateTokens(text string) int {
+	return (len(text) + 3) / 4
+}
+
+// promptContext is the part of ReviewInput that fits in the budget.
+type promptContext struct {
+	findings  []string
+	documents []Document
+	files     []Document
+	omitted   []string
+}
+
+// fitContext fills the budget in a fixed order: findings (most severe
+// first), repository documents in configured order, then touched files with
+// the most changed lines first. An item that does not fit is truncated when
+// enough budget remains, otherwise omitted; later items may still fit.
+func fitContext(input ReviewInput) promptContext {
+	budget := input.MaxTokens
+	if budget <= 0 {
+		budget = DefaultContextTokens
+	}
+	var result promptContext
+
+	for _, line := range findingLines(input.Findings) {
+		cost := EstimateTokens(line) + 1
+		if cost > budget {
+			result.omitted = append(result.omitted, fmt.Sprintf("%d finding(s)", len(input.Findings)-len(result.findings)))
+			break
+		}
+		budget -= cost
+		result.findings = append(result.findings, line)
+	}
+
+	take := func(document Document) (Document, bool) {
+		if document.Content == "" {
+			return document, false
+		}
+		cost := EstimateTokens(document.Content)
+		if cost <= budget {
+			budget -= cost
+			return document, true
+		}
+		if budget < minSectionTokens {
+			result.omitted = append(result.omitted, document.Path)
+			return document, false
+		}
+		cut := budget * 4
+		if newline := strings.LastIndex(document.Content[:cut], "\n"); newline > 0 {
+			cut = newline
+		}
+		document.Content = document.Content[:cut] + "\n...truncated..."
+		budget = 0
+		return document, true
+	}
+	for _, document := range input.Documents {
+		if fitted, ok := take(document); ok {
+			result.documents = append(result.documents, fitted)
+		}
+	}
+	files := append([]Document(nil), input.Files...)
+	sort.SliceStable(files, func(i, j int) bool {
+		if files[i].ChangedLines != files[j].ChangedLines {
+			return files[i].ChangedLines > files[j].ChangedLines
+		}
+		return files[i].Path < files[j].Path
+	})
+	for _, file := range files {
+		if fitted, ok := take(file); ok {
+			result.files = append(result.files, fitted)
+		}
+	}
+	return result
+}
+
+var severityOrder = map[string]int{"high": 0, "medium": 1, "low": 2}
+
+func findingLines(issues []analysis.Issue) []string {
+	sorted := append([]analysis.Issue(nil), issues...)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		a, b := sorted[i], sorted[j]
+		if rankA, rankB := severityRank(a.Severity), severityRank(b.Severity); rankA != rankB {
+			return rankA < rankB
+		}
+		if a.File != b.File {
+			return a.File < b.File
+		}
+		return a.Line < b.Line
+	})
+	lines := make([]string, 0, len(sorted))
+	for _, issue := range sorted {
+		location := issue.File
+		if issue.Line > 0 {
+			location = fmt.Sprintf("%s:%d", issue.File, issue.Line)
+		}
+		if location == "" {
+			location = "PR"
+		}
+		lines = append(lines, fmt.Sprintf("- %s [%s] %s: %s", location, issue.Severity, issue.RuleID, issue.Message))
+	}
+	return lines
+}
+
+func severityRank(severity string) int {
+	if rank, ok := severityOrder[strings.ToLower(severity)]; ok {
+		return rank
+	}
+	return len(severityOrder)
+}
+
+func fencedDocuments(nonce string, documents []Document) string {
+	var builder strings.Builder
+	for _, document := range documents {
+		fmt.Fprintf(&builder, "\n=== %s ===\n%s", document.Path, fenced(nonce, document.Path, document.Content))
+	}
+	return builder.String()
+}
@@ -0,0 +1,90 @@
+package ai
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/example/pr-ai-teammate/internal/analysis"
+)
+
+func TestBuildPromptIncludesContext(t *testing.T) {
+	input := ReviewInput{
+		Title: "Add caching",
+		Body:  "Caches user lookups.",
+		Findings: []analysis.Issue{
+			{File: "cache.go", Line: 12, RuleID: "todo", Severity: "low", Message: "TODO left in code"},
+			{File: "cache.go", Line: 3, RuleID: "sql-injection", Severity: "high", Message: "Query built with fmt.Sprintf"},
+		},
+		Documents: []Document{{Path: "CONTRIBUTING.md", Content: "Wrap errors with fmt.Errorf.\n"}},
+		Files:     []Document{{Path: "cache.go", Content: "package cache\n", ChangedLines: 4}},
+	}
+	prompts, err := NewPrompts(PromptConfig{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, prompt, err := buildPrompt(prompts, "", input, "diff --git a/cache.go b/cache.go", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, want := range []string{
+		"source=\"pr-title\">\nAdd caching\n</untrusted-",
+		"- cache.go:3 [high] sql-injection: Query built with fmt.Sprintf\n- cache.go:12 [low] todo",
+		"=== CONTRIBUTING.md ===\n<untrusted-",
+		"source=\"CONTRIBUTING.md\">\nWrap errors with fmt.Errorf.\n</untrusted-",
+		"source=\"cache.go\">\npackage cache\n</untrusted-",
+		"source=\"diff\">\ndiff --git a/cache.go b/cache.go\n</untrusted-",
+	} {
+		if !strings.Contains(prompt, want) {
+			t.Fatalf("prompt is missing %q:\n%s", want, prompt)
+		}
+	}
+	if strings.Contains(prompt, "Omitted") {
+		t.Fatalf("nothing should be omitted within the default budget:\n%s", prompt)
+	}
+}
+
+func TestFitContextPrioritizesWithinBudget(t *testing.T) {
+	lines := func(n int) string {
+		return strings.Repeat("0123456789abcdef\n", n)
+	}
+	input := ReviewInput{
+		MaxTokens: 1000,
+		Findings:  []analysis.Issue{{File: "a.go", Line: 1, RuleID: "todo", Severity: "low", Message: "TODO"}},
+		Documents: []Document{
+			{Path: "README.md", Content: lines(100)},
+			{Path: "CONTRIBUTING.md", Content: lines(200)},
+		},
+		Files: []Document{
+			{Path: "small.go", Content: lines(2), ChangedLines: 1},
+			{Path: "big.go", Content: lines(400), ChangedLines: 50},
+			{Path: "also-small.go", Content: lines(2), ChangedLines: 1},
+		},
+	}
+
+	first := fitContext(input)
+	second := fitContext(input)
+	if strings.Join(first.omitted, ",") != strings.Join(second.omitted, ",") {
+		t.Fatalf("fitting is not deterministic: %v vs %v", first.omitted, second.omitted)
+	}
+
+	if len(first.findings) != 1 || len(first.documents) != 2 {
+		t.Fatalf("expected the finding and both documents, got %+v", first)
+	}
+	if !strings.HasSuffix(first.documents[1].Content, "...truncated...") {
+		t.Fatalf("expected CONTRIBUTING.md to be truncated to the remaining budget")
+	}
+	if len(first.files) != 0 || strings.Join(first.omitted, ",") != "big.go,also-small.go,small.go" {
+		t.Fatalf("expected touched files omitted in priority order, got files %+v omitted %v", first.files, first.omitted)
+	}
+
+	input.MaxTokens = 3000
+	fitted := fitContext(input)
+	var paths []string
+	for _, file := range fitted.files {
+		paths = append(paths, file.Path)
+	}
+	if strings.Join(paths, ",") != "big.go,also-small.go" || strings.Join(fitted.omitted, ",") != "small.go" {
+		t.Fatalf("expected the most changed file first, got %v (omitted %v)", paths, fitted.omitted)
+	}
+}
@@ -0,0 +1,246 @@
+package ai
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/example/pr-ai-teammate/internal/analysis"
+)
+
+// PR titles, descriptions, diffs and repository files are written by
+// whoever opened the pull request, so the model must treat them as data.
+// They are fenced in tags the author cannot close, the model is told so,
+// instruction-like text is reported, and the reply is checked before any
+// of it is posted.
+
+// injectionPhrases are common attempts to steer a reviewer model. They are
+// only matched against prose: the PR title and description, markdown and
+// text files, and comments in code.
+var injectionPhrases = []struct {
+	name    string
+	pattern *regexp.Regexp
+}{
+	{"override instructions", regexp.MustCompile(`(?i)\b(ignore|disregard|forget|override)\b.{0,20}\b(previous|prior|above|earlier|preceding|all|your)\b.{0,20}\b(instructions?|prompts?|directions)\b`)},
+	{"new instructions", regexp.MustCompile(`(?i)\b(new|updated|real) (system )?instructions\s*:`)},
+	{"role change", regexp.MustCompile(`(?i)\bpretend (to be|you are)\b`)},
+	{"prompt extraction", regexp.MustCompile(`(?i)\b(reveal|print|show|repeat|output) (your|the) (system prompt|instructions)\b`)},
+	{"approval request", regexp.MustCompile(`(?i)\b(please|you (must|should|will)) (approve|merge|lgtm)\b|\bapprove (this|the) (pr|pull request)\b|\bmark (this|it) as approved\b`)},
+	{"suppression request", regexp.MustCompile(`(?i)\b(do not|don't|never) (report|flag|mention|comment on) (any|this|these|the) ?(security )?(issues?|findings?|problems?|vulnerabilit(y|ies))\b`)},
+	{"role tag", regexp.MustCompile(`(?i)\[/?INST\]|<\|im_(start|end)\|>|</?untrusted`)},
+}
+
+// proseExtensions are files whose every line is prose.
+var proseExtensions = []string{".md", ".markdown", ".txt", ".rst", ".adoc"}
+
+// commentPrefixes start a comment line in the languages reviewed most often.
+var commentPrefixes = []string{"//", "#", "/*", "*", "--", ";", "<!--"}
+
+// detectInjection reports instruction-like text in the title, description
+// and added prose and comment lines. Hits in the diff are placed on their
+// line; the others have no file and go in the summary.
+func detectInjection(input ReviewInput) []analysis.Issue {
+	var issues []analysis.Issue
+	for _, field := range []struct{ name, text string }{{"title", input.Title}, {"description", input.Body}} {
+		if name := matchInjection(field.text); name != "" {
+			issues = append(issues, injectionIssue("", 0, fmt.Sprintf("The PR %s contains text that looks like an instruction to the AI reviewer (%s).", field.name, name)))
+		}
+	}
+	files, _ := analysis.ParseUnifiedDiff(input.Diff)
+	for _, file := range files {
+		for _, line := range file.AddedLines {
+			if name := matchInjection(proseText(file.Path, line.Content)); name != "" {
+				issues = append(issues, injectionIssue(file.Path, line.Number, fmt.Sprintf("This line looks like an instruction to the AI reviewer (%s).", name)))
+			}
+		}
+	}
+	return issues
+}
+
+// proseText returns the part of a line written for people: the whole line in
+// prose files and comment lines, a trailing comment, or nothing.
+func proseText(path, line string) string {
+	lower := strings.ToLower(path)
+	for _, extension := range proseExtensions {
+		if strings.HasSuffix(lower, extension) {
+			return line
+		}
+	}
+	trimmed := strings.TrimSpace(line)
+	for _, prefix := range commentPrefixes {
+		if strings.HasPrefix(trimmed, prefix) {
+			return trimmed
+		}
+	}
+	for _, marker := range []string{" //", " #", "/*", "<!--"} {
+		if i := strings.Index(line, marker); i >= 0 {
+			return line[i:]
+		}
+	}
+	return ""
+}
+
+func matchInjection(text string) string {
+	if text == "" {
+		return ""
+	}
+	for _, phrase := range injectionPhrases {
+		if phrase.pattern.MatchString(text) {
+			return phrase.name
+		}
+	}
+	return ""
+}
+
+func injectionIssue(file string, line int, message string) analysis.Issue {
+	return analysis.Issue{
+		File:       file,
+		Line:       line,
+		RuleID:     "prompt-injection",
+		Severity:   "high",
+		Message:    message,
+		Suggestion: "Remove text addressed to automated reviewers; a human reviewer should check this change.",
+		Source:     "ai",
+	}
+}
+
+// fenceNonce derives the tag suffix from the untrusted content itself, so
+// the author cannot predict it without changing it, and identical prompts
+// still share a cache key.
+func fenceNonce(parts ...string) string {
+	hash := sha256.New()
+	for _, part := range parts {
+		fmt.Fprintf(hash, "%d:%s\x00", len(part), part)
+	}
+	return hex.EncodeToString(hash.Sum(nil))[:12]
+}
+
+func fenced(nonce, source, content string) string {
+	return fmt.Sprintf("<untrusted-%s source=%q>\n%s\n</untrusted-%s>\n", nonce, source, strings.TrimRight(content, "\n"), nonce)
+}
+
+// reviewReply is the JSON shape the model is asked to answer in.
+type reviewReply struct {
+	Summary  string         `json:"summary"`
+	Findings []replyFinding `json:"findings"`
+}
+
+type replyFinding struct {
+	File       string `json:"file"`
+	Line       int    `json:"line"`
+	Severity   string `json:"severity"`
+	Message    string `json:"message"`
+	Suggestion string `json:"suggestion"`
+}
+
+const replyFormat = `Answer with a single JSON object and nothing else:
+{"summary": "overall assessment in markdown", "findings": [{"file": "path", "line": 12, "severity": "low|medium|high", "message": "why it matters", "suggestion": "concrete improvement"}]}
+Only report findings on lines added in the diff, using the new file's line numbers.`
+
+// parseReply reads the model's JSON answer. Models sometimes wrap it in a
+// code fence or answer in prose; prose becomes the summary.
+func parseReply(content string) reviewReply {
+	var reply reviewReply
+	if err := decodeReply(content, &reply); err != nil {
+		return reviewReply{Summary: strings.TrimSpace(content)}
+	}
+	return reply
+}
+
+// decodeReply unmarshals a JSON reply, with or without a code fence.
+func decodeReply(content string, v any) error {
+	text := strings.TrimSpace(content)
+	if strings.HasPrefix(text, "```") {
+		text = strings.TrimPrefix(text, "```json")
+		text = strings.TrimPrefix(text, "```")
+		text = strings.TrimSuffix(strings.TrimSpace(text), "```")
+	}
+	return json.Unmarshal([]byte(text), v)
+}
+
+var (
+	approvalPattern = regexp.MustCompile(`(?i)\b(lgtm|looks good to me|(i|we) (hereby )?approve|approv(e|ed|ing) (this|the) (pr|pull request|change)|(ready|safe|good|fine) to (merge|ship)|ship it)\b`)
+	linkPattern     = regexp.MustCompile(`(?i)\bhttps?://[^\s<>()\[\]"']+`)
+	// allowedLinkHosts may be linked from reviews, with their subdomains.
+	allowedLinkHosts = []string{"github.com"}
+)
+
+// validateReply turns a reply into issues and a summary that are safe to
+// post: approval language is removed, links outside allowedLinkHosts are
+// stripped and findings must point at a line added in diff. Findings are
+// labelled with ruleID. It returns the number of findings dropped.
+func validateReply(reply reviewReply, diff, ruleID string) ([]analysis.Issue, string, int) {
+	added := map[string]map[int]bool{}
+	files, _ := analysis.ParseUnifiedDiff(diff)
+	for _, file := range files {
+		added[file.Path] = map[int]bool{}
+		for _, line := range file.AddedLines {
+			added[file.Path][line.Number] = true
+		}
+	}
+
+	var issues []analysis.Issue
+	dropped := 0
+	for _, finding := range reply.Findings {
+		if !added[finding.File][finding.Line] || strings.TrimSpace(finding.Message) == "" ||
+			approvalPattern.MatchString(finding.Message) || approvalPattern.MatchString(finding.Suggestion) {
+			dropped++
+			continue
+		}
+		issues = append(issues, analysis.Issue{
+			File:       finding.File,
+			Line:       finding.Line,
+			RuleID:     ruleID,
+			Severity:   normalizeSeverity(finding.Severity),
+			Message:    stripLinks(strings.TrimSpace(finding.Message)),
+			Suggestion: stripLinks(strings.TrimSpace(finding.Suggestion)),
+			Source:     "ai",
+		})
+	}
+	sort.SliceStable(issues, func(i, j int) bool {
+		if issues[i].File != issues[j].File {
+			return issues[i].File < issues[j].File
+		}
+		return issues[i].Line < issues[j].Line
+	})
+
+	var summary []string
+	for _, line := range strings.Split(reply.Summary, "\n") {
+		if approvalPattern.MatchString(line) {
+			continue
+		}
+		summary = append(summary, stripLinks(line))
+	}
+	return issues, strings.TrimSpace(strings.Join(summary, "\n")), dropped
+}
+
+func normalizeSeverity(severity string) string {
+	switch severity = strings.ToLower(strings.TrimSpace(severity)); severity {
+	case "low", "medium", "high":
+		return severity
+	case "critical":
+		return "high"
+	default:
+		return "medium"
+	}
+}
+
+func stripLinks(text string) string {
+	return linkPattern.ReplaceAllStringFunc(text, func(link string) string {
+		parsed, err := url.Parse(link)
+		if err == nil {
+			host := strings.ToLower(parsed.Hostname())
+			for _, allowed := range allowedLinkHosts {
+				if host == allowed || strings.HasSuffix(host, "."+allowed) {
+					return link
+				}
+			}
+		}
+		return "[link removed]"
+	})
+}
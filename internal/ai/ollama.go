@@ -0,0 +1,91 @@
+package ai
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// OllamaProvider talks to a self-hosted Ollama server through its native
+// /api/chat endpoint.
+type OllamaProvider struct {
+	baseURL string
+	model   string
+	client  *http.Client
+}
+
+func NewOllamaProvider(baseURL string, model string) *OllamaProvider {
+	if baseURL == "" {
+		baseURL = "http://localhost:11434"
+	}
+	if model == "" {
+		model = "llama3.1"
+	}
+	return &OllamaProvider{
+		baseURL: strings.TrimRight(baseURL, "/"),
+		model:   model,
+		client:  newHTTPClient(),
+	}
+}
+
+func (p *OllamaProvider) Name() string     { return "ollama" }
+func (p *OllamaProvider) Model() string    { return p.model }
+func (p *OllamaProvider) SelfHosted() bool { return true }
+
+func (p *OllamaProvider) Complete(ctx context.Context, request CompletionRequest) (CompletionResponse, error) {
+	payload := ollamaRequest{
+		Model: p.model,
+		Messages: []chatMessage{
+			{Role: "system", Content: request.System},
+			{Role: "user", Content: request.Prompt},
+		},
+		Stream: false,
+	}
+	payload.Options.Temperature = request.Temperature
+	payload.Options.NumPredict = request.MaxTokens
+	resp, err := postJSON(ctx, p.client, p.baseURL+"/api/chat", nil, payload)
+	if err != nil {
+		return CompletionResponse{}, err
+	}
+	if resp.Status >= 300 {
+		var failure ollamaResponse
+		_ = json.Unmarshal(resp.Body, &failure)
+		return CompletionResponse{}, resp.failed("ollama", failure.Error)
+	}
+
+	var response ollamaResponse
+	if err := json.Unmarshal(resp.Body, &response); err != nil {
+		return CompletionResponse{}, fmt.Errorf("ollama: decode response: %w", err)
+	}
+	if response.Error != "" {
+		return CompletionResponse{}, resp.failed("ollama", response.Error)
+	}
+	return CompletionResponse{
+		Content: response.Message.Content,
+		Model:   response.Model,
+		Usage: Usage{
+			InputTokens:  response.PromptEvalCount,
+			OutputTokens: response.EvalCount,
+		},
+	}, nil
+}
+
+type ollamaRequest struct {
+	Model    string        `json:"model"`
+	Messages []chatMessage `json:"messages"`
+	Stream   bool          `json:"stream"`
+	Options  struct {
+		Temperature float32 `json:"temperature"`
+		NumPredict  int     `json:"num_predict,omitempty"`
+	} `json:"options"`
+}
+
+type ollamaResponse struct {
+	Model           string      `json:"model"`
+	Message         chatMessage `json:"message"`
+	PromptEvalCount int         `json:"prompt_eval_count"`
+	EvalCount       int         `json:"eval_count"`
+	Error           string      `json:"error"`
+}
@@ -0,0 +1,132 @@
+package ai
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// OpenAIProvider speaks the chat-completions format used by OpenAI, Azure
+// OpenAI deployments and OpenAI-compatible local servers such as llama.cpp.
+type OpenAIProvider struct {
+	name       string
+	url        string
+	model      string
+	headers    map[string]string
+	selfHosted bool
+	client     *http.Client
+}
+
+func NewOpenAIProvider(apiKey string, baseURL string, model string) *OpenAIProvider {
+	if baseURL == "" {
+		baseURL = "https://api.openai.com/v1"
+	}
+	if model == "" {
+		model = "gpt-4o-mini"
+	}
+	return &OpenAIProvider{
+		name:    "openai",
+		url:     strings.TrimRight(baseURL, "/") + "/chat/completions",
+		model:   model,
+		headers: map[string]string{"Authorization": "Bearer " + strings.TrimSpace(apiKey)},
+		client:  newHTTPClient(),
+	}
+}
+
+// NewAzureProvider targets an Azure OpenAI deployment. The deployment picks
+// the model, and authentication uses the api-key header.
+func NewAzureProvider(endpoint string, deployment string, apiVersion string, apiKey string) *OpenAIProvider {
+	if apiVersion == "" {
+		apiVersion = "2024-06-01"
+	}
+	return &OpenAIProvider{
+		name: "azure",
+		url: fmt.Sprintf("%s/openai/deployments/%s/chat/completions?api-version=%s",
+			strings.TrimRight(endpoint, "/"), url.PathEscape(deployment), url.QueryEscape(apiVersion)),
+		model:   deployment,
+		headers: map[string]string{"api-key": strings.TrimSpace(apiKey)},
+		client:  newHTTPClient(),
+	}
+}
+
+// NewLlamaCppProvider targets a self-hosted OpenAI-compatible server, such as
+// llama.cpp's llama-server. baseURL includes the /v1 prefix.
+func NewLlamaCppProvider(baseURL string, model string) *OpenAIProvider {
+	return &OpenAIProvider{
+		name:       "llamacpp",
+		url:        strings.TrimRight(baseURL, "/") + "/chat/completions",
+		model:      model,
+		selfHosted: true,
+		client:     newHTTPClient(),
+	}
+}
+
+func (p *OpenAIProvider) Name() string     { return p.name }
+func (p *OpenAIProvider) Model() string    { return p.model }
+func (p *OpenAIProvider) SelfHosted() bool { return p.selfHosted }
+
+func (p *OpenAIProvider) Complete(ctx context.Context, request CompletionRequest) (CompletionResponse, error) {
+	payload := chatCompletionRequest{
+		Model: p.model,
+		Messages: []chatMessage{
+			{Role: "system", Content: request.System},
+			{Role: "user", Content: request.Prompt},
+		},
+		Temperature: request.Temperature,
+		MaxTokens:   request.MaxTokens,
+	}
+	resp, err := postJSON(ctx, p.client, p.url, p.headers, payload)
+	if err != nil {
+		return CompletionResponse{}, err
+	}
+	if resp.Status >= 300 {
+		var failure chatCompletionResponse
+		_ = json.Unmarshal(resp.Body, &failure)
+		return CompletionResponse{}, resp.failed(p.name, failure.Error.Message)
+	}
+
+	var response chatCompletionResponse
+	if err := json.Unmarshal(resp.Body, &response); err != nil {
+		return CompletionResponse{}, fmt.Errorf("%s: decode response: %w", p.name, err)
+	}
+	if len(response.Choices) == 0 {
+		return CompletionResponse{}, fmt.Errorf("%s returned no choices", p.name)
+	}
+	return CompletionResponse{
+		Content: response.Choices[0].Message.Content,
+		Model:   response.Model,
+		Usage: Usage{
+			InputTokens:  response.Usage.PromptTokens,
+			OutputTokens: response.Usage.CompletionTokens,
+		},
+	}, nil
+}
+
+type chatCompletionRequest struct {
+	Model       string        `json:"model,omitempty"`
+	Messages    []chatMessage `json:"messages"`
+	Temperature float32       `json:"temperature"`
+	MaxTokens   int           `json:"max_tokens,omitempty"`
+}
+
+type chatMessage struct {
+	Role    string `json:"role"`
+	Content string `json:"content"`
+}
+
+type chatCompletionResponse struct {
+	Model   string `json:"model"`
+	Choices []struct {
+		Message chatMessage `json:"message"`
+	} `json:"choices"`
+	Usage struct {
+		PromptTokens     int `json:"prompt_tokens"`
+		CompletionTokens int `json:"completion_tokens"`
+	} `json:"usage"`
+	Error struct {
+		Message string `json:"message"`
+	} `json:"error"`
+}
@@ -0,0 +1,162 @@
+package ai
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/example/pr-ai-teammate/internal/analysis"
+)
+
+// Pass is one focused review run alongside the general one. It runs when a
+// changed file matches Paths or an added line matches one of the Content
+// regular expressions; a pass with neither always runs. Provider picks a
+// different backend for the pass, for example a stronger model for security.
+type Pass struct {
+	Variant  string
+	Provider string
+	Paths    []string
+	Content  []string
+}
+
+// DefaultPasses are the built-in specialist reviews. Repositories opt in to
+// them by variant, since each one that runs is another provider call.
+func DefaultPasses() []Pass {
+	return []Pass{
+		{
+			Variant: "security",
+			Paths:   []string{"*auth*", "*crypto*", "*password*", "*secret*", "*login*", "*.sql", "**/auth/**", "**/migrations/**"},
+			Content: []string{
+				`(?i)\b(password|passwd|secret|jwt|oauth|csrf|api[_-]?key)\b`,
+				`"(crypto/[a-z0-9/]+|math/rand|os/exec|database/sql|html/template|text/template)"`,
+				`\b(exec\.Command|sql\.Open|\.(Query|Exec|QueryRow)(Context)?\(|tls\.Config|InsecureSkipVerify|filepath\.Join|http\.Redirect|template\.HTML)\b`,
+				`(?i)\b(select|insert|update|delete)\b.+\b(from|into|set|where)\b`,
+			},
+		},
+		{
+			Variant: "performance",
+			Content: []string{
+				`\bgo (func|\w+\()`,
+				`\b(sync\.|chan\b|time\.Sleep|regexp\.MustCompile|\.(Query|Exec)(Context)?\()`,
+			},
+		},
+		{
+			Variant: "api-design",
+			Paths:   []string{"**/api/**", "*handler*", "*.proto", "*openapi*", "*swagger*", "*.graphql"},
+			Content: []string{
+				`\b(HandleFunc|http\.Handle|ServeHTTP)\b`,
+			},
+		},
+		{
+			Variant: "tests",
+			Paths:   []string{"*_test.go", "*_test.py", "test_*.py", "*.test.*", "*.spec.*", "**/test/**", "**/tests/**"},
+		},
+	}
+}
+
+// selectPasses returns the specialist passes worth running for a diff,
+// leaving out any with the variant of the main review, which always runs.
+func selectPasses(passes []Pass, mainVariant string, diff string) ([]Pass, error) {
+	files, _ := analysis.ParseUnifiedDiff(diff)
+	var selected []Pass
+	for _, pass := range passes {
+		if pass.Variant == mainVariant {
+			continue
+		}
+		matches, err := pass.matches(files)
+		if err != nil {
+			return nil, err
+		}
+		if matches {
+			selected = append(selected, pass)
+		}
+	}
+	return selected, nil
+}
+
+func (p Pass) matches(files []analysis.FileDiff) (bool, error) {
+	if len(p.Paths) == 0 && len(p.Content) == 0 {
+		return true, nil
+	}
+	patterns := make([]*regexp.Regexp, 0, len(p.Content))
+	for _, content := range p.Content {
+		pattern, err := regexp.Compile(content)
+		if err != nil {
+			return false, fmt.Errorf("pass %q: %w", p.Variant, err)
+		}
+		patterns = append(patterns, pattern)
+	}
+	for _, file := range files {
+		if analysis.MatchAnyPath(p.Paths, file.Path) {
+			return true, nil
+		}
+		for _, line := range file.AddedLines {
+			for _, pattern := range patterns {
+				if pattern.MatchString(line.Content) {
+					return true, nil
+				}
+			}
+		}
+	}
+	return false, nil
+}
+
+// mergeIssues drops findings that repeat another pass's finding on the same
+// line, keeping the more severe one. Different concerns on one line stay.
+func mergeIssues(issues []analysis.Issue) []analysis.Issue {
+	var merged []analysis.Issue
+	for _, issue := range issues {
+		duplicate := false
+		for i, kept := range merged {
+			if kept.File != issue.File || kept.Line != issue.Line || !similarMessages(kept.Message, issue.Message) {
+				continue
+			}
+			duplicate = true
+			if severityRank(issue.Severity) < severityRank(kept.Severity) {
+				merged[i] = issue
+			}
+			break
+		}
+		if !duplicate {
+			merged = append(merged, issue)
+		}
+	}
+	return merged
+}
+
+// similarMessages compares the sets of words longer than three letters; two
+// findings sharing at least half of them are taken to say the same thing.
+func similarMessages(a, b string) bool {
+	wordsA, wordsB := messageWords(a), messageWords(b)
+	if len(wordsA) == 0 || len(wordsB) == 0 {
+		return false
+	}
+	shared := 0
+	for word := range wordsA {
+		if wordsB[word] {
+			shared++
+		}
+	}
+	return 2*shared >= len(wordsA)+len(wordsB)-shared
+}
+
+func messageWords(message string) map[string]bool {
+	words := map[string]bool{}
+	for _, word := range strings.FieldsFunc(strings.ToLower(message), func(r rune) bool {
+		return !(r >= 'a' && r <= 'z' || r >= '0' && r <= '9')
+	}) {
+		if len(word) > 3 {
+			words[word] = true
+		}
+	}
+	return words
+}
+
+// passTitle labels a pass in the review summary, e.g. "API design".
+func passTitle(variant string) string {
+	if variant == "api-design" {
+		return "API design"
+	}
+	title := strings.ReplaceAll(variant, "-", " ")
+	return strings.ToUpper(title[:1]) + title[1:]
+}
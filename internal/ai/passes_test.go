@@ -0,0 +1,137 @@
+package ai
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/example/pr-ai-teammate/internal/analysis"
+)
+
+func newFileDiff(path string, lines ...string) string {
+	diff := fmt.Sprintf("diff --git a/%[1]s b/%[1]s\n--- /dev/null\n+++ b/%[1]s\n@@ -0,0 +1,%[2]d @@\n", path, len(lines))
+	for _, line := range lines {
+		diff += "+" + line + "\n"
+	}
+	return diff
+}
+
+func variants(passes []Pass) string {
+	var names []string
+	for _, pass := range passes {
+		names = append(names, pass.Variant)
+	}
+	return strings.Join(names, ",")
+}
+
+func TestSelectPassesByChangedFiles(t *testing.T) {
+	for _, tc := range []struct {
+		diff string
+		want string
+	}{
+		{newFileDiff("docs/guide.md", "# Guide"), ""},
+		{newFileDiff("internal/auth/login.go", "package auth"), "security"},
+		{newFileDiff("store.go", "package store", "func load(db *sql.DB) {", "\tdb.QueryContext(ctx, \"SELECT id FROM users WHERE name = \" + name)", "}"), "security,performance"},
+		{newFileDiff("internal/api/client.go", "package api", "func NewClient(url string) *Client {"), "api-design"},
+		{newFileDiff("sum.go", "package sum", "type Totals struct{}", "func Sum(values []int) (total int) {", "\tfor _, v := range values {", "\t\ttotal += v", "\t}", "\treturn total", "}"), ""},
+		{newFileDiff("session.go", "package auth", "func tokenCount(s string) int { return len(strings.Fields(s)) }"), ""},
+		{newFileDiff("parse_test.go", "package parse"), "tests"},
+	} {
+		passes, err := selectPasses(DefaultPasses(), DefaultVariant, tc.diff)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := variants(passes); got != tc.want {
+			t.Errorf("got passes %s, want %s for\n%s", got, tc.want, tc.diff)
+		}
+	}
+}
+
+func TestMergeIssuesKeepsMostSevereDuplicate(t *testing.T) {
+	merged := mergeIssues([]analysis.Issue{
+		{File: "a.go", Line: 3, RuleID: "ai-performance", Severity: "medium", Message: "Query runs inside the loop for every user."},
+		{File: "a.go", Line: 3, RuleID: "ai-security", Severity: "high", Message: "SQL built by concatenation allows injection."},
+		{File: "a.go", Line: 3, RuleID: "ai-general", Severity: "high", Message: "The query runs inside the loop for every user row."},
+	})
+	if len(merged) != 2 || merged[0].RuleID != "ai-general" || merged[1].RuleID != "ai-security" {
+		t.Fatalf("unexpected merge %+v", merged)
+	}
+}
+
+type passProvider struct {
+	name    string
+	replies map[string]string
+	err     error
+	focuses []string
+}
+
+func (p *passProvider) Name() string     { return p.name }
+func (p *passProvider) Model() string    { return p.name + "-1" }
+func (p *passProvider) SelfHosted() bool { return false }
+
+func (p *passProvider) Complete(ctx context.Context, request CompletionRequest) (CompletionResponse, error) {
+	focus := request.Prompt[:strings.Index(request.Prompt, "\n")]
+	p.focuses = append(p.focuses, focus)
+	if p.err != nil {
+		return CompletionResponse{}, p.err
+	}
+	for prefix, reply := range p.replies {
+		if strings.HasPrefix(focus, prefix) {
+			return CompletionResponse{Content: reply}, nil
+		}
+	}
+	return CompletionResponse{Content: `{"summary": ""}`}, nil
+}
+
+func TestReviewRunsPassesOnTheirProviders(t *testing.T) {
+	diff := newFileDiff("store.go", "package store", "func load(db *sql.DB) {", "\tfor _, name := range names {", "\t\tdb.Query(\"SELECT id FROM users WHERE name = '\" + name + \"'\")", "\t}", "}")
+	openai := &passProvider{name: "openai", replies: map[string]string{
+		"Review this PR for performance risks": `{"summary": "Queries in a loop.", "findings": [{"file": "store.go", "line": 4, "severity": "medium", "message": "Query runs inside the loop for every name."}]}`,
+	}}
+	anthropic := &passProvider{name: "anthropic", replies: map[string]string{
+		"Review this PR for security issues": `{"summary": "SQL injection.", "findings": [{"file": "store.go", "line": 4, "severity": "high", "message": "SQL built by concatenation allows injection."}]}`,
+	}}
+	passes := DefaultPasses()
+	passes[0].Provider = "anthropic"
+
+	result, err := NewReviewer("openai", openai, anthropic).Review(context.Background(), ReviewInput{Title: "Load users", Diff: diff, Passes: passes})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(anthropic.focuses) != 1 || len(openai.focuses) != 2 {
+		t.Fatalf("expected security on anthropic and the general and performance passes on openai, got %v and %v", anthropic.focuses, openai.focuses)
+	}
+	if strings.Join(result.Passes, ",") != "general,security,performance" || len(result.Calls) != 3 {
+		t.Fatalf("unexpected passes %v and calls %+v", result.Passes, result.Calls)
+	}
+	if len(result.Issues) != 2 || result.Issues[0].RuleID != "ai-security" || result.Issues[1].RuleID != "ai-performance" {
+		t.Fatalf("unexpected issues %+v", result.Issues)
+	}
+	if result.Summary != "**Security:** SQL injection.\n\n**Performance:** Queries in a loop." {
+		t.Fatalf("unexpected summary %q", result.Summary)
+	}
+}
+
+func TestReviewSurvivesAFailedPass(t *testing.T) {
+	diff := newFileDiff("store.go", "package store", "func load(db *sql.DB) {", "\tfor _, name := range names {", "\t\tdb.Query(query, name)", "\t}", "}")
+	openai := &passProvider{name: "openai", replies: map[string]string{"Review this PR for performance risks": `{"summary": "Fine loop."}`}}
+	broken := &passProvider{name: "anthropic", err: &StatusError{Provider: "anthropic", StatusCode: 500, Message: "boom"}}
+	passes := DefaultPasses()
+	passes[0].Provider = "anthropic"
+
+	result, err := NewReviewer("openai", openai, broken).Review(context.Background(), ReviewInput{Diff: diff, Passes: passes})
+	if err != nil {
+		t.Fatalf("expected the performance pass to carry the review, got %v", err)
+	}
+	if strings.Join(result.FailedPasses, ",") != "security" || result.Summary != "**Performance:** Fine loop." {
+		t.Fatalf("unexpected result %+v", result)
+	}
+
+	_, err = NewReviewer("anthropic", broken).Review(context.Background(), ReviewInput{Diff: diff, Passes: passes})
+	var status *StatusError
+	if !errors.As(err, &status) {
+		t.Fatalf("expected the provider error when every pass fails, got %v", err)
+	}
+}
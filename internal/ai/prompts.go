@@ -0,0 +1,182 @@
+package ai
+
+import (
+	"crypto/sha256"
+	"embed"
+	"encoding/hex"
+	"fmt"
+	"sort"
+	"strings"
+	"text/template"
+)
+
+// PromptVersion identifies the built-in templates in prompts/. Bump it
+// whenever they change so reviews from different revisions can be told
+// apart, and cached replies to older prompts are not reused.
+const PromptVersion = "review-v6"
+
+// DefaultVariant is the focus used when a repository does not pick one.
+const DefaultVariant = "general"
+
+//go:embed prompts/*.tmpl
+var promptFiles embed.FS
+
+var promptFuncs = template.FuncMap{"join": strings.Join}
+
+var defaultPrompts = template.Must(template.New("prompts").
+	Funcs(promptFuncs).
+	ParseFS(promptFiles, "prompts/*.tmpl"))
+
+// PromptConfig is a repository's choice of focus variant and its changes to
+// the built-in templates. Templates may replace "guidelines" or define
+// "focus/<variant>"; the rest of the prompt, including how pull request
+// content is fenced, cannot be changed. Instructions are appended to every
+// prompt.
+type PromptConfig struct {
+	Variant      string
+	Templates    map[string]string
+	Instructions string
+}
+
+// Prompts is a parsed set of templates and the version that names it.
+type Prompts struct {
+	templates    *template.Template
+	instructions string
+	version      string
+}
+
+// NewPrompts applies a repository's overrides to the built-in templates.
+// Overridden prompts get a version suffix derived from the overrides.
+func NewPrompts(config PromptConfig) (*Prompts, error) {
+	prompts := &Prompts{templates: defaultPrompts, instructions: strings.TrimSpace(config.Instructions), version: PromptVersion}
+	if len(config.Templates) == 0 && prompts.instructions == "" {
+		return prompts, nil
+	}
+
+	templates, err := defaultPrompts.Clone()
+	if err != nil {
+		return nil, err
+	}
+	names := make([]string, 0, len(config.Templates))
+	for name := range config.Templates {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	hash := sha256.New()
+	for _, name := range names {
+		if !OverridableTemplate(name) {
+			return nil, fmt.Errorf("prompt template %q cannot be overridden", name)
+		}
+		// Each override is parsed on its own so a {{define}} inside it cannot
+		// replace the fixed templates.
+		override, err := template.New(name).Funcs(promptFuncs).Parse(config.Templates[name])
+		if err != nil {
+			return nil, fmt.Errorf("prompt template %q: %w", name, err)
+		}
+		var extra []string
+		for _, defined := range override.Templates() {
+			if defined.Name() != name {
+				extra = append(extra, fmt.Sprintf("%q", defined.Name()))
+			}
+		}
+		if len(extra) > 0 {
+			sort.Strings(extra)
+			return nil, fmt.Errorf("prompt template %q may not define %s", name, strings.Join(extra, ", "))
+		}
+		if _, err := templates.AddParseTree(name, override.Tree); err != nil {
+			return nil, fmt.Errorf("prompt template %q: %w", name, err)
+		}
+		fmt.Fprintf(hash, "%s\x00%s\x00", name, config.Templates[name])
+	}
+	fmt.Fprintf(hash, "%s", prompts.instructions)
+	prompts.templates = templates
+	prompts.version = PromptVersion + "+repo." + hex.EncodeToString(hash.Sum(nil))[:8]
+	return prompts, nil
+}
+
+// OverridableTemplate reports whether a repository may define name.
+func OverridableTemplate(name string) bool {
+	return name == "guidelines" || (strings.HasPrefix(name, "focus/") && len(name) > len("focus/"))
+}
+
+// Variants lists the built-in focus variants.
+func Variants() []string {
+	var variants []string
+	for _, tmpl := range defaultPrompts.Templates() {
+		if variant, ok := strings.CutPrefix(tmpl.Name(), "focus/"); ok {
+			variants = append(variants, variant)
+		}
+	}
+	sort.Strings(variants)
+	return variants
+}
+
+// HasVariant reports whether variant is built in or defined by the
+// repository.
+func (p *Prompts) HasVariant(variant string) bool {
+	return p.templates.Lookup("focus/"+variant) != nil
+}
+
+func (p *Prompts) Version() string {
+	return p.version
+}
+
+// promptData is what the "prompt" template sees. Untrusted sections are
+// fenced before rendering so templates cannot leave them unfenced.
+type promptData struct {
+	Focus        string
+	Instructions string
+	Nonce        string
+	Detected     int
+	Title        string
+	Body         string
+	Findings     string
+	Documents    string
+	Files        string
+	Omitted      []string
+	Diff         string
+	ReplyFormat  string
+}
+
+// render returns the system message and prompt for a focus variant.
+func (p *Prompts) render(variant string, data promptData) (string, string, error) {
+	if variant == "" {
+		variant = DefaultVariant
+	}
+	focus := p.templates.Lookup("focus/" + variant)
+	if focus == nil {
+		return "", "", fmt.Errorf("unknown prompt variant %q", variant)
+	}
+	var builder strings.Builder
+	if err := focus.Execute(&builder, data); err != nil {
+		return "", "", fmt.Errorf("prompt variant %q: %w", variant, err)
+	}
+	data.Focus = strings.TrimSpace(builder.String())
+	data.Instructions = p.instructions
+	data.ReplyFormat = replyFormat
+
+	builder.Reset()
+	if err := p.templates.ExecuteTemplate(&builder, "system", data); err != nil {
+		return "", "", fmt.Errorf("system prompt: %w", err)
+	}
+	system := builder.String()
+	builder.Reset()
+	if err := p.templates.ExecuteTemplate(&builder, "prompt", data); err != nil {
+		return "", "", fmt.Errorf("prompt: %w", err)
+	}
+	return system, builder.String(), nil
+}
+
+// renderVerification returns the system message and prompt that ask the
+// model to re-check its findings. data.Findings holds the fenced findings.
+func (p *Prompts) renderVerification(data promptData) (string, string, error) {
+	var system, prompt strings.Builder
+	if err := p.templates.ExecuteTemplate(&system, "system", data); err != nil {
+		return "", "", fmt.Errorf("system prompt: %w", err)
+	}
+	if err := p.templates.ExecuteTemplate(&prompt, "verify", data); err != nil {
+		return "", "", fmt.Errorf("verification prompt: %w", err)
+	}
+	return system.String(), prompt.String(), nil
+}
@@ -0,0 +1,136 @@
+package ai
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ErrProviderUnavailable is returned when a repository asks for a provider
+// the server has not configured, or for a self-hosted model and the provider
+// is hosted by a third party. No PR content is sent in either case.
+var ErrProviderUnavailable = errors.New("ai provider unavailable")
+
+// Provider sends one completion request to an LLM backend.
+type Provider interface {
+	Name() string
+	// Model is the configured model, used when a reply does not name one.
+	Model() string
+	// SelfHosted reports whether prompts stay on infrastructure we run.
+	SelfHosted() bool
+	Complete(ctx context.Context, request CompletionRequest) (CompletionResponse, error)
+}
+
+type CompletionRequest struct {
+	System      string
+	Prompt      string
+	Temperature float32
+	MaxTokens   int
+}
+
+type CompletionResponse struct {
+	Content string
+	Model   string
+	Usage   Usage
+}
+
+// Usage is the token count reported by the provider for one request.
+type Usage struct {
+	InputTokens  int
+	OutputTokens int
+}
+
+func newHTTPClient() *http.Client {
+	return &http.Client{Timeout: 60 * time.Second}
+}
+
+// httpResponse is a provider reply read in full, so providers can decode
+// the body as their success or error shape.
+type httpResponse struct {
+	Status int
+	Header http.Header
+	Body   []byte
+}
+
+// postJSON sends payload and returns the raw response, leaving decoding to
+// the provider since every backend shapes errors differently.
+func postJSON(ctx context.Context, client *http.Client, url string, headers map[string]string, payload any) (httpResponse, error) {
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return httpResponse{}, err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return httpResponse{}, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	for key, value := range headers {
+		req.Header.Set(key, value)
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return httpResponse{}, err
+	}
+	defer resp.Body.Close()
+	data, err := io.ReadAll(io.LimitReader(resp.Body, 4<<20))
+	if err != nil {
+		return httpResponse{}, err
+	}
+	return httpResponse{Status: resp.StatusCode, Header: resp.Header, Body: data}, nil
+}
+
+// StatusError is a non-success reply from a provider.
+type StatusError struct {
+	Provider   string
+	StatusCode int
+	Message    string
+	// RetryAfter is the delay the provider asked for, if any.
+	RetryAfter time.Duration
+}
+
+func (e *StatusError) Error() string {
+	return fmt.Sprintf("%s request failed (%d): %s", e.Provider, e.StatusCode, e.Message)
+}
+
+// failed builds a StatusError from the message decoded out of a JSON error
+// body. Proxies and gateways often answer with HTML or plain text instead,
+// so without a decoded message the start of the raw body is used.
+func (r httpResponse) failed(provider string, message string) error {
+	if message == "" {
+		message = strings.Join(strings.Fields(string(r.Body)), " ")
+		if len(message) > 300 {
+			message = message[:300] + "..."
+		}
+	}
+	if message == "" {
+		message = http.StatusText(r.Status)
+	}
+	return &StatusError{
+		Provider:   provider,
+		StatusCode: r.Status,
+		Message:    message,
+		RetryAfter: parseRetryAfter(r.Header.Get("Retry-After"), time.Now()),
+	}
+}
+
+// parseRetryAfter reads the delay-seconds and HTTP-date forms.
+func parseRetryAfter(value string, now time.Time) time.Duration {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return 0
+	}
+	if seconds, err := strconv.Atoi(value); err == nil && seconds > 0 {
+		return time.Duration(seconds) * time.Second
+	}
+	if at, err := http.ParseTime(value); err == nil && at.After(now) {
+		return at.Sub(now)
+	}
+	return 0
+}
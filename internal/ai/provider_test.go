@@ -0,0 +1,95 @@
+package ai
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestProvidersSendAuthAndReadUsage(t *testing.T) {
+	cases := []struct {
+		name     string
+		path     string
+		header   string
+		value    string
+		response string
+		provider func(url string) Provider
+	}{
+		{
+			name: "openai", path: "/v1/chat/completions", header: "Authorization", value: "Bearer sk-test",
+			response: `{"model":"gpt-4o-mini","choices":[{"message":{"role":"assistant","content":"looks good"}}],"usage":{"prompt_tokens":120,"completion_tokens":30}}`,
+			provider: func(url string) Provider { return NewOpenAIProvider("sk-test", url+"/v1", "") },
+		},
+		{
+			name: "azure", path: "/openai/deployments/review-gpt4/chat/completions", header: "api-key", value: "azure-key",
+			response: `{"model":"gpt-4","choices":[{"message":{"role":"assistant","content":"looks good"}}],"usage":{"prompt_tokens":120,"completion_tokens":30}}`,
+			provider: func(url string) Provider { return NewAzureProvider(url, "review-gpt4", "", "azure-key") },
+		},
+		{
+			name: "anthropic", path: "/v1/messages", header: "x-api-key", value: "ant-key",
+			response: `{"type":"message","model":"claude","content":[{"type":"text","text":"looks "},{"type":"text","text":"good"}],"usage":{"input_tokens":120,"output_tokens":30}}`,
+			provider: func(url string) Provider { return NewAnthropicProvider("ant-key", url, "") },
+		},
+		{
+			name: "ollama", path: "/api/chat",
+			response: `{"model":"llama3.1","message":{"role":"assistant","content":"looks good"},"prompt_eval_count":120,"eval_count":30}`,
+			provider: func(url string) Provider { return NewOllamaProvider(url, "") },
+		},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if r.URL.Path != tc.path {
+					t.Errorf("unexpected path %s", r.URL.Path)
+				}
+				if tc.header != "" && r.Header.Get(tc.header) != tc.value {
+					t.Errorf("expected %s header %q, got %q", tc.header, tc.value, r.Header.Get(tc.header))
+				}
+				var body map[string]any
+				if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+					t.Errorf("request is not JSON: %v", err)
+				}
+				w.Write([]byte(tc.response))
+			}))
+			defer server.Close()
+
+			response, err := tc.provider(server.URL).Complete(context.Background(), CompletionRequest{System: "sys", Prompt: "review"})
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if response.Content != "looks good" || response.Usage != (Usage{InputTokens: 120, OutputTokens: 30}) {
+				t.Fatalf("unexpected response %+v", response)
+			}
+		})
+	}
+}
+
+func TestProviderErrorsAreDecoded(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte(`{"type":"error","error":{"type":"invalid_request_error","message":"max_tokens: too large"}}`))
+	}))
+	defer server.Close()
+
+	_, err := NewAnthropicProvider("key", server.URL, "").Complete(context.Background(), CompletionRequest{Prompt: "x"})
+	if err == nil || !strings.Contains(err.Error(), "(400): max_tokens: too large") {
+		t.Fatalf("expected decoded error, got %v", err)
+	}
+}
+
+func TestReviewerEnforcesSelfHostedOnly(t *testing.T) {
+	reviewer := NewReviewer("", NewOpenAIProvider("sk-test", "http://127.0.0.1:1", ""), NewOllamaProvider("http://127.0.0.1:1", ""))
+
+	_, err := reviewer.Review(context.Background(), ReviewInput{SelfHostedOnly: true})
+	if !errors.Is(err, ErrProviderUnavailable) {
+		t.Fatalf("expected the hosted default provider to be refused, got %v", err)
+	}
+	_, err = reviewer.Review(context.Background(), ReviewInput{Provider: "anthropic"})
+	if !errors.Is(err, ErrProviderUnavailable) {
+		t.Fatalf("expected an unconfigured provider to be refused, got %v", err)
+	}
+}
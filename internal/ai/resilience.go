@@ -0,0 +1,206 @@
+package ai
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"math/rand"
+	"net"
+	"net/http"
+	"sync"
+	"syscall"
+	"time"
+)
+
+// ErrCircuitOpen is returned without calling the provider while its circuit
+// breaker is open.
+var ErrCircuitOpen = errors.New("ai provider circuit open")
+
+// RetryPolicy controls how often a failed request is retried. Delays grow
+// exponentially from BaseDelay up to MaxDelay, with jitter; a Retry-After
+// from the provider overrides the computed delay. A Retry-After longer than
+// MaxDelay is not waited for.
+type RetryPolicy struct {
+	MaxAttempts int
+	BaseDelay   time.Duration
+	MaxDelay    time.Duration
+}
+
+func DefaultRetryPolicy() RetryPolicy {
+	return RetryPolicy{MaxAttempts: 4, BaseDelay: 500 * time.Millisecond, MaxDelay: 30 * time.Second}
+}
+
+// CircuitBreaker opens after Threshold consecutive failed requests and
+// rejects requests for Cooldown. After the cooldown one trial request is let
+// through; it closes the circuit on success and reopens it on failure.
+type CircuitBreaker struct {
+	threshold int
+	cooldown  time.Duration
+	now       func() time.Time
+
+	mu        sync.Mutex
+	failures  int
+	openUntil time.Time
+	trial     bool
+}
+
+func NewCircuitBreaker(threshold int, cooldown time.Duration) *CircuitBreaker {
+	if threshold <= 0 {
+		threshold = 5
+	}
+	return &CircuitBreaker{threshold: threshold, cooldown: cooldown, now: time.Now}
+}
+
+func (b *CircuitBreaker) allow() bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.failures < b.threshold {
+		return true
+	}
+	if b.trial || b.now().Before(b.openUntil) {
+		return false
+	}
+	b.trial = true
+	return true
+}
+
+func (b *CircuitBreaker) record(failed bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.trial = false
+	if !failed {
+		b.failures = 0
+		return
+	}
+	b.failures++
+	if b.failures >= b.threshold {
+		b.openUntil = b.now().Add(b.cooldown)
+	}
+}
+
+// resilientProvider adds retries and a circuit breaker to a provider.
+type resilientProvider struct {
+	Provider
+	policy  RetryPolicy
+	breaker *CircuitBreaker
+	sleep   func(ctx context.Context, delay time.Duration) error
+	jitter  func(delay time.Duration) time.Duration
+}
+
+// WithResilience retries transient failures (network errors, 429 and 5xx)
+// and stops calling a provider that keeps failing. Each provider needs its
+// own breaker.
+func WithResilience(provider Provider, policy RetryPolicy, breaker *CircuitBreaker) Provider {
+	if policy.MaxAttempts <= 0 {
+		policy.MaxAttempts = 1
+	}
+	return &resilientProvider{
+		Provider: provider,
+		policy:   policy,
+		breaker:  breaker,
+		sleep:    sleepContext,
+		jitter:   equalJitter,
+	}
+}
+
+func (p *resilientProvider) Complete(ctx context.Context, request CompletionRequest) (CompletionResponse, error) {
+	if p.breaker != nil && !p.breaker.allow() {
+		return CompletionResponse{}, fmt.Errorf("%s: %w", p.Name(), ErrCircuitOpen)
+	}
+	var err error
+	for attempt := 1; ; attempt++ {
+		var response CompletionResponse
+		response, err = p.Provider.Complete(ctx, request)
+		if err == nil {
+			p.record(false)
+			return response, nil
+		}
+		if !retryable(ctx, err) {
+			// The provider is up; the request itself was rejected.
+			p.record(false)
+			return CompletionResponse{}, err
+		}
+		if attempt >= p.policy.MaxAttempts {
+			break
+		}
+		delay, ok := p.delay(attempt, err)
+		if !ok {
+			break
+		}
+		if sleepErr := p.sleep(ctx, delay); sleepErr != nil {
+			break
+		}
+	}
+	p.record(true)
+	return CompletionResponse{}, err
+}
+
+func (p *resilientProvider) record(failed bool) {
+	if p.breaker != nil {
+		p.breaker.record(failed)
+	}
+}
+
+// delay returns how long to wait before the next attempt, and false when the
+// provider asked for a longer wait than the policy allows.
+func (p *resilientProvider) delay(attempt int, err error) (time.Duration, bool) {
+	var status *StatusError
+	if errors.As(err, &status) && status.RetryAfter > 0 {
+		return status.RetryAfter, p.policy.MaxDelay <= 0 || status.RetryAfter <= p.policy.MaxDelay
+	}
+	delay := p.policy.BaseDelay << (attempt - 1)
+	if p.policy.MaxDelay > 0 && (delay > p.policy.MaxDelay || delay <= 0) {
+		delay = p.policy.MaxDelay
+	}
+	return p.jitter(delay), true
+}
+
+func retryable(ctx context.Context, err error) bool {
+	if ctx.Err() != nil {
+		return false
+	}
+	var status *StatusError
+	if errors.As(err, &status) {
+		// 529 is Anthropic's "overloaded".
+		return status.StatusCode == http.StatusTooManyRequests || status.StatusCode >= 500
+	}
+	return transient(err)
+}
+
+// transient reports transport errors: refused connections, resets, dropped
+// or truncated responses and timeouts. Anything else, such as an
+// undecodable reply or a bad provider URL, fails the same way on retry.
+func transient(err error) bool {
+	if errors.Is(err, syscall.ECONNRESET) || errors.Is(err, syscall.ECONNREFUSED) ||
+		errors.Is(err, io.ErrUnexpectedEOF) || errors.Is(err, io.EOF) {
+		return true
+	}
+	var opErr *net.OpError
+	if errors.As(err, &opErr) {
+		return true
+	}
+	var netErr net.Error
+	return errors.As(err, &netErr) && netErr.Timeout()
+}
+
+// equalJitter waits between half and all of the computed delay, so clients
+// that failed together do not retry together.
+func equalJitter(delay time.Duration) time.Duration {
+	half := delay / 2
+	if half <= 0 {
+		return delay
+	}
+	return half + time.Duration(rand.Int63n(int64(half)+1))
+}
+
+func sleepContext(ctx context.Context, delay time.Duration) error {
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
@@ -0,0 +1,156 @@
+package ai
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"syscall"
+	"testing"
+	"time"
+)
+
+type scriptedProvider struct {
+	errs  []error
+	calls int
+}
+
+func (p *scriptedProvider) Name() string     { return "scripted" }
+func (p *scriptedProvider) Model() string    { return "scripted-1" }
+func (p *scriptedProvider) SelfHosted() bool { return false }
+
+func (p *scriptedProvider) Complete(ctx context.Context, request CompletionRequest) (CompletionResponse, error) {
+	p.calls++
+	if p.calls <= len(p.errs) {
+		return CompletionResponse{}, p.errs[p.calls-1]
+	}
+	return CompletionResponse{Content: "ok"}, nil
+}
+
+func newTestResilience(provider Provider, policy RetryPolicy, breaker *CircuitBreaker, delays *[]time.Duration) *resilientProvider {
+	resilient := WithResilience(provider, policy, breaker).(*resilientProvider)
+	resilient.jitter = func(delay time.Duration) time.Duration { return delay }
+	resilient.sleep = func(ctx context.Context, delay time.Duration) error {
+		*delays = append(*delays, delay)
+		return nil
+	}
+	return resilient
+}
+
+func TestResilienceRetriesWithBackoffAndRetryAfter(t *testing.T) {
+	provider := &scriptedProvider{errs: []error{
+		&StatusError{Provider: "scripted", StatusCode: 503, Message: "unavailable"},
+		fmt.Errorf("read response: %w", syscall.ECONNRESET),
+		&StatusError{Provider: "scripted", StatusCode: 429, Message: "slow down", RetryAfter: 7 * time.Second},
+	}}
+	var delays []time.Duration
+	resilient := newTestResilience(provider, RetryPolicy{MaxAttempts: 4, BaseDelay: time.Second, MaxDelay: 30 * time.Second}, nil, &delays)
+
+	response, err := resilient.Complete(context.Background(), CompletionRequest{})
+	if err != nil || response.Content != "ok" {
+		t.Fatalf("expected success after retries, got %+v, %v", response, err)
+	}
+	want := []time.Duration{time.Second, 2 * time.Second, 7 * time.Second}
+	if len(delays) != len(want) {
+		t.Fatalf("got delays %v, want %v", delays, want)
+	}
+	for i := range want {
+		if delays[i] != want[i] {
+			t.Fatalf("got delays %v, want %v", delays, want)
+		}
+	}
+}
+
+func TestResilienceDoesNotRetryClientErrors(t *testing.T) {
+	provider := &scriptedProvider{errs: []error{&StatusError{Provider: "scripted", StatusCode: 401, Message: "bad key"}}}
+	var delays []time.Duration
+	resilient := newTestResilience(provider, DefaultRetryPolicy(), nil, &delays)
+
+	if _, err := resilient.Complete(context.Background(), CompletionRequest{}); err == nil || provider.calls != 1 {
+		t.Fatalf("expected one failed call, got %d calls and %v", provider.calls, err)
+	}
+
+	provider = &scriptedProvider{errs: []error{&StatusError{Provider: "scripted", StatusCode: 429, RetryAfter: time.Hour}}}
+	resilient = newTestResilience(provider, DefaultRetryPolicy(), nil, &delays)
+	if _, err := resilient.Complete(context.Background(), CompletionRequest{}); err == nil || provider.calls != 1 || len(delays) != 0 {
+		t.Fatalf("expected no wait beyond MaxDelay, got %d calls, delays %v", provider.calls, delays)
+	}
+}
+
+func TestResilienceDoesNotRetryMalformedReplies(t *testing.T) {
+	var requests atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests.Add(1)
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"choices": [`))
+	}))
+	defer server.Close()
+
+	breaker := NewCircuitBreaker(1, time.Minute)
+	var delays []time.Duration
+	resilient := newTestResilience(NewOpenAIProvider("sk-test", server.URL, ""), DefaultRetryPolicy(), breaker, &delays)
+
+	if _, err := resilient.Complete(context.Background(), CompletionRequest{Prompt: "x"}); err == nil || requests.Load() != 1 || len(delays) != 0 {
+		t.Fatalf("expected one request without retries, got %d requests, delays %v, err %v", requests.Load(), delays, err)
+	}
+	if !breaker.allow() {
+		t.Fatal("expected a malformed reply not to open the circuit")
+	}
+}
+
+func TestCircuitBreakerOpensAndRecovers(t *testing.T) {
+	now := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	breaker := NewCircuitBreaker(2, time.Minute)
+	breaker.now = func() time.Time { return now }
+	failure := &StatusError{Provider: "scripted", StatusCode: 500, Message: "boom"}
+	provider := &scriptedProvider{errs: []error{failure, failure, failure}}
+	var delays []time.Duration
+	resilient := newTestResilience(provider, RetryPolicy{MaxAttempts: 1}, breaker, &delays)
+
+	for i := 0; i < 2; i++ {
+		if _, err := resilient.Complete(context.Background(), CompletionRequest{}); !errors.As(err, new(*StatusError)) {
+			t.Fatalf("call %d: expected provider error, got %v", i, err)
+		}
+	}
+	if _, err := resilient.Complete(context.Background(), CompletionRequest{}); !errors.Is(err, ErrCircuitOpen) || provider.calls != 2 {
+		t.Fatalf("expected open circuit without calling the provider, got %v after %d calls", err, provider.calls)
+	}
+
+	now = now.Add(time.Minute)
+	if _, err := resilient.Complete(context.Background(), CompletionRequest{}); !errors.As(err, new(*StatusError)) {
+		t.Fatalf("expected the trial request to reach the provider, got %v", err)
+	}
+	if _, err := resilient.Complete(context.Background(), CompletionRequest{}); !errors.Is(err, ErrCircuitOpen) {
+		t.Fatalf("expected the failed trial to reopen the circuit, got %v", err)
+	}
+
+	now = now.Add(time.Minute)
+	if response, err := resilient.Complete(context.Background(), CompletionRequest{}); err != nil || response.Content != "ok" {
+		t.Fatalf("expected recovery, got %+v, %v", response, err)
+	}
+	if _, err := resilient.Complete(context.Background(), CompletionRequest{}); err != nil {
+		t.Fatalf("expected a closed circuit, got %v", err)
+	}
+}
+
+func TestProviderDecodesNonJSONErrors(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html")
+		w.Header().Set("Retry-After", "12")
+		w.WriteHeader(http.StatusBadGateway)
+		w.Write([]byte("<html>\n  <body>502 Bad Gateway</body>\n</html>"))
+	}))
+	defer server.Close()
+
+	_, err := NewOpenAIProvider("sk-test", server.URL, "").Complete(context.Background(), CompletionRequest{Prompt: "x"})
+	var status *StatusError
+	if !errors.As(err, &status) {
+		t.Fatalf("expected a status error, got %v", err)
+	}
+	if status.StatusCode != 502 || status.RetryAfter != 12*time.Second || !strings.Contains(status.Message, "<body>502 Bad Gateway</body>") {
+		t.Fatalf("unexpected status error %+v", status)
+	}
+}
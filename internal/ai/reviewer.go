@@ -1,130 +1,304 @@
 package ai
 
 import (
-	"bytes"
 	"context"
-	"encoding/json"
 	"fmt"
-	"net/http"
+	"log"
 	"strings"
 	"time"
 
 	"github.com/example/pr-ai-teammate/internal/analysis"
+	"github.com/example/pr-ai-teammate/internal/redact"
 )
 
+// Reviewer routes each review to the provider the repository asked for, or
+// to the server's default provider.
 type Reviewer struct {
-	apiKey  string
-	baseURL string
-	model   string
-	client  *http.Client
+	providers       map[string]Provider
+	defaultProvider string
+	cache           Cache
+	cacheTTL        time.Duration
 }
 
-func NewReviewer(apiKey string, baseURL string, model string) *Reviewer {
-	trimmedKey := strings.TrimSpace(apiKey)
-	if baseURL == "" {
-		baseURL = "https://api.openai.com/v1"
-	}
-	if model == "" {
-		model = "gpt-4o-mini"
-	}
-	return &Reviewer{
-		apiKey:  trimmedKey,
-		baseURL: strings.TrimRight(baseURL, "/"),
-		model:   model,
-		client:  &http.Client{Timeout: 30 * time.Second},
+func NewReviewer(defaultProvider string, providers ...Provider) *Reviewer {
+	reviewer := &Reviewer{providers: map[string]Provider{}, defaultProvider: defaultProvider}
+	for _, provider := range providers {
+		reviewer.providers[provider.Name()] = provider
+		if reviewer.defaultProvider == "" {
+			reviewer.defaultProvider = provider.Name()
+		}
 	}
+	return reviewer
+}
+
+// SetCache serves repeated prompts from cache for ttl instead of paying for
+// the same completion again.
+func (r *Reviewer) SetCache(cache Cache, ttl time.Duration) {
+	r.cache = cache
+	r.cacheTTL = ttl
 }
 
-func (r *Reviewer) Review(ctx context.Context, input ReviewInput) ([]analysis.Issue, string, error) {
-	if r.apiKey == "" {
-		return nil, "", nil
+func (r *Reviewer) Review(ctx context.Context, input ReviewInput) (ReviewResult, error) {
+	prompts, err := NewPrompts(input.Prompt)
+	if err != nil {
+		return ReviewResult{}, err
+	}
+	mainVariant := input.Prompt.Variant
+	if mainVariant == "" {
+		mainVariant = DefaultVariant
+	}
+	specialists, err := selectPasses(input.Passes, mainVariant, input.Diff)
+	if err != nil {
+		return ReviewResult{}, err
+	}
+	passes := append([]Pass{{Variant: mainVariant}}, specialists...)
+	detected := detectInjection(input)
+	originalDiff := input.Diff
+	redactor, err := redact.New(input.RedactPatterns)
+	if err != nil {
+		return ReviewResult{}, err
+	}
+	for _, line := range input.SecretLines {
+		redactor.Add(redact.KindSecret, line)
 	}
+	input = redactInput(redactor, input)
 
 	diff := input.Diff
 	if len(diff) > 8000 {
 		diff = diff[:8000] + "\n...diff truncated..."
 	}
 
-	prompt := buildPrompt(input.Title, input.Body, diff)
-	request := chatCompletionRequest{
-		Model: r.model,
-		Messages: []chatMessage{
-			{Role: "system", Content: "You are a senior software engineer performing a code review."},
-			{Role: "user", Content: prompt},
-		},
-		Temperature: 0.2,
+	result := ReviewResult{Redactions: redactor.Summary(), PromptVersion: prompts.Version()}
+	var issues []analysis.Issue
+	var summaries []string
+	var firstErr error
+	for _, pass := range passes {
+		reply, call, err := r.runPass(ctx, prompts, pass, input, diff, detected)
+		if err != nil {
+			if ctx.Err() != nil {
+				return result, err
+			}
+			log.Printf("ai %s pass failed: %v", pass.Variant, err)
+			result.FailedPasses = append(result.FailedPasses, pass.Variant)
+			if firstErr == nil {
+				firstErr = err
+			}
+			continue
+		}
+		result.Calls = append(result.Calls, call)
+		result.Passes = append(result.Passes, pass.Variant)
+
+		reply.Summary = redactor.Restore(reply.Summary)
+		for i := range reply.Findings {
+			reply.Findings[i].Message = redactor.Restore(reply.Findings[i].Message)
+			reply.Findings[i].Suggestion = redactor.Restore(reply.Findings[i].Suggestion)
+		}
+		passIssues, summary, dropped := validateReply(reply, originalDiff, "ai-"+pass.Variant)
+		issues = append(issues, passIssues...)
+		result.Dropped += dropped
+		if summary != "" {
+			summaries = append(summaries, summary)
+			if len(passes) > 1 {
+				summaries[len(summaries)-1] = fmt.Sprintf("**%s:** %s", passTitle(pass.Variant), summary)
+			}
+		}
+	}
+	if len(result.Passes) == 0 {
+		return result, firstErr
 	}
 
-	payload, err := json.Marshal(request)
-	if err != nil {
-		return nil, "", err
+	findings := mergeIssues(issues)
+	if input.Verification.Enabled && len(findings) > 0 {
+		checked, err := r.verify(ctx, prompts, redactor, input, findings)
+		if checked.call.Provider != "" {
+			result.Calls = append(result.Calls, checked.call)
+		}
+		switch {
+		case err == nil:
+			findings = checked.kept
+			result.PossibleConcerns = checked.concerns
+			result.Verification = checked.stats
+		case ctx.Err() != nil:
+			return result, err
+		default:
+			// Unverified findings are still better than none.
+			log.Printf("ai verification failed: %v", err)
+			result.Verification.Failed = true
+		}
+	}
+	result.Issues = append(detected, findings...)
+	for i := range result.Issues {
+		result.Issues[i].PromptVersion = prompts.Version()
 	}
+	for i := range result.PossibleConcerns {
+		result.PossibleConcerns[i].PromptVersion = prompts.Version()
+	}
+	result.Summary = strings.Join(summaries, "\n\n")
+	return result, nil
+}
 
-	url := fmt.Sprintf("%s/chat/completions", r.baseURL)
-	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(payload))
+// runPass asks the pass's provider for one focused review.
+func (r *Reviewer) runPass(ctx context.Context, prompts *Prompts, pass Pass, input ReviewInput, diff string, detected []analysis.Issue) (reviewReply, Call, error) {
+	name := pass.Provider
+	if name == "" {
+		name = input.Provider
+	}
+	provider, err := r.provider(name, input.SelfHostedOnly)
 	if err != nil {
-		return nil, "", err
+		return reviewReply{}, Call{}, err
 	}
-	req.Header.Set("Authorization", "Bearer "+r.apiKey)
-	req.Header.Set("Content-Type", "application/json")
-
-	resp, err := r.client.Do(req)
+	system, prompt, err := buildPrompt(prompts, pass.Variant, input, diff, detected)
 	if err != nil {
-		return nil, "", err
+		return reviewReply{}, Call{}, err
 	}
-	defer resp.Body.Close()
+	response, call, err := r.complete(ctx, provider, prompts.Version(), CompletionRequest{
+		System:      system,
+		Prompt:      prompt,
+		Temperature: 0.2,
+	})
+	if err != nil {
+		return reviewReply{}, Call{}, err
+	}
+	return parseReply(response.Content), call, nil
+}
 
-	var response chatCompletionResponse
-	if err := json.NewDecoder(resp.Body).Decode(&response); err != nil {
-		return nil, "", err
+func (r *Reviewer) provider(name string, selfHostedOnly bool) (Provider, error) {
+	if name == "" {
+		name = r.defaultProvider
 	}
-	if resp.StatusCode >= 300 {
-		return nil, "", fmt.Errorf("ai reviewer request failed: %s", response.Error.Message)
+	provider, ok := r.providers[name]
+	if !ok {
+		return nil, fmt.Errorf("%w: %q is not configured on this server", ErrProviderUnavailable, name)
 	}
-	if len(response.Choices) == 0 {
-		return nil, "", fmt.Errorf("ai reviewer returned no choices")
+	if selfHostedOnly && !provider.SelfHosted() {
+		return nil, fmt.Errorf("%w: the repository requires a self-hosted model and %q is not self-hosted", ErrProviderUnavailable, name)
 	}
-
-	return nil, strings.TrimSpace(response.Choices[0].Message.Content), nil
+	return provider, nil
 }
 
-type ReviewInput struct {
-	Title string
-	Body  string
-	Diff  string
+// redactInput returns a copy of input with secrets and personal data
+// replaced by placeholders.
+func redactInput(redactor *redact.Redactor, input ReviewInput) ReviewInput {
+	input.Findings = append([]analysis.Issue(nil), input.Findings...)
+	input.Documents = append([]Document(nil), input.Documents...)
+	input.Files = append([]Document(nil), input.Files...)
+	texts := []*string{&input.Diff, &input.Title, &input.Body}
+	for i := range input.Files {
+		texts = append(texts, &input.Files[i].Content)
+	}
+	for i := range input.Documents {
+		texts = append(texts, &input.Documents[i].Content)
+	}
+	for i := range input.Findings {
+		texts = append(texts, &input.Findings[i].Message, &input.Findings[i].Suggestion)
+	}
+	redactor.RedactAll(texts...)
+	return input
 }
 
-type chatCompletionRequest struct {
-	Model       string        `json:"model"`
-	Messages    []chatMessage `json:"messages"`
-	Temperature float32       `json:"temperature"`
-}
+// complete sends one request through the cache. version names the prompt
+// templates that produced request. Cache errors are logged and
+// otherwise ignored, since the provider can still answer.
+func (r *Reviewer) complete(ctx context.Context, provider Provider, version string, request CompletionRequest) (CompletionResponse, Call, error) {
+	key := cacheKey(provider, version, request)
+	if r.cache != nil {
+		cached, ok, err := r.cache.GetAIResponse(ctx, key)
+		if err != nil {
+			log.Printf("ai cache lookup failed: %v", err)
+		} else if ok {
+			return cached, Call{Provider: provider.Name(), Model: modelName(provider, cached), Usage: cached.Usage, Cached: true}, nil
+		}
+	}
 
-type chatMessage struct {
-	Role    string `json:"role"`
-	Content string `json:"content"`
+	response, err := provider.Complete(ctx, request)
+	if err != nil {
+		return CompletionResponse{}, Call{}, err
+	}
+	if r.cache != nil {
+		if err := r.cache.PutAIResponse(ctx, key, response, time.Now().Add(r.cacheTTL)); err != nil {
+			log.Printf("ai cache store failed: %v", err)
+		}
+	}
+	return response, Call{Provider: provider.Name(), Model: modelName(provider, response), Usage: response.Usage}, nil
 }
 
-type chatCompletionResponse struct {
-	Choices []struct {
-		Message chatMessage `json:"message"`
-	} `json:"choices"`
-	Error struct {
-		Message string `json:"message"`
-	} `json:"error"`
+func modelName(provider Provider, response CompletionResponse) string {
+	if response.Model != "" {
+		return response.Model
+	}
+	return provider.Model()
 }
 
-func buildPrompt(title string, body string, diff string) string {
-	return fmt.Sprintf(`Review this PR for architectural concerns, performance risks, security issues, maintainability, and API design.
+// ReviewResult is the AI review and the provider calls it took.
+type ReviewResult struct {
+	Issues  []analysis.Issue
+	Summary string
+	Calls   []Call
+	// Redactions summarizes what was withheld from the provider.
+	Redactions string
+	// Dropped counts model findings that failed validation.
+	Dropped int
+	// PromptVersion names the templates the review was prompted with.
+	PromptVersion string
+	// Passes are the focus variants that ran; FailedPasses those whose
+	// provider call failed.
+	Passes       []string
+	FailedPasses []string
+	// PossibleConcerns are findings verification was not confident enough
+	// about to post as line comments.
+	PossibleConcerns []analysis.Issue
+	Verification     VerificationStats
+}
 
-For each issue:
-- Explain why it matters
-- Suggest a concrete improvement
-- Reference specific lines when possible
+// ReviewInput is what the reviewer sees. Findings, Documents and Files are
+// added to the prompt in that priority order until MaxTokens is spent.
+type ReviewInput struct {
+	Title     string
+	Body      string
+	Diff      string
+	Findings  []analysis.Issue
+	Documents []Document
+	Files     []Document
+	MaxTokens int
+	// Provider names the backend to use; empty means the server default.
+	Provider       string
+	SelfHostedOnly bool
+	// RedactPatterns are extra regular expressions redacted before sending.
+	RedactPatterns []string
+	// SecretLines are added lines a secret rule flagged; they are redacted
+	// whole wherever they appear.
+	SecretLines []string
+	Prompt      PromptConfig
+	// Passes are specialist reviews selected by what the diff touches. They
+	// run in addition to the main review with Prompt.Variant.
+	Passes       []Pass
+	Verification VerificationConfig
+}
 
-PR Title: %s
-PR Description: %s
+// buildPrompt renders the system message and prompt. Every piece of pull
+// request or repository content is fenced with a nonce derived from all of
+// it.
+func buildPrompt(prompts *Prompts, variant string, input ReviewInput, diff string, detected []analysis.Issue) (string, string, error) {
+	fitted := fitContext(input)
+	parts := []string{input.Title, input.Body, diff}
+	parts = append(parts, fitted.findings...)
+	for _, document := range append(append([]Document(nil), fitted.documents...), fitted.files...) {
+		parts = append(parts, document.Path, document.Content)
+	}
+	nonce := fenceNonce(parts...)
 
-Diff:
-%s`, title, body, diff)
+	data := promptData{
+		Nonce:     nonce,
+		Detected:  len(detected),
+		Title:     fenced(nonce, "pr-title", input.Title),
+		Body:      fenced(nonce, "pr-description", input.Body),
+		Documents: fencedDocuments(nonce, fitted.documents),
+		Files:     fencedDocuments(nonce, fitted.files),
+		Omitted:   fitted.omitted,
+		Diff:      fenced(nonce, "diff", diff),
+	}
+	if len(fitted.findings) > 0 {
+		data.Findings = fenced(nonce, "findings", strings.Join(fitted.findings, "\n"))
+	}
+	return prompts.render(variant, data)
 }
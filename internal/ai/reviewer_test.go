@@ -0,0 +1,61 @@
+package ai
+
+import (
+	"context"
+	"strings"
+	"testing"
+)
+
+type capturingProvider struct {
+	prompt string
+	reply  string
+}
+
+func (p *capturingProvider) Name() string     { return "capture" }
+func (p *capturingProvider) Model() string    { return "capture-1" }
+func (p *capturingProvider) SelfHosted() bool { return true }
+
+func (p *capturingProvider) Complete(ctx context.Context, request CompletionRequest) (CompletionResponse, error) {
+	p.prompt = request.Prompt
+	return CompletionResponse{Content: p.reply}, nil
+}
+
+func TestReviewerRedactsPromptAndRestoresReply(t *testing.T) {
+	provider := &capturingProvider{reply: "Rotate [REDACTED_SECRET_1] and do not email [REDACTED_EMAIL_1]."}
+	reviewer := NewReviewer("", provider)
+
+	result, err := reviewer.Review(context.Background(), ReviewInput{
+		Title: "Add mailer",
+		Body:  "Owner: ops@example.com",
+		Diff:  "+\tsmtpPassword = \"correct-horse-battery\"\n+\tto := \"ops@example.com\"",
+		Files: []Document{{Path: "mailer.go", Content: "smtpPassword = \"correct-horse-battery\"\n"}},
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if strings.Contains(provider.prompt, "correct-horse-battery") || strings.Contains(provider.prompt, "ops@example.com") {
+		t.Fatalf("secret or email sent to the provider:\n%s", provider.prompt)
+	}
+	if result.Summary != "Rotate correct-horse-battery and do not email ops@example.com." {
+		t.Fatalf("unexpected summary: %s", result.Summary)
+	}
+	if result.Redactions != "1 email address, 1 secret" {
+		t.Fatalf("unexpected redactions: %q", result.Redactions)
+	}
+}
+
+func TestReviewerRedactsFlaggedSecretLines(t *testing.T) {
+	provider := &capturingProvider{reply: "Fine."}
+	line := "\tcfg.Secret = lookupVault(\"payments/prod\")"
+	_, err := NewReviewer("", provider).Review(context.Background(), ReviewInput{
+		Diff:        "+" + line + "\n",
+		Files:       []Document{{Path: "cfg.go", Content: line + "\n"}},
+		SecretLines: []string{line},
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if strings.Contains(provider.prompt, "payments/prod") {
+		t.Fatalf("flagged line sent to the provider:\n%s", provider.prompt)
+	}
+}
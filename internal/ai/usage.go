@@ -0,0 +1,301 @@
+package ai
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Call is one provider request made during a review. Cached calls were
+// answered from the response cache; Usage is what the original request
+// cost.
+type Call struct {
+	Provider string
+	Model    string
+	Usage    Usage
+	Cached   bool
+}
+
+// Price is the cost of a model in US dollars per million tokens.
+type Price struct {
+	InputPerMillion  float64 `json:"input_per_million"`
+	OutputPerMillion float64 `json:"output_per_million"`
+}
+
+// PriceTable maps model names to prices. A model without an exact entry
+// uses the longest entry it starts with, so "gpt-4o-2024-08-06" is priced as
+// "gpt-4o". Unknown models, such as most self-hosted ones, cost nothing.
+type PriceTable map[string]Price
+
+func DefaultPrices() PriceTable {
+	return PriceTable{
+		"gpt-4o":            {InputPerMillion: 2.50, OutputPerMillion: 10.00},
+		"gpt-4o-mini":       {InputPerMillion: 0.15, OutputPerMillion: 0.60},
+		"gpt-4.1":           {InputPerMillion: 2.00, OutputPerMillion: 8.00},
+		"gpt-4.1-mini":      {InputPerMillion: 0.40, OutputPerMillion: 1.60},
+		"claude-3-5-sonnet": {InputPerMillion: 3.00, OutputPerMillion: 15.00},
+		"claude-3-5-haiku":  {InputPerMillion: 0.80, OutputPerMillion: 4.00},
+	}
+}
+
+// LoadPriceTable reads a JSON object of model prices and merges it over the
+// defaults.
+func LoadPriceTable(path string) (PriceTable, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var overrides PriceTable
+	if err := json.Unmarshal(data, &overrides); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	prices := DefaultPrices()
+	for model, price := range overrides {
+		if price.InputPerMillion < 0 || price.OutputPerMillion < 0 {
+			return nil, fmt.Errorf("%s: negative price for %q", path, model)
+		}
+		prices[model] = price
+	}
+	return prices, nil
+}
+
+func (t PriceTable) Lookup(model string) (Price, bool) {
+	if price, ok := t[model]; ok {
+		return price, true
+	}
+	best := ""
+	for name := range t {
+		if strings.HasPrefix(model, name) && len(name) > len(best) {
+			best = name
+		}
+	}
+	if best == "" {
+		return Price{}, false
+	}
+	return t[best], true
+}
+
+func (t PriceTable) Cost(model string, usage Usage) float64 {
+	price, _ := t.Lookup(model)
+	return (float64(usage.InputTokens)*price.InputPerMillion + float64(usage.OutputTokens)*price.OutputPerMillion) / 1e6
+}
+
+// UsageRecord is a priced Call, stored per pull request. One record per
+// review also carries how many AI findings verification checked and
+// dropped, so drop rates can be broken down by repository and PR.
+type UsageRecord struct {
+	Repo            string
+	PullNumber      int
+	CommitSHA       string
+	Provider        string
+	Model           string
+	InputTokens     int
+	OutputTokens    int
+	CostUSD         float64
+	FindingsChecked int
+	FindingsDropped int
+	CreatedAt       time.Time
+}
+
+type UsageTotals struct {
+	Calls           int
+	InputTokens     int
+	OutputTokens    int
+	CostUSD         float64
+	FindingsChecked int
+	FindingsDropped int
+}
+
+func (t *UsageTotals) Add(record UsageRecord) {
+	t.Calls++
+	t.InputTokens += record.InputTokens
+	t.OutputTokens += record.OutputTokens
+	t.CostUSD += record.CostUSD
+	t.FindingsChecked += record.FindingsChecked
+	t.FindingsDropped += record.FindingsDropped
+}
+
+// DropRate is the share of verified findings that were not posted.
+func (t UsageTotals) DropRate() float64 {
+	if t.FindingsChecked == 0 {
+		return 0
+	}
+	return float64(t.FindingsDropped) / float64(t.FindingsChecked)
+}
+
+// UsageQuery selects AI usage for a repository, optionally narrowed to one
+// pull request (PullNumber > 0) and to [From, To) when those are set.
+type UsageQuery struct {
+	Repo       string
+	PullNumber int
+	From       time.Time
+	To         time.Time
+}
+
+func (q UsageQuery) Matches(record UsageRecord) bool {
+	return record.Repo == q.Repo &&
+		(q.PullNumber == 0 || record.PullNumber == q.PullNumber) &&
+		(q.From.IsZero() || !record.CreatedAt.Before(q.From)) &&
+		(q.To.IsZero() || record.CreatedAt.Before(q.To))
+}
+
+// Budget caps AI spend per repository and calendar month (UTC). Repos
+// overrides MonthlyUSD; zero means no limit.
+type Budget struct {
+	MonthlyUSD float64
+	Repos      map[string]float64
+}
+
+func (b Budget) Limit(repo string) float64 {
+	if limit, ok := b.Repos[repo]; ok {
+		return limit
+	}
+	return b.MonthlyUSD
+}
+
+// ParseRepoBudgets reads "owner/repo=50,owner/other=10".
+func ParseRepoBudgets(spec string) (map[string]float64, error) {
+	budgets := map[string]float64{}
+	for _, entry := range strings.Split(spec, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		repo, amount, ok := strings.Cut(entry, "=")
+		if !ok || strings.TrimSpace(repo) == "" {
+			return nil, fmt.Errorf("invalid budget %q: expected owner/repo=amount", entry)
+		}
+		value, err := strconv.ParseFloat(strings.TrimSpace(amount), 64)
+		if err != nil || value < 0 {
+			return nil, fmt.Errorf("invalid budget %q: amount must be a non-negative number", entry)
+		}
+		budgets[strings.TrimSpace(repo)] = value
+	}
+	return budgets, nil
+}
+
+// MonthStart returns the first instant of t's month in UTC.
+func MonthStart(t time.Time) time.Time {
+	t = t.UTC()
+	return time.Date(t.Year(), t.Month(), 1, 0, 0, 0, 0, time.UTC)
+}
+
+// Meter keeps process-wide usage counters for the metrics endpoint.
+type Meter struct {
+	mu             sync.Mutex
+	models         map[meterKey]*UsageTotals
+	cacheHits      map[meterKey]int
+	budgetExceeded int
+	verification   VerificationStats
+}
+
+type meterKey struct {
+	provider string
+	model    string
+}
+
+func NewMeter() *Meter {
+	return &Meter{models: map[meterKey]*UsageTotals{}, cacheHits: map[meterKey]int{}}
+}
+
+func (m *Meter) Record(record UsageRecord) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	key := meterKey{provider: record.Provider, model: record.Model}
+	if m.models[key] == nil {
+		m.models[key] = &UsageTotals{}
+	}
+	m.models[key].Add(record)
+}
+
+func (m *Meter) CacheHit(call Call) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.cacheHits[meterKey{provider: call.Provider, model: call.Model}]++
+}
+
+func (m *Meter) BudgetExceeded() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.budgetExceeded++
+}
+
+// Verified adds a review's verification outcome; the drop rate is
+// dropped over checked findings.
+func (m *Meter) Verified(stats VerificationStats) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.verification.Checked += stats.Checked
+	m.verification.Confirmed += stats.Confirmed
+	m.verification.Downgraded += stats.Downgraded
+	m.verification.Rejected += stats.Rejected
+	m.verification.LowConfidence += stats.LowConfidence
+}
+
+// WritePrometheus writes the counters in the Prometheus text format.
+func (m *Meter) WritePrometheus(w io.Writer) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	keys := sortedKeys(m.models)
+
+	var b strings.Builder
+	b.WriteString("# HELP ai_teammate_ai_requests_total LLM requests made for reviews.\n# TYPE ai_teammate_ai_requests_total counter\n")
+	for _, key := range keys {
+		fmt.Fprintf(&b, "ai_teammate_ai_requests_total{%s} %d\n", key.labels(), m.models[key].Calls)
+	}
+	b.WriteString("# HELP ai_teammate_ai_tokens_total LLM tokens used for reviews.\n# TYPE ai_teammate_ai_tokens_total counter\n")
+	for _, key := range keys {
+		fmt.Fprintf(&b, "ai_teammate_ai_tokens_total{%s,direction=\"input\"} %d\n", key.labels(), m.models[key].InputTokens)
+		fmt.Fprintf(&b, "ai_teammate_ai_tokens_total{%s,direction=\"output\"} %d\n", key.labels(), m.models[key].OutputTokens)
+	}
+	b.WriteString("# HELP ai_teammate_ai_cost_usd_total Estimated LLM cost in US dollars.\n# TYPE ai_teammate_ai_cost_usd_total counter\n")
+	for _, key := range keys {
+		fmt.Fprintf(&b, "ai_teammate_ai_cost_usd_total{%s} %g\n", key.labels(), m.models[key].CostUSD)
+	}
+	b.WriteString("# HELP ai_teammate_ai_cache_hits_total Reviews answered from the AI response cache.\n# TYPE ai_teammate_ai_cache_hits_total counter\n")
+	for _, key := range sortedKeys(m.cacheHits) {
+		fmt.Fprintf(&b, "ai_teammate_ai_cache_hits_total{%s} %d\n", key.labels(), m.cacheHits[key])
+	}
+	b.WriteString("# HELP ai_teammate_ai_budget_exceeded_total Reviews posted without AI because the repository's monthly budget was used up.\n# TYPE ai_teammate_ai_budget_exceeded_total counter\n")
+	fmt.Fprintf(&b, "ai_teammate_ai_budget_exceeded_total %d\n", m.budgetExceeded)
+	b.WriteString("# HELP ai_teammate_ai_findings_verified_total AI findings re-checked by the verification pass, by outcome.\n# TYPE ai_teammate_ai_findings_verified_total counter\n")
+	for _, outcome := range []struct {
+		name  string
+		count int
+	}{
+		{"confirmed", m.verification.Confirmed},
+		{"downgraded", m.verification.Downgraded},
+		{"rejected", m.verification.Rejected},
+		{"low_confidence", m.verification.LowConfidence},
+	} {
+		fmt.Fprintf(&b, "ai_teammate_ai_findings_verified_total{outcome=%q} %d\n", outcome.name, outcome.count)
+	}
+	b.WriteString("# HELP ai_teammate_ai_findings_drop_rate Share of verified AI findings that were rejected or below the confidence threshold.\n# TYPE ai_teammate_ai_findings_drop_rate gauge\n")
+	fmt.Fprintf(&b, "ai_teammate_ai_findings_drop_rate %g\n", m.verification.DropRate())
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+func (k meterKey) labels() string {
+	return fmt.Sprintf("provider=%s,model=%s", strconv.Quote(k.provider), strconv.Quote(k.model))
+}
+
+func sortedKeys[V any](counters map[meterKey]V) []meterKey {
+	keys := make([]meterKey, 0, len(counters))
+	for key := range counters {
+		keys = append(keys, key)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		if keys[i].provider != keys[j].provider {
+			return keys[i].provider < keys[j].provider
+		}
+		return keys[i].model < keys[j].model
+	})
+	return keys
+}
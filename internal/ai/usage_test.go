@@ -0,0 +1,80 @@
+package ai
+
+import (
+	"math"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestPriceTableCost(t *testing.T) {
+	prices := DefaultPrices()
+	usage := Usage{InputTokens: 200000, OutputTokens: 10000}
+	if cost := prices.Cost("gpt-4o-mini-2024-07-18", usage); math.Abs(cost-0.036) > 1e-9 {
+		t.Fatalf("expected gpt-4o-mini pricing, got %v", cost)
+	}
+	if cost := prices.Cost("gpt-4o-2024-08-06", usage); math.Abs(cost-0.6) > 1e-9 {
+		t.Fatalf("expected gpt-4o pricing, got %v", cost)
+	}
+	if cost := prices.Cost("llama3.1:70b", usage); cost != 0 {
+		t.Fatalf("expected unknown models to be free, got %v", cost)
+	}
+}
+
+func TestParseRepoBudgets(t *testing.T) {
+	budgets, err := ParseRepoBudgets("acme/api=50, acme/web=12.5,")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	budget := Budget{MonthlyUSD: 20, Repos: budgets}
+	if budget.Limit("acme/api") != 50 || budget.Limit("acme/web") != 12.5 || budget.Limit("acme/docs") != 20 {
+		t.Fatalf("unexpected budgets: %v", budgets)
+	}
+	if _, err := ParseRepoBudgets("acme/api"); err == nil {
+		t.Fatalf("expected an error for a missing amount")
+	}
+}
+
+func TestUsageQueryAndMeter(t *testing.T) {
+	may := time.Date(2024, 5, 31, 23, 0, 0, 0, time.UTC)
+	record := UsageRecord{Repo: "acme/api", PullNumber: 7, Provider: "openai", Model: "gpt-4o-mini", InputTokens: 100, OutputTokens: 20, CostUSD: 0.5, CreatedAt: may}
+	if !(UsageQuery{Repo: "acme/api", From: MonthStart(may)}).Matches(record) {
+		t.Fatalf("expected the record to count towards May")
+	}
+	if (UsageQuery{Repo: "acme/api", From: MonthStart(may.Add(2 * time.Hour))}).Matches(record) {
+		t.Fatalf("expected the record not to count towards June")
+	}
+	if (UsageQuery{Repo: "acme/api", PullNumber: 8}).Matches(record) {
+		t.Fatalf("expected the record not to match another PR")
+	}
+
+	var totals UsageTotals
+	totals.Add(UsageRecord{InputTokens: 100, FindingsChecked: 4, FindingsDropped: 1})
+	totals.Add(UsageRecord{InputTokens: 50})
+	totals.Add(UsageRecord{InputTokens: 10, FindingsChecked: 4, FindingsDropped: 3})
+	if totals.Calls != 3 || totals.FindingsChecked != 8 || totals.DropRate() != 0.5 {
+		t.Fatalf("unexpected totals: %+v", totals)
+	}
+
+	meter := NewMeter()
+	meter.Record(record)
+	meter.Record(record)
+	meter.BudgetExceeded()
+	meter.Verified(VerificationStats{Checked: 4, Confirmed: 3, Rejected: 1})
+	var out strings.Builder
+	if err := meter.WritePrometheus(&out); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	for _, want := range []string{
+		`ai_teammate_ai_requests_total{provider="openai",model="gpt-4o-mini"} 2`,
+		`ai_teammate_ai_tokens_total{provider="openai",model="gpt-4o-mini",direction="input"} 200`,
+		`ai_teammate_ai_cost_usd_total{provider="openai",model="gpt-4o-mini"} 1`,
+		`ai_teammate_ai_budget_exceeded_total 1`,
+		`ai_teammate_ai_findings_verified_total{outcome="rejected"} 1`,
+		`ai_teammate_ai_findings_drop_rate 0.25`,
+	} {
+		if !strings.Contains(out.String(), want) {
+			t.Fatalf("metrics missing %q:\n%s", want, out.String())
+		}
+	}
+}
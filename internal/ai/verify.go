@@ -0,0 +1,197 @@
+package ai
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/example/pr-ai-teammate/internal/analysis"
+	"github.com/example/pr-ai-teammate/internal/redact"
+)
+
+// DefaultMinConfidence is the verification confidence below which an AI
+// finding is not posted as a line comment.
+const DefaultMinConfidence = 0.6
+
+// contextRadius is how many lines around a finding the verifier sees.
+const contextRadius = 6
+
+// VerificationConfig controls the second pass that re-checks AI findings
+// against their code. Findings the model rejects are dropped; those below
+// MinConfidence are dropped with DropLowConfidence, and otherwise listed as
+// possible concerns in the summary. A MinConfidence of 0 keeps every
+// confirmed or downgraded finding.
+type VerificationConfig struct {
+	Enabled           bool
+	MinConfidence     float64
+	DropLowConfidence bool
+	// Provider runs the verification; empty means the review's provider.
+	Provider string
+}
+
+// VerificationStats counts what verification did with the AI findings.
+type VerificationStats struct {
+	Checked       int
+	Confirmed     int
+	Downgraded    int
+	Rejected      int
+	LowConfidence int
+	// Failed is set when the findings could not be verified and were kept
+	// as they were.
+	Failed bool
+}
+
+// Dropped is the number of checked findings not posted as line comments.
+func (s VerificationStats) Dropped() int {
+	return s.Rejected + s.LowConfidence
+}
+
+func (s VerificationStats) DropRate() float64 {
+	if s.Checked == 0 {
+		return 0
+	}
+	return float64(s.Dropped()) / float64(s.Checked)
+}
+
+type verdictReply struct {
+	Verdicts []struct {
+		ID         int     `json:"id"`
+		Verdict    string  `json:"verdict"`
+		Severity   string  `json:"severity"`
+		Confidence float64 `json:"confidence"`
+		Reason     string  `json:"reason"`
+	} `json:"verdicts"`
+}
+
+// verification is the outcome of re-checking a set of findings.
+type verification struct {
+	kept     []analysis.Issue
+	concerns []analysis.Issue
+	stats    VerificationStats
+	call     Call
+}
+
+// verify asks the model to confirm, downgrade or reject each finding given
+// the code around it. input is the redacted review input; findings carry
+// restored text and are redacted again before they are sent.
+func (r *Reviewer) verify(ctx context.Context, prompts *Prompts, redactor *redact.Redactor, input ReviewInput, findings []analysis.Issue) (verification, error) {
+	name := input.Verification.Provider
+	if name == "" {
+		name = input.Provider
+	}
+	provider, err := r.provider(name, input.SelfHostedOnly)
+	if err != nil {
+		return verification{}, err
+	}
+	files, _ := analysis.ParseUnifiedDiff(input.Diff)
+	blocks := make([]string, len(findings))
+	for i, finding := range findings {
+		blocks[i] = fmt.Sprintf("%s:%d [%s] %s\nSuggestion: %s\n\nCode:\n%s",
+			finding.File, finding.Line, finding.Severity, redactor.Redact(finding.Message), redactor.Redact(finding.Suggestion),
+			codeContext(input.Files, files, finding.File, finding.Line))
+	}
+	nonce := fenceNonce(blocks...)
+	var builder strings.Builder
+	for i, block := range blocks {
+		fmt.Fprintf(&builder, "Finding %d:\n%s\n", i+1, fenced(nonce, fmt.Sprintf("finding-%d", i+1), block))
+	}
+
+	system, prompt, err := prompts.renderVerification(promptData{Nonce: nonce, Findings: builder.String()})
+	if err != nil {
+		return verification{}, err
+	}
+	response, call, err := r.complete(ctx, provider, prompts.Version(), CompletionRequest{System: system, Prompt: prompt})
+	if err != nil {
+		return verification{}, err
+	}
+	var reply verdictReply
+	if err := decodeReply(response.Content, &reply); err != nil {
+		return verification{call: call}, fmt.Errorf("verification reply is not valid JSON: %w", err)
+	}
+	result := applyVerdicts(findings, reply, input.Verification)
+	result.call = call
+	return result, nil
+}
+
+// applyVerdicts sorts findings by the model's verdicts. A finding without a
+// verdict counts as having no confidence.
+func applyVerdicts(findings []analysis.Issue, reply verdictReply, settings VerificationConfig) verification {
+	result := verification{stats: VerificationStats{Checked: len(findings)}}
+	for i, finding := range findings {
+		verdict, confidence := "", 0.0
+		for _, candidate := range reply.Verdicts {
+			if candidate.ID == i+1 {
+				verdict, confidence = strings.ToLower(strings.TrimSpace(candidate.Verdict)), candidate.Confidence
+				if confidence > 1 && confidence <= 100 {
+					confidence /= 100
+				}
+				if verdict == "downgrade" {
+					finding.Severity = downgrade(finding.Severity, candidate.Severity)
+				}
+				break
+			}
+		}
+		switch {
+		case verdict == "reject":
+			result.stats.Rejected++
+		case confidence < settings.MinConfidence || (verdict != "confirm" && verdict != "downgrade"):
+			result.stats.LowConfidence++
+			if !settings.DropLowConfidence {
+				result.concerns = append(result.concerns, finding)
+			}
+		default:
+			if verdict == "downgrade" {
+				result.stats.Downgraded++
+			} else {
+				result.stats.Confirmed++
+			}
+			result.kept = append(result.kept, finding)
+		}
+	}
+	return result
+}
+
+// downgrade lowers severity to the suggested one, or by one level when the
+// suggestion is missing or not lower.
+func downgrade(severity, suggested string) string {
+	suggested = normalizeSeverity(suggested)
+	if severityRank(suggested) > severityRank(severity) {
+		return suggested
+	}
+	switch severity {
+	case "high":
+		return "medium"
+	default:
+		return "low"
+	}
+}
+
+// codeContext returns the numbered lines around line, from the touched file
+// when its content is known and otherwise from the lines the diff added.
+func codeContext(documents []Document, files []analysis.FileDiff, path string, line int) string {
+	var builder strings.Builder
+	for _, document := range documents {
+		if document.Path != path {
+			continue
+		}
+		lines := strings.Split(document.Content, "\n")
+		for number := max(1, line-contextRadius); number <= min(len(lines), line+contextRadius); number++ {
+			fmt.Fprintf(&builder, "%5d | %s\n", number, lines[number-1])
+		}
+		return builder.String()
+	}
+	for _, file := range files {
+		if file.Path != path {
+			continue
+		}
+		for _, added := range file.AddedLines {
+			if added.Number >= line-contextRadius && added.Number <= line+contextRadius {
+				fmt.Fprintf(&builder, "%5d | %s\n", added.Number, added.Content)
+			}
+		}
+		if builder.Len() > 0 {
+			return "(added lines only)\n" + builder.String()
+		}
+	}
+	return "(code not available)\n"
+}
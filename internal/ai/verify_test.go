@@ -0,0 +1,109 @@
+package ai
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/example/pr-ai-teammate/internal/analysis"
+)
+
+func TestApplyVerdicts(t *testing.T) {
+	findings := []analysis.Issue{
+		{File: "a.go", Line: 1, Severity: "high", Message: "confirmed"},
+		{File: "a.go", Line: 2, Severity: "high", Message: "downgraded"},
+		{File: "a.go", Line: 3, Severity: "medium", Message: "rejected"},
+		{File: "a.go", Line: 4, Severity: "medium", Message: "unsure"},
+		{File: "a.go", Line: 5, Severity: "low", Message: "no verdict"},
+	}
+	var reply verdictReply
+	if err := decodeReply("```json\n"+`{"verdicts": [
+		{"id": 1, "verdict": "confirm", "confidence": 0.95},
+		{"id": 2, "verdict": "downgrade", "severity": "low", "confidence": 80},
+		{"id": 3, "verdict": "reject", "confidence": 0.9},
+		{"id": 4, "verdict": "confirm", "confidence": 0.4}
+	]}`+"\n```", &reply); err != nil {
+		t.Fatal(err)
+	}
+
+	result := applyVerdicts(findings, reply, VerificationConfig{Enabled: true, MinConfidence: DefaultMinConfidence})
+	if len(result.kept) != 2 || result.kept[0].Message != "confirmed" || result.kept[1].Severity != "low" {
+		t.Fatalf("unexpected kept findings %+v", result.kept)
+	}
+	if len(result.concerns) != 2 || result.concerns[0].Message != "unsure" || result.concerns[1].Message != "no verdict" {
+		t.Fatalf("unexpected concerns %+v", result.concerns)
+	}
+	want := VerificationStats{Checked: 5, Confirmed: 1, Downgraded: 1, Rejected: 1, LowConfidence: 2}
+	if result.stats != want || result.stats.DropRate() != 0.6 {
+		t.Fatalf("got stats %+v, want %+v", result.stats, want)
+	}
+
+	result = applyVerdicts(findings, reply, VerificationConfig{Enabled: true, MinConfidence: 0.3, DropLowConfidence: true})
+	if len(result.kept) != 3 || len(result.concerns) != 0 || result.stats.LowConfidence != 1 {
+		t.Fatalf("unexpected result with a lower threshold %+v", result)
+	}
+
+	result = applyVerdicts(findings, reply, VerificationConfig{Enabled: true, MinConfidence: 0})
+	if len(result.kept) != 3 || len(result.concerns) != 1 || result.concerns[0].Message != "no verdict" {
+		t.Fatalf("expected a zero threshold to keep every confirmed finding, got %+v", result)
+	}
+}
+
+type verifyingProvider struct {
+	review  string
+	verdict string
+	prompts []string
+}
+
+func (p *verifyingProvider) Name() string     { return "verify" }
+func (p *verifyingProvider) Model() string    { return "verify-1" }
+func (p *verifyingProvider) SelfHosted() bool { return true }
+
+func (p *verifyingProvider) Complete(ctx context.Context, request CompletionRequest) (CompletionResponse, error) {
+	p.prompts = append(p.prompts, request.Prompt)
+	if strings.HasPrefix(request.Prompt, "You reviewed a pull request") {
+		return CompletionResponse{Content: p.verdict}, nil
+	}
+	return CompletionResponse{Content: p.review}, nil
+}
+
+func TestReviewVerifiesFindings(t *testing.T) {
+	diff := newFileDiff("cache.go", "package cache", "", "var apiKey = \"sk-live-0123456789abcdef\"", "func get(k string) string {", "\treturn m[k]", "}")
+	provider := &verifyingProvider{
+		review: `{"summary": "Two issues.", "findings": [
+			{"file": "cache.go", "line": 3, "severity": "high", "message": "Hard-coded key sk-live-0123456789abcdef."},
+			{"file": "cache.go", "line": 5, "severity": "high", "message": "Map read without a lock may race."}
+		]}`,
+		verdict: `{"verdicts": [{"id": 1, "verdict": "confirm", "confidence": 0.9}, {"id": 2, "verdict": "confirm", "confidence": 0.3}]}`,
+	}
+	input := ReviewInput{
+		Diff:         diff,
+		Files:        []Document{{Path: "cache.go", Content: "package cache\n\nvar apiKey = \"sk-live-0123456789abcdef\"\nfunc get(k string) string {\n\treturn m[k]\n}\n"}},
+		Verification: VerificationConfig{Enabled: true, MinConfidence: DefaultMinConfidence},
+	}
+
+	result, err := NewReviewer("", provider).Review(context.Background(), input)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(provider.prompts) != 2 || len(result.Calls) != 2 {
+		t.Fatalf("expected a review and a verification call, got %d prompts and %d calls", len(provider.prompts), len(result.Calls))
+	}
+	verification := provider.prompts[1]
+	if strings.Contains(verification, "sk-live-0123456789abcdef") || !strings.Contains(verification, "    5 | \treturn m[k]") {
+		t.Fatalf("verification prompt should show redacted code context:\n%s", verification)
+	}
+	if len(result.Issues) != 1 || result.Issues[0].Line != 3 || !strings.Contains(result.Issues[0].Message, "sk-live-0123456789abcdef") {
+		t.Fatalf("unexpected issues %+v", result.Issues)
+	}
+	if len(result.PossibleConcerns) != 1 || result.PossibleConcerns[0].Line != 5 || result.PossibleConcerns[0].PromptVersion != PromptVersion {
+		t.Fatalf("unexpected possible concerns %+v", result.PossibleConcerns)
+	}
+
+	provider.verdict = "I think they are all fine."
+	provider.prompts = nil
+	result, err = NewReviewer("", provider).Review(context.Background(), input)
+	if err != nil || !result.Verification.Failed || len(result.Issues) != 2 {
+		t.Fatalf("expected unverified findings to be kept, got %+v (err %v)", result, err)
+	}
+}
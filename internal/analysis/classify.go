@@ -1,10 +1,37 @@
 package analysis
 
-import "strings"
+import (
+	"path"
+	"strings"
+)
+
+var dependencyFiles = map[string]bool{
+	"go.mod":            true,
+	"go.sum":            true,
+	"package.json":      true,
+	"package-lock.json": true,
+	"yarn.lock":         true,
+	"pnpm-lock.yaml":    true,
+	"poetry.lock":       true,
+}
 
 func ClassifyPath(path string) FileType {
 	lower := strings.ToLower(path)
+	if isVendored(lower) {
+		return FileTypeVendored
+	}
+	return classifySource(lower)
+}
+
+// classifySource classifies a lowercased path as if it were not vendored.
+func classifySource(lower string) FileType {
 	switch {
+	case isDependencyFile(lower):
+		return FileTypeDependency
+	case isDockerfile(lower):
+		return FileTypeDockerfile
+	case strings.Contains("/"+lower, "/.github/workflows/") && IsYAML(lower):
+		return FileTypeWorkflow
 	case strings.Contains(lower, "/test/") || strings.HasSuffix(lower, "_test.go") || strings.HasSuffix(lower, ".spec.ts") || strings.HasSuffix(lower, ".test.ts"):
 		return FileTypeTest
 	case strings.HasSuffix(lower, ".yml") || strings.HasSuffix(lower, ".yaml") || strings.HasSuffix(lower, ".json") || strings.Contains(lower, "/config/"):
@@ -13,3 +40,31 @@ func ClassifyPath(path string) FileType {
 		return FileTypeProd
 	}
 }
+
+func isDependencyFile(lower string) bool {
+	base := path.Base(lower)
+	if dependencyFiles[base] {
+		return true
+	}
+	return strings.HasPrefix(base, "requirements") && strings.HasSuffix(base, ".txt")
+}
+
+func IsYAML(path string) bool {
+	lower := strings.ToLower(path)
+	return strings.HasSuffix(lower, ".yaml") || strings.HasSuffix(lower, ".yml")
+}
+
+// RefineFileType narrows a YAML config file's type once its content is
+// known: documents declaring apiVersion and kind are Kubernetes objects,
+// including Helm templates.
+func RefineFileType(file FileDiff, content string) FileType {
+	if file.Type != FileTypeConfig || !IsYAML(file.Path) {
+		return file.Type
+	}
+	for _, document := range ParseYAMLDocuments(content) {
+		if document.Get("apiVersion").Text() != "" && document.Get("kind").Text() != "" {
+			return FileTypeKubernetes
+		}
+	}
+	return file.Type
+}
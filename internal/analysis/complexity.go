@@ -0,0 +1,457 @@
+package analysis
+
+import (
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+type FunctionMetrics struct {
+	File       string
+	Name       string
+	Line       int
+	EndLine    int
+	Cyclomatic int
+	Cognitive  int
+}
+
+type ComplexityChange struct {
+	FunctionMetrics
+	BaseCyclomatic int
+	BaseCognitive  int
+	IsNew          bool
+}
+
+type ComplexityThresholds struct {
+	MaxCyclomatic int
+	MaxCognitive  int
+}
+
+// AnalyzeComplexity measures every Go function touched by the diff and
+// compares it with the same function at the base revision.
+func AnalyzeComplexity(files []FileDiff, contents map[string]string, baseContents map[string]string, thresholds ComplexityThresholds) ([]ComplexityChange, []Issue) {
+	var changes []ComplexityChange
+	var issues []Issue
+	for _, file := range files {
+		if filepath.Ext(file.Path) != ".go" || file.Type == FileTypeTest {
+			continue
+		}
+		source, ok := contents[file.Path]
+		if !ok {
+			continue
+		}
+		head, err := ComputeFunctionMetrics(file.Path, source)
+		if err != nil {
+			continue
+		}
+
+		base := map[string]FunctionMetrics{}
+		if baseSource, ok := baseContents[file.Path]; ok {
+			baseMetrics, err := ComputeFunctionMetrics(file.Path, baseSource)
+			if err == nil {
+				for _, metrics := range baseMetrics {
+					base[metrics.Name] = metrics
+				}
+			}
+		}
+
+		for _, metrics := range head {
+			if !spansAddedLine(file, metrics.Line, metrics.EndLine) {
+				continue
+			}
+			change := ComplexityChange{FunctionMetrics: metrics, IsNew: true}
+			if previous, ok := base[metrics.Name]; ok {
+				change.IsNew = false
+				change.BaseCyclomatic = previous.Cyclomatic
+				change.BaseCognitive = previous.Cognitive
+			}
+			changes = append(changes, change)
+			issues = append(issues, complexityIssues(change, thresholds)...)
+		}
+	}
+	return changes, issues
+}
+
+func complexityIssues(change ComplexityChange, thresholds ComplexityThresholds) []Issue {
+	var issues []Issue
+	if thresholds.MaxCyclomatic > 0 && change.Cyclomatic > thresholds.MaxCyclomatic {
+		issues = append(issues, Issue{
+			File:     change.File,
+			Line:     change.Line,
+			RuleID:   "cyclomatic-complexity",
+			Severity: "medium",
+			Message:  fmt.Sprintf("%s has cyclomatic complexity %d (limit %d)%s; consider splitting it.", change.Name, change.Cyclomatic, thresholds.MaxCyclomatic, deltaSuffix(change.IsNew, change.BaseCyclomatic)),
+		})
+	}
+	if thresholds.MaxCognitive > 0 && change.Cognitive > thresholds.MaxCognitive {
+		issues = append(issues, Issue{
+			File:     change.File,
+			Line:     change.Line,
+			RuleID:   "cognitive-complexity",
+			Severity: "medium",
+			Message:  fmt.Sprintf("%s has cognitive complexity %d (limit %d)%s; consider flattening nested logic.", change.Name, change.Cognitive, thresholds.MaxCognitive, deltaSuffix(change.IsNew, change.BaseCognitive)),
+		})
+	}
+	return issues
+}
+
+func deltaSuffix(isNew bool, base int) string {
+	if isNew {
+		return ""
+	}
+	return fmt.Sprintf(", was %d", base)
+}
+
+// ComplexityDeltas renders one summary line per changed function whose
+// complexity moved, e.g. "HandleLogin complexity 8 → 17".
+func ComplexityDeltas(changes []ComplexityChange) []string {
+	sorted := append([]ComplexityChange{}, changes...)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return sorted[i].Cyclomatic-sorted[i].BaseCyclomatic > sorted[j].Cyclomatic-sorted[j].BaseCyclomatic
+	})
+
+	var lines []string
+	for _, change := range sorted {
+		switch {
+		case change.IsNew:
+			lines = append(lines, fmt.Sprintf("`%s` (new) complexity %d, cognitive %d", change.Name, change.Cyclomatic, change.Cognitive))
+		case change.Cyclomatic != change.BaseCyclomatic || change.Cognitive != change.BaseCognitive:
+			lines = append(lines, fmt.Sprintf("`%s` complexity %d → %d, cognitive %d → %d", change.Name, change.BaseCyclomatic, change.Cyclomatic, change.BaseCognitive, change.Cognitive))
+		}
+	}
+	return lines
+}
+
+func ComputeFunctionMetrics(path string, source string) ([]FunctionMetrics, error) {
+	fset := token.NewFileSet()
+	parsed, err := parser.ParseFile(fset, path, source, 0)
+	if err != nil {
+		return nil, err
+	}
+
+	var metrics []FunctionMetrics
+	for _, decl := range parsed.Decls {
+		fn, ok := decl.(*ast.FuncDecl)
+		if !ok || fn.Body == nil {
+			continue
+		}
+		name := funcDeclName(fn)
+		metrics = append(metrics, measureFunction(fset, path, name, selfCall(fn), fn, fn.Body))
+		metrics = append(metrics, measureLiterals(fset, path, name, fn.Body, literalAnchors(fn.Body))...)
+	}
+	return metrics, nil
+}
+
+// measureLiterals measures the function literals in body, naming each after
+// its anchor rather than its position so that adding a literal does not
+// rename the ones after it: HandleLogin.func(go), Serve.func(handler).
+// Literals sharing an anchor are numbered from the second one on.
+func measureLiterals(fset *token.FileSet, path string, prefix string, body *ast.BlockStmt, anchors map[*ast.FuncLit]string) []FunctionMetrics {
+	var metrics []FunctionMetrics
+	seen := map[string]int{}
+	ast.Inspect(body, func(node ast.Node) bool {
+		lit, ok := node.(*ast.FuncLit)
+		if !ok {
+			return true
+		}
+		anchor := anchors[lit]
+		if anchor == "" {
+			anchor = "func"
+		}
+		seen[anchor]++
+		name := fmt.Sprintf("%s.func(%s)", prefix, anchor)
+		if seen[anchor] > 1 {
+			name = fmt.Sprintf("%s#%d", name, seen[anchor])
+		}
+		metrics = append(metrics, measureFunction(fset, path, name, nil, lit, lit.Body))
+		metrics = append(metrics, measureLiterals(fset, path, name, lit.Body, anchors)...)
+		return false
+	})
+	return metrics
+}
+
+// literalAnchors names function literals after what holds them: the go or
+// defer statement, the variable or field they are assigned to, the function
+// they are passed to, or the return statement.
+func literalAnchors(body *ast.BlockStmt) map[*ast.FuncLit]string {
+	anchors := map[*ast.FuncLit]string{}
+	anchor := func(expr ast.Expr, name string) {
+		if lit, ok := expr.(*ast.FuncLit); ok && name != "" && anchors[lit] == "" {
+			anchors[lit] = name
+		}
+	}
+	ast.Inspect(body, func(node ast.Node) bool {
+		switch n := node.(type) {
+		case *ast.GoStmt:
+			anchor(n.Call.Fun, "go")
+		case *ast.DeferStmt:
+			anchor(n.Call.Fun, "defer")
+		case *ast.AssignStmt:
+			for i, rhs := range n.Rhs {
+				if i < len(n.Lhs) {
+					anchor(rhs, anchorName(n.Lhs[i]))
+				}
+			}
+		case *ast.ValueSpec:
+			for i, value := range n.Values {
+				if i < len(n.Names) {
+					anchor(value, n.Names[i].Name)
+				}
+			}
+		case *ast.KeyValueExpr:
+			anchor(n.Value, anchorName(n.Key))
+		case *ast.CallExpr:
+			for _, arg := range n.Args {
+				anchor(arg, calledName(n))
+			}
+		case *ast.ReturnStmt:
+			for _, result := range n.Results {
+				anchor(result, "return")
+			}
+		}
+		return true
+	})
+	return anchors
+}
+
+func anchorName(expr ast.Expr) string {
+	switch e := expr.(type) {
+	case *ast.Ident:
+		if e.Name != "_" {
+			return e.Name
+		}
+	case *ast.SelectorExpr:
+		return e.Sel.Name
+	case *ast.BasicLit:
+		return strings.Trim(e.Value, "`\"")
+	}
+	return ""
+}
+
+func measureFunction(fset *token.FileSet, path string, name string, isSelfCall func(*ast.CallExpr) bool, node ast.Node, body *ast.BlockStmt) FunctionMetrics {
+	cognitive := &cognitiveVisitor{isSelfCall: isSelfCall}
+	cognitive.walk(body)
+	return FunctionMetrics{
+		File:       path,
+		Name:       name,
+		Line:       fset.Position(node.Pos()).Line,
+		EndLine:    fset.Position(node.End()).Line,
+		Cyclomatic: cyclomaticComplexity(body),
+		Cognitive:  cognitive.score,
+	}
+}
+
+// selfCall matches the calls through which fn recurses: Name(...) for a
+// function, and recv.Name(...) on the method's own receiver for a method,
+// so that Close calling s.db.Close is not recursion.
+func selfCall(fn *ast.FuncDecl) func(*ast.CallExpr) bool {
+	if fn.Recv == nil || len(fn.Recv.List) == 0 {
+		return func(call *ast.CallExpr) bool {
+			ident, ok := call.Fun.(*ast.Ident)
+			return ok && ident.Name == fn.Name.Name
+		}
+	}
+	names := fn.Recv.List[0].Names
+	if len(names) == 0 || names[0].Name == "_" {
+		return nil
+	}
+	receiver := names[0].Name
+	return func(call *ast.CallExpr) bool {
+		selector, ok := call.Fun.(*ast.SelectorExpr)
+		if !ok || selector.Sel.Name != fn.Name.Name {
+			return false
+		}
+		x, ok := selector.X.(*ast.Ident)
+		return ok && x.Name == receiver
+	}
+}
+
+func funcDeclName(fn *ast.FuncDecl) string {
+	if fn.Recv == nil || len(fn.Recv.List) == 0 {
+		return fn.Name.Name
+	}
+	recv := fn.Recv.List[0].Type
+	if star, ok := recv.(*ast.StarExpr); ok {
+		recv = star.X
+	}
+	switch t := recv.(type) {
+	case *ast.IndexExpr:
+		recv = t.X
+	case *ast.IndexListExpr:
+		recv = t.X
+	}
+	if ident, ok := recv.(*ast.Ident); ok {
+		return ident.Name + "." + fn.Name.Name
+	}
+	return fn.Name.Name
+}
+
+func spansAddedLine(file FileDiff, start int, end int) bool {
+	for _, line := range file.AddedLines {
+		if line.Number >= start && line.Number <= end {
+			return true
+		}
+	}
+	return false
+}
+
+// cyclomaticComplexity counts decision points the way gocyclo does. Function
+// literals are measured on their own and do not add to the enclosing body.
+func cyclomaticComplexity(body *ast.BlockStmt) int {
+	complexity := 1
+	ast.Inspect(body, func(node ast.Node) bool {
+		switch n := node.(type) {
+		case *ast.FuncLit:
+			return false
+		case *ast.IfStmt, *ast.ForStmt, *ast.RangeStmt:
+			complexity++
+		case *ast.CaseClause:
+			if n.List != nil {
+				complexity++
+			}
+		case *ast.CommClause:
+			if n.Comm != nil {
+				complexity++
+			}
+		case *ast.BinaryExpr:
+			if n.Op == token.LAND || n.Op == token.LOR {
+				complexity++
+			}
+		}
+		return true
+	})
+	return complexity
+}
+
+// cognitiveVisitor implements the SonarSource cognitive complexity model:
+// structural breaks cost one plus the current nesting depth, else branches
+// and mixed boolean operator sequences cost one each.
+type cognitiveVisitor struct {
+	isSelfCall func(*ast.CallExpr) bool
+	nesting    int
+	score      int
+}
+
+func (v *cognitiveVisitor) walk(nodes ...ast.Node) {
+	for _, node := range nodes {
+		if node != nil {
+			ast.Walk(v, node)
+		}
+	}
+}
+
+func (v *cognitiveVisitor) nested(nodes ...ast.Node) {
+	v.nesting++
+	v.walk(nodes...)
+	v.nesting--
+}
+
+func (v *cognitiveVisitor) Visit(node ast.Node) ast.Visitor {
+	switch n := node.(type) {
+	case *ast.FuncLit:
+		return nil
+	case *ast.IfStmt:
+		v.visitIf(n, false)
+		return nil
+	case *ast.SwitchStmt:
+		v.score += 1 + v.nesting
+		v.walk(n.Init, n.Tag)
+		v.nested(n.Body)
+		return nil
+	case *ast.TypeSwitchStmt:
+		v.score += 1 + v.nesting
+		v.walk(n.Init, n.Assign)
+		v.nested(n.Body)
+		return nil
+	case *ast.SelectStmt:
+		v.score += 1 + v.nesting
+		v.nested(n.Body)
+		return nil
+	case *ast.ForStmt:
+		v.score += 1 + v.nesting
+		v.walk(n.Init, n.Cond, n.Post)
+		v.nested(n.Body)
+		return nil
+	case *ast.RangeStmt:
+		v.score += 1 + v.nesting
+		v.walk(n.X)
+		v.nested(n.Body)
+		return nil
+	case *ast.BranchStmt:
+		if n.Tok == token.GOTO || n.Label != nil {
+			v.score++
+		}
+	case *ast.BinaryExpr:
+		if n.Op == token.LAND || n.Op == token.LOR {
+			var operands []ast.Expr
+			ops := flattenLogical(n, nil, &operands)
+			v.score += logicalSequences(ops)
+			for _, operand := range operands {
+				v.walk(operand)
+			}
+			return nil
+		}
+	case *ast.CallExpr:
+		if v.isSelfCall != nil && v.isSelfCall(n) {
+			v.score++
+		}
+	}
+	return v
+}
+
+func (v *cognitiveVisitor) visitIf(n *ast.IfStmt, elseIf bool) {
+	if elseIf {
+		v.score++
+	} else {
+		v.score += 1 + v.nesting
+	}
+	v.walk(n.Init, n.Cond)
+	v.nested(n.Body)
+
+	switch branch := n.Else.(type) {
+	case *ast.IfStmt:
+		v.visitIf(branch, true)
+	case *ast.BlockStmt:
+		v.score++
+		v.nested(branch)
+	}
+}
+
+func flattenLogical(expr ast.Expr, ops []token.Token, operands *[]ast.Expr) []token.Token {
+	switch e := expr.(type) {
+	case *ast.ParenExpr:
+		return flattenLogical(e.X, ops, operands)
+	case *ast.BinaryExpr:
+		if e.Op == token.LAND || e.Op == token.LOR {
+			ops = flattenLogical(e.X, ops, operands)
+			ops = append(ops, e.Op)
+			return flattenLogical(e.Y, ops, operands)
+		}
+	}
+	*operands = append(*operands, expr)
+	return ops
+}
+
+func logicalSequences(ops []token.Token) int {
+	sequences := 0
+	for i, op := range ops {
+		if i == 0 || ops[i-1] != op {
+			sequences++
+		}
+	}
+	return sequences
+}
+
+func calledName(call *ast.CallExpr) string {
+	switch fn := call.Fun.(type) {
+	case *ast.Ident:
+		return fn.Name
+	case *ast.SelectorExpr:
+		return fn.Sel.Name
+	}
+	return ""
+}
@@ -0,0 +1,150 @@
+package analysis
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestComputeFunctionMetrics(t *testing.T) {
+	source := `package auth
+
+func HandleLogin(user string, ok bool) error {
+	if user == "" || !ok {
+		return nil
+	}
+	for i := 0; i < 3; i++ {
+		if i > 1 && ok {
+			continue
+		} else {
+			break
+		}
+	}
+	go func() {
+		if ok {
+			return
+		}
+	}()
+	return nil
+}
+`
+
+	metrics, err := ComputeFunctionMetrics("auth.go", source)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(metrics) != 2 {
+		t.Fatalf("expected function and literal metrics, got %d", len(metrics))
+	}
+	login := metrics[0]
+	if login.Name != "HandleLogin" || login.Cyclomatic != 6 || login.Cognitive != 7 {
+		t.Fatalf("unexpected metrics for HandleLogin: %+v", login)
+	}
+	literal := metrics[1]
+	if literal.Name != "HandleLogin.func(go)" || literal.Cyclomatic != 2 || literal.Cognitive != 1 {
+		t.Fatalf("unexpected metrics for literal: %+v", literal)
+	}
+}
+
+func TestCognitiveComplexityRecursion(t *testing.T) {
+	source := `package store
+
+type Store struct{ db *DB }
+
+func (s *Store) Close() error {
+	return s.db.Close()
+}
+
+func (s *Store) Walk(depth int) {
+	s.Walk(depth - 1)
+}
+
+func Fib(n int) int {
+	return Fib(n-1) + other.Fib(n-2)
+}
+`
+	metrics, err := ComputeFunctionMetrics("store.go", source)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	want := map[string]int{"Store.Close": 0, "Store.Walk": 1, "Fib": 1}
+	for _, metric := range metrics {
+		if score, ok := want[metric.Name]; !ok || metric.Cognitive != score {
+			t.Fatalf("unexpected metrics for %s: %+v", metric.Name, metric)
+		}
+	}
+}
+
+func TestAnalyzeComplexityReportsDelta(t *testing.T) {
+	base := `package auth
+
+func Check(a bool) bool {
+	return a
+}
+`
+	head := `package auth
+
+func Check(a bool) bool {
+	if a {
+		if !a {
+			return false
+		}
+	}
+	return a
+}
+`
+	files := []FileDiff{{Path: "auth.go", Type: FileTypeProd, AddedLines: []Line{{Number: 4}}}}
+
+	changes, issues := AnalyzeComplexity(files, map[string]string{"auth.go": head}, map[string]string{"auth.go": base}, ComplexityThresholds{MaxCyclomatic: 2, MaxCognitive: 10})
+	if len(changes) != 1 {
+		t.Fatalf("expected 1 changed function, got %d", len(changes))
+	}
+	if changes[0].IsNew || changes[0].BaseCyclomatic != 1 || changes[0].Cyclomatic != 3 {
+		t.Fatalf("unexpected change: %+v", changes[0])
+	}
+	if len(issues) != 1 || issues[0].RuleID != "cyclomatic-complexity" {
+		t.Fatalf("expected one cyclomatic issue, got %+v", issues)
+	}
+	deltas := ComplexityDeltas(changes)
+	if len(deltas) != 1 || deltas[0] != "`Check` complexity 1 → 3, cognitive 0 → 3" {
+		t.Fatalf("unexpected deltas: %v", deltas)
+	}
+}
+
+func TestLiteralNamesSurviveInsertedLiterals(t *testing.T) {
+	base := `package server
+
+func Serve(mux Mux) {
+	mux.Handle("/health", func() {})
+	defer func() {}()
+}
+`
+	head := `package server
+
+func Serve(mux Mux) {
+	sort.Slice(items, func(i, j int) bool { return i < j })
+	mux.Handle("/health", func() {})
+	mux.Handle("/ready", func() {})
+	defer func() {
+		go func() {}()
+	}()
+}
+`
+	names := func(source string) []string {
+		metrics, err := ComputeFunctionMetrics("server.go", source)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var names []string
+		for _, m := range metrics {
+			names = append(names, m.Name)
+		}
+		return names
+	}
+	if got := strings.Join(names(base), ", "); got != "Serve, Serve.func(Handle), Serve.func(defer)" {
+		t.Fatalf("unexpected base names: %s", got)
+	}
+	want := "Serve, Serve.func(Slice), Serve.func(Handle), Serve.func(Handle)#2, Serve.func(defer), Serve.func(defer).func(go)"
+	if got := strings.Join(names(head), ", "); got != want {
+		t.Fatalf("got names %s, want %s", got, want)
+	}
+}
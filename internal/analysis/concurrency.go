@@ -0,0 +1,401 @@
+package analysis
+
+import (
+	"fmt"
+	"go/ast"
+	"go/token"
+	"go/types"
+	"strconv"
+	"strings"
+)
+
+var lockTypes = map[string]bool{
+	"Mutex":     true,
+	"RWMutex":   true,
+	"WaitGroup": true,
+	"Once":      true,
+	"Cond":      true,
+}
+
+func analyzeConcurrency(fset *token.FileSet, parsed *ast.File, info *types.Info, typed bool, file FileDiff, opts StaticOptions) []Issue {
+	checker := concurrencyChecker{
+		fset:        fset,
+		info:        info,
+		typed:       typed,
+		file:        file,
+		changed:     addedLineSet(file),
+		legacyLoops: !goVersionAtLeast(opts.GoVersions[file.Path], 1, 22),
+	}
+	if !typed {
+		checker.lockStructs = structsWithLocks(parsed, info)
+		checker.waitGroups = waitGroupNames(parsed, info)
+	}
+	for _, decl := range parsed.Decls {
+		fn, ok := decl.(*ast.FuncDecl)
+		if !ok {
+			continue
+		}
+		checker.checkCopiedLocks(fn)
+		if fn.Body != nil {
+			checker.inspectBody(fn.Body, 0)
+		}
+	}
+	return checker.issues
+}
+
+type concurrencyChecker struct {
+	fset *token.FileSet
+	info *types.Info
+	// typed is false when the standard library could not be imported; sync
+	// types are then matched by declaration through lockStructs and
+	// waitGroups.
+	typed       bool
+	file        FileDiff
+	changed     map[int]bool
+	legacyLoops bool
+	lockStructs map[string]bool
+	waitGroups  map[string]bool
+	issues      []Issue
+}
+
+func (c *concurrencyChecker) report(pos token.Pos, ruleID string, message string, suggestion string) {
+	line := c.fset.Position(pos).Line
+	if !c.changed[line] {
+		return
+	}
+	c.issues = append(c.issues, Issue{
+		File:       c.file.Path,
+		Line:       line,
+		RuleID:     ruleID,
+		Severity:   "high",
+		Message:    message,
+		Suggestion: suggestion,
+	})
+}
+
+// inspectBody walks a function body tracking loop depth. Function literals
+// reset the depth because a defer inside them runs when the literal returns.
+func (c *concurrencyChecker) inspectBody(body ast.Node, loopDepth int) {
+	ast.Inspect(body, func(node ast.Node) bool {
+		switch n := node.(type) {
+		case *ast.FuncLit:
+			c.inspectBody(n.Body, 0)
+			return false
+		case *ast.ForStmt:
+			c.inspectLoop(n.Body, c.loopVariables(n), loopDepth)
+			return false
+		case *ast.RangeStmt:
+			c.inspectLoop(n.Body, c.loopVariables(n), loopDepth)
+			return false
+		case *ast.DeferStmt:
+			if loopDepth > 0 {
+				c.report(n.Pos(), "defer-in-loop",
+					"defer inside a loop runs only when the function returns, so resources pile up for every iteration.",
+					"Move the loop body into a helper function (or a closure called per iteration) so each defer runs at the end of its iteration.")
+			}
+		case *ast.GoStmt:
+			c.checkWaitGroupAdd(n)
+		}
+		return true
+	})
+}
+
+func (c *concurrencyChecker) inspectLoop(body *ast.BlockStmt, vars map[types.Object]bool, loopDepth int) {
+	if c.legacyLoops && len(vars) > 0 {
+		ast.Inspect(body, func(node ast.Node) bool {
+			goStmt, ok := node.(*ast.GoStmt)
+			if !ok {
+				return true
+			}
+			if lit, ok := goStmt.Call.Fun.(*ast.FuncLit); ok {
+				c.checkLoopCapture(lit, vars)
+			}
+			return true
+		})
+	}
+	c.inspectBody(body, loopDepth+1)
+}
+
+// checkLoopCapture reports uses of the loop variables themselves, resolved
+// by go/types, so parameters, item := item copies and fields named like a
+// loop variable are not mistaken for them.
+func (c *concurrencyChecker) checkLoopCapture(lit *ast.FuncLit, vars map[types.Object]bool) {
+	reported := map[types.Object]bool{}
+	ast.Inspect(lit.Body, func(node ast.Node) bool {
+		ident, ok := node.(*ast.Ident)
+		if !ok {
+			return true
+		}
+		object := c.info.Uses[ident]
+		if object == nil || !vars[object] || reported[object] {
+			return true
+		}
+		reported[object] = true
+		c.report(ident.Pos(), "loop-var-capture",
+			fmt.Sprintf("goroutine captures loop variable %q; before Go 1.22 every iteration shares one variable, so goroutines may all see the last value.", ident.Name),
+			fmt.Sprintf("Pass %[1]s as an argument (go func(%[1]s T) { ... }(%[1]s)) or copy it with %[1]s := %[1]s before the go statement.", ident.Name))
+		return true
+	})
+}
+
+func (c *concurrencyChecker) checkWaitGroupAdd(goStmt *ast.GoStmt) {
+	lit, ok := goStmt.Call.Fun.(*ast.FuncLit)
+	if !ok {
+		return
+	}
+	ast.Inspect(lit.Body, func(node ast.Node) bool {
+		if _, ok := node.(*ast.FuncLit); ok {
+			return false
+		}
+		call, ok := node.(*ast.CallExpr)
+		if !ok {
+			return true
+		}
+		selector, ok := call.Fun.(*ast.SelectorExpr)
+		if !ok || selector.Sel.Name != "Add" || !c.isWaitGroup(selector.X) {
+			return true
+		}
+		c.report(call.Pos(), "waitgroup-add-in-goroutine",
+			"WaitGroup.Add is called inside the goroutine it accounts for, so Wait can return before the goroutine has started.",
+			"Call Add before the go statement and keep only defer wg.Done() inside the goroutine.")
+		return true
+	})
+}
+
+func (c *concurrencyChecker) checkCopiedLocks(fn *ast.FuncDecl) {
+	if fn.Recv != nil {
+		for _, field := range fn.Recv.List {
+			if name, ok := c.copiesLock(field.Type); ok {
+				c.report(field.Pos(), "lock-copied",
+					fmt.Sprintf("method %s has a value receiver of type %s, which copies its lock; the copy does not protect the original state.", fn.Name.Name, name),
+					fmt.Sprintf("Use a pointer receiver (*%s).", name))
+			}
+		}
+	}
+	for _, field := range fn.Type.Params.List {
+		if name, ok := c.copiesLock(field.Type); ok {
+			c.report(field.Pos(), "lock-copied",
+				fmt.Sprintf("parameter of type %s is passed by value, which copies its lock.", name),
+				fmt.Sprintf("Pass *%s instead.", name))
+		}
+	}
+}
+
+func (c *concurrencyChecker) copiesLock(expr ast.Expr) (string, bool) {
+	if c.typed {
+		if t := c.info.TypeOf(expr); t != nil && t != types.Typ[types.Invalid] {
+			return types.ExprString(expr), containsLock(t, map[types.Type]bool{})
+		}
+	}
+	switch t := expr.(type) {
+	case *ast.Ident:
+		return t.Name, c.lockStructs[t.Name]
+	case *ast.SelectorExpr:
+		if selectedPackage(c.info, t) == "sync" && lockTypes[t.Sel.Name] {
+			return "sync." + t.Sel.Name, true
+		}
+	}
+	return "", false
+}
+
+// containsLock reports whether a value of type t holds a sync primitive
+// directly, in a struct field or in an array, so copying it copies the lock.
+func containsLock(t types.Type, seen map[types.Type]bool) bool {
+	if seen[t] {
+		return false
+	}
+	seen[t] = true
+	if isSyncType(t, lockTypes) {
+		return true
+	}
+	switch u := t.Underlying().(type) {
+	case *types.Struct:
+		for i := 0; i < u.NumFields(); i++ {
+			if containsLock(u.Field(i).Type(), seen) {
+				return true
+			}
+		}
+	case *types.Array:
+		return containsLock(u.Elem(), seen)
+	}
+	return false
+}
+
+func isSyncType(t types.Type, names map[string]bool) bool {
+	named, ok := t.(*types.Named)
+	if !ok || named.Obj().Pkg() == nil {
+		return false
+	}
+	return named.Obj().Pkg().Path() == "sync" && names[named.Obj().Name()]
+}
+
+// structsWithLocks finds struct types in the file that embed or hold a sync
+// primitive by value, directly or through another such struct. It is the
+// fallback for when go/types could not resolve the sync package.
+func structsWithLocks(parsed *ast.File, info *types.Info) map[string]bool {
+	fields := map[string][]ast.Expr{}
+	for _, decl := range parsed.Decls {
+		gen, ok := decl.(*ast.GenDecl)
+		if !ok || gen.Tok != token.TYPE {
+			continue
+		}
+		for _, spec := range gen.Specs {
+			typeSpec := spec.(*ast.TypeSpec)
+			structType, ok := typeSpec.Type.(*ast.StructType)
+			if !ok {
+				continue
+			}
+			for _, field := range structType.Fields.List {
+				fields[typeSpec.Name.Name] = append(fields[typeSpec.Name.Name], field.Type)
+			}
+		}
+	}
+
+	locked := map[string]bool{}
+	for changed := true; changed; {
+		changed = false
+		for name, fieldTypes := range fields {
+			if locked[name] {
+				continue
+			}
+			for _, fieldType := range fieldTypes {
+				if isLockValue(fieldType, locked, info) {
+					locked[name] = true
+					changed = true
+					break
+				}
+			}
+		}
+	}
+	return locked
+}
+
+func isLockValue(expr ast.Expr, locked map[string]bool, info *types.Info) bool {
+	switch t := expr.(type) {
+	case *ast.Ident:
+		return locked[t.Name]
+	case *ast.SelectorExpr:
+		return selectedPackage(info, t) == "sync" && lockTypes[t.Sel.Name]
+	case *ast.ArrayType:
+		return t.Len != nil && isLockValue(t.Elt, locked, info)
+	}
+	return false
+}
+
+func (c *concurrencyChecker) isWaitGroup(expr ast.Expr) bool {
+	if c.typed {
+		t := c.info.TypeOf(expr)
+		if pointer, ok := t.(*types.Pointer); ok {
+			t = pointer.Elem()
+		}
+		return t != nil && isSyncType(t, map[string]bool{"WaitGroup": true})
+	}
+	switch x := expr.(type) {
+	case *ast.Ident:
+		return c.waitGroups[x.Name]
+	case *ast.SelectorExpr:
+		return c.waitGroups[x.Sel.Name]
+	}
+	return false
+}
+
+// waitGroupNames lists the fields, parameters and variables in the file
+// declared as a sync.WaitGroup or a pointer to one. It is the fallback for
+// when go/types could not resolve the sync package.
+func waitGroupNames(parsed *ast.File, info *types.Info) map[string]bool {
+	names := map[string]bool{}
+	isWaitGroupType := func(expr ast.Expr) bool {
+		if star, ok := expr.(*ast.StarExpr); ok {
+			expr = star.X
+		}
+		selector, ok := expr.(*ast.SelectorExpr)
+		return ok && selectedPackage(info, selector) == "sync" && selector.Sel.Name == "WaitGroup"
+	}
+	ast.Inspect(parsed, func(node ast.Node) bool {
+		var declared []*ast.Ident
+		switch n := node.(type) {
+		case *ast.Field:
+			if isWaitGroupType(n.Type) {
+				declared = n.Names
+			}
+		case *ast.ValueSpec:
+			if n.Type != nil && isWaitGroupType(n.Type) {
+				declared = n.Names
+			}
+		}
+		for _, name := range declared {
+			names[name.Name] = true
+		}
+		return true
+	})
+	return names
+}
+
+func (c *concurrencyChecker) loopVariables(node ast.Node) map[types.Object]bool {
+	vars := map[types.Object]bool{}
+	add := func(expr ast.Expr) {
+		if ident, ok := expr.(*ast.Ident); ok && ident.Name != "_" && c.info.Defs[ident] != nil {
+			vars[c.info.Defs[ident]] = true
+		}
+	}
+	switch loop := node.(type) {
+	case *ast.RangeStmt:
+		if loop.Tok == token.DEFINE {
+			add(loop.Key)
+			add(loop.Value)
+		}
+	case *ast.ForStmt:
+		if assign, ok := loop.Init.(*ast.AssignStmt); ok && assign.Tok == token.DEFINE {
+			for _, lhs := range assign.Lhs {
+				add(lhs)
+			}
+		}
+	}
+	return vars
+}
+
+func addedLineSet(file FileDiff) map[int]bool {
+	lines := make(map[int]bool, len(file.AddedLines))
+	for _, line := range file.AddedLines {
+		lines[line.Number] = true
+	}
+	return lines
+}
+
+// ParseGoDirective returns the version from a go.mod "go" directive, or ""
+// when the directive is missing.
+func ParseGoDirective(gomod string) string {
+	for _, line := range strings.Split(gomod, "\n") {
+		fields := strings.Fields(line)
+		if len(fields) >= 2 && fields[0] == "go" {
+			return fields[1]
+		}
+	}
+	return ""
+}
+
+// goVersionAtLeast reports whether a go directive such as "1.21" or
+// "1.22.3" is at least major.minor. A missing directive means the module
+// predates the directive and gets legacy semantics.
+func goVersionAtLeast(version string, major int, minor int) bool {
+	parts := strings.SplitN(version, ".", 3)
+	if len(parts) < 2 {
+		return false
+	}
+	gotMajor, err := strconv.Atoi(parts[0])
+	if err != nil {
+		return false
+	}
+	digits := strings.IndexFunc(parts[1], func(r rune) bool { return r < '0' || r > '9' })
+	if digits >= 0 {
+		parts[1] = parts[1][:digits]
+	}
+	gotMinor, err := strconv.Atoi(parts[1])
+	if err != nil {
+		return false
+	}
+	if gotMajor != major {
+		return gotMajor > major
+	}
+	return gotMinor >= minor
+}
@@ -0,0 +1,172 @@
+package analysis
+
+import (
+	"strings"
+	"testing"
+)
+
+const concurrencySource = `package worker
+
+import "sync"
+
+type Counter struct {
+	mu sync.Mutex
+	n  int
+}
+
+func (c Counter) Value() int {
+	return c.n
+}
+
+func Process(items []string) {
+	var wg sync.WaitGroup
+	for _, item := range items {
+		go func() {
+			wg.Add(1)
+			defer wg.Done()
+			handle(item)
+		}()
+		f := open(item)
+		defer f.Close()
+	}
+	wg.Wait()
+}
+`
+
+func TestConcurrencyChecks(t *testing.T) {
+	file := fileWithAllLinesAdded("worker.go", concurrencySource)
+
+	issues := RunStaticAnalysisWithOptions([]FileDiff{file}, map[string]string{"worker.go": concurrencySource}, StaticOptions{GoVersions: map[string]string{"worker.go": "1.21"}})
+	found := map[string]int{}
+	for _, issue := range issues {
+		found[issue.RuleID] = issue.Line
+		if issue.RuleID != "panic" && issue.Suggestion == "" {
+			t.Fatalf("expected suggestion for %s", issue.RuleID)
+		}
+	}
+	expected := map[string]int{
+		"lock-copied":                10,
+		"waitgroup-add-in-goroutine": 18,
+		"loop-var-capture":           20,
+		"defer-in-loop":              23,
+	}
+	for ruleID, line := range expected {
+		if found[ruleID] != line {
+			t.Fatalf("expected %s on line %d, got %d (%+v)", ruleID, line, found[ruleID], issues)
+		}
+	}
+}
+
+func TestLoopCaptureRespectsGoVersion(t *testing.T) {
+	file := fileWithAllLinesAdded("worker.go", concurrencySource)
+
+	issues := RunStaticAnalysisWithOptions([]FileDiff{file}, map[string]string{"worker.go": concurrencySource}, StaticOptions{GoVersions: map[string]string{"worker.go": "1.22.1"}})
+	for _, issue := range issues {
+		if issue.RuleID == "loop-var-capture" {
+			t.Fatalf("did not expect loop-var-capture with Go 1.22 semantics")
+		}
+	}
+}
+
+func TestLoopCaptureIgnoresCopiesAndFields(t *testing.T) {
+	source := `package worker
+
+func Process(items []Item, x Holder) {
+	for _, item := range items {
+		item := item
+		go func() {
+			handle(item)
+		}()
+	}
+	for _, item := range items {
+		go func(item Item) {
+			handle(item, x.item)
+		}(item)
+	}
+	for _, item := range items {
+		use(item)
+		go func() {
+			handle(x.item, Job{item: 1})
+		}()
+	}
+}
+
+type Job struct{ item int }
+`
+	file := fileWithAllLinesAdded("worker.go", source)
+	issues := RunStaticAnalysisWithOptions([]FileDiff{file}, map[string]string{"worker.go": source}, StaticOptions{GoVersions: map[string]string{"worker.go": "1.21"}})
+	for _, issue := range issues {
+		if issue.RuleID == "loop-var-capture" {
+			t.Fatalf("unexpected loop-var-capture on line %d", issue.Line)
+		}
+	}
+}
+
+func TestLoopCaptureUsesNearestModuleGoVersion(t *testing.T) {
+	legacy := fileWithAllLinesAdded("legacy/worker.go", concurrencySource)
+	current := fileWithAllLinesAdded("worker.go", concurrencySource)
+	issues := RunStaticAnalysisWithOptions([]FileDiff{legacy, current},
+		map[string]string{"legacy/worker.go": concurrencySource, "worker.go": concurrencySource},
+		StaticOptions{GoVersions: map[string]string{"legacy/worker.go": "1.20", "worker.go": "1.22"}})
+	var files []string
+	for _, issue := range issues {
+		if issue.RuleID == "loop-var-capture" {
+			files = append(files, issue.File)
+		}
+	}
+	if len(files) != 1 || files[0] != "legacy/worker.go" {
+		t.Fatalf("expected loop-var-capture only in the legacy module, got %v", files)
+	}
+}
+
+const syncTypesSource = `package worker
+
+import "sync"
+
+type Guarded struct {
+	state [2]sync.RWMutex
+}
+
+type counter struct{}
+
+func (counter) Add(n int) {}
+
+func (g Guarded) Snapshot(done *sync.WaitGroup) {
+	var pending sync.WaitGroup
+	var xwg counter
+	go func() {
+		pending.Add(1)
+		done.Add(1)
+		xwg.Add(1)
+	}()
+}
+`
+
+func TestSyncTypesAreResolvedByType(t *testing.T) {
+	check := func(t *testing.T) {
+		file := fileWithAllLinesAdded("worker.go", syncTypesSource)
+		found := map[string][]int{}
+		for _, issue := range RunStaticAnalysis([]FileDiff{file}, map[string]string{"worker.go": syncTypesSource}) {
+			found[issue.RuleID] = append(found[issue.RuleID], issue.Line)
+		}
+		if lines := found["waitgroup-add-in-goroutine"]; len(lines) != 2 || lines[0] != 17 || lines[1] != 18 {
+			t.Fatalf("expected waitgroup-add-in-goroutine on lines 17 and 18, got %v", found)
+		}
+		if lines := found["lock-copied"]; len(lines) != 1 || lines[0] != 13 {
+			t.Fatalf("expected lock-copied on line 13, got %v", found)
+		}
+	}
+	t.Run("typed", check)
+	t.Run("without standard library", func(t *testing.T) {
+		withoutStdlib(t)
+		check(t)
+	})
+}
+
+func fileWithAllLinesAdded(path string, source string) FileDiff {
+	file := FileDiff{Path: path, Type: ClassifyPath(path)}
+	for i := 1; i <= strings.Count(source, "\n"); i++ {
+		file.AddedLines = append(file.AddedLines, Line{Number: i})
+	}
+	return file
+}
@@ -0,0 +1,298 @@
+package analysis
+
+import (
+	"fmt"
+	"go/ast"
+	"go/token"
+	"go/types"
+	"strconv"
+)
+
+var sqlContextVariants = map[string]string{
+	"Query":    "QueryContext",
+	"QueryRow": "QueryRowContext",
+	"Exec":     "ExecContext",
+	"Prepare":  "PrepareContext",
+	"Begin":    "BeginTx",
+	"Ping":     "PingContext",
+}
+
+var httpContextlessCalls = map[string]bool{
+	"NewRequest": true,
+	"Get":        true,
+	"Head":       true,
+	"Post":       true,
+	"PostForm":   true,
+}
+
+// sqlTypes are the database/sql types whose methods have Context variants.
+var sqlTypes = map[string]bool{"DB": true, "Tx": true, "Conn": true, "Stmt": true}
+
+func analyzeContextPropagation(fset *token.FileSet, parsed *ast.File, info *types.Info, typed bool, file FileDiff) []Issue {
+	checker := contextChecker{
+		fset:    fset,
+		info:    info,
+		typed:   typed,
+		file:    file,
+		changed: addedLineSet(file),
+	}
+	if !typed {
+		checker.usesSQL = importsAny(parsed, "database/sql", "github.com/jmoiron/sqlx")
+		checker.clientNames = checker.httpClientNames(parsed)
+	}
+	for _, decl := range parsed.Decls {
+		fn, ok := decl.(*ast.FuncDecl)
+		if !ok || fn.Body == nil {
+			continue
+		}
+		checker.checkFunction(fn)
+	}
+	return checker.issues
+}
+
+type contextChecker struct {
+	fset *token.FileSet
+	info *types.Info
+	// typed is false when the standard library could not be imported; the
+	// receiver checks then fall back to usesSQL and clientNames.
+	typed   bool
+	file    FileDiff
+	changed map[int]bool
+	usesSQL bool
+	// clientNames are the fields, parameters and variables in the file that
+	// are declared as or assigned an http.Client.
+	clientNames map[string]bool
+	issues      []Issue
+}
+
+func (c *contextChecker) report(pos token.Pos, ruleID string, message string, suggestion string) {
+	line := c.fset.Position(pos).Line
+	if !c.changed[line] {
+		return
+	}
+	c.issues = append(c.issues, Issue{
+		File:       c.file.Path,
+		Line:       line,
+		RuleID:     ruleID,
+		Severity:   "medium",
+		Message:    message,
+		Suggestion: suggestion,
+	})
+}
+
+func (c *contextChecker) checkFunction(fn *ast.FuncDecl) {
+	ctxName, hasCtx := c.contextParam(fn.Type)
+	// ioCalls are the calls that do I/O, in source order.
+	var ioCalls []token.Pos
+
+	ast.Inspect(fn.Body, func(node ast.Node) bool {
+		call, ok := node.(*ast.CallExpr)
+		if !ok {
+			return true
+		}
+		selector, ok := call.Fun.(*ast.SelectorExpr)
+		if !ok {
+			return true
+		}
+		pkg := c.packageOf(selector)
+		name := selector.Sel.Name
+		recvPkg, recvType := c.methodOf(selector)
+		isSQL := recvPkg == "database/sql" && sqlTypes[recvType]
+		isClientDo := recvPkg == "net/http" && recvType == "Client" && name == "Do"
+		if !c.typed {
+			isSQL = pkg == "" && c.usesSQL
+			isClientDo = name == "Do" && c.isHTTPClient(selector.X)
+		}
+
+		switch {
+		case pkg == "context" && (name == "Background" || name == "TODO"):
+			if hasCtx {
+				c.report(call.Pos(), "context-dropped",
+					fmt.Sprintf("context.%s() is used although the function already receives %s; cancellation and deadlines from the caller are lost.", name, ctxName),
+					fmt.Sprintf("Pass %s (or a context derived from it) instead.", ctxName))
+			}
+		case pkg == "net/http" && httpContextlessCalls[name]:
+			ioCalls = append(ioCalls, call.Pos())
+			c.report(call.Pos(), "http-without-context",
+				fmt.Sprintf("http.%s issues a request that cannot be cancelled by the caller's context.", name),
+				"Build the request with http.NewRequestWithContext and send it with an http.Client.")
+		case isSQL && sqlContextVariants[name] != "":
+			ioCalls = append(ioCalls, call.Pos())
+			c.report(call.Pos(), "sql-without-context",
+				fmt.Sprintf("%s runs a database call without a context, so it ignores request cancellation and timeouts.", name),
+				fmt.Sprintf("Use %s with the caller's context.", sqlContextVariants[name]))
+		case isSQL && isSQLContextVariant(name):
+			ioCalls = append(ioCalls, call.Pos())
+		case isClientDo:
+			// Other Do methods, such as sync.Once.Do, do no I/O.
+			ioCalls = append(ioCalls, call.Pos())
+		}
+		return true
+	})
+
+	if len(ioCalls) == 0 || hasCtx || !fn.Name.IsExported() || fn.Name.Name == "main" {
+		return
+	}
+	// Report on the first I/O call the diff adds, or on the signature when
+	// the diff only changes that, such as by dropping the ctx parameter.
+	anchor := token.NoPos
+	for _, pos := range ioCalls {
+		if c.changed[c.fset.Position(pos).Line] {
+			anchor = pos
+			break
+		}
+	}
+	if anchor == token.NoPos {
+		anchor = fn.Name.Pos()
+	}
+	c.report(anchor, "io-without-context",
+		fmt.Sprintf("exported function %s performs I/O but does not accept a context.Context.", fn.Name.Name),
+		"Add ctx context.Context as the first parameter and pass it to every I/O call.")
+}
+
+func isSQLContextVariant(name string) bool {
+	for _, variant := range sqlContextVariants {
+		if name == variant {
+			return true
+		}
+	}
+	return false
+}
+
+// methodOf returns the package and receiver type name of the method a
+// selector calls, or empty strings when go/types could not resolve it.
+func (c *contextChecker) methodOf(selector *ast.SelectorExpr) (string, string) {
+	selection := c.info.Selections[selector]
+	if selection == nil || selection.Kind() != types.MethodVal {
+		return "", ""
+	}
+	method, ok := selection.Obj().(*types.Func)
+	if !ok || method.Pkg() == nil {
+		return "", ""
+	}
+	recv := method.Type().(*types.Signature).Recv()
+	if recv == nil {
+		return "", ""
+	}
+	recvType := recv.Type()
+	if pointer, ok := recvType.(*types.Pointer); ok {
+		recvType = pointer.Elem()
+	}
+	named, ok := recvType.(*types.Named)
+	if !ok {
+		return "", ""
+	}
+	return method.Pkg().Path(), named.Obj().Name()
+}
+
+// contextParam returns the name of a context.Context parameter, or of an
+// *http.Request parameter since handlers get their context from the request.
+func (c *contextChecker) contextParam(fnType *ast.FuncType) (string, bool) {
+	for _, field := range fnType.Params.List {
+		expr := field.Type
+		pointer := false
+		if star, ok := expr.(*ast.StarExpr); ok {
+			expr = star.X
+			pointer = true
+		}
+		selector, ok := expr.(*ast.SelectorExpr)
+		if !ok {
+			continue
+		}
+		name := "the request context"
+		if len(field.Names) > 0 {
+			name = field.Names[0].Name
+		}
+		pkg := c.packageOf(selector)
+		if !pointer && pkg == "context" && selector.Sel.Name == "Context" {
+			return name, true
+		}
+		if pointer && pkg == "net/http" && selector.Sel.Name == "Request" {
+			return name + ".Context()", true
+		}
+	}
+	return "", false
+}
+
+func (c *contextChecker) packageOf(selector *ast.SelectorExpr) string {
+	return selectedPackage(c.info, selector)
+}
+
+func importsAny(parsed *ast.File, paths ...string) bool {
+	for _, spec := range parsed.Imports {
+		imported, err := strconv.Unquote(spec.Path.Value)
+		if err != nil {
+			continue
+		}
+		for _, path := range paths {
+			if imported == path {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// isHTTPClient is the syntactic fallback for spotting an http.Client
+// receiver: http.DefaultClient, or a name in clientNames.
+func (c *contextChecker) isHTTPClient(expr ast.Expr) bool {
+	switch x := expr.(type) {
+	case *ast.SelectorExpr:
+		if c.packageOf(x) == "net/http" {
+			return x.Sel.Name == "DefaultClient"
+		}
+		return c.clientNames[x.Sel.Name]
+	case *ast.Ident:
+		return c.clientNames[x.Name]
+	}
+	return false
+}
+
+func (c *contextChecker) httpClientNames(parsed *ast.File) map[string]bool {
+	names := map[string]bool{}
+	ast.Inspect(parsed, func(node ast.Node) bool {
+		switch n := node.(type) {
+		case *ast.Field:
+			if !c.isHTTPClientType(n.Type) {
+				return true
+			}
+			for _, name := range n.Names {
+				names[name.Name] = true
+			}
+			if len(n.Names) == 0 {
+				names["Client"] = true
+			}
+		case *ast.ValueSpec:
+			for i, name := range n.Names {
+				if (n.Type != nil && c.isHTTPClientType(n.Type)) || (i < len(n.Values) && c.isHTTPClientValue(n.Values[i])) {
+					names[name.Name] = true
+				}
+			}
+		case *ast.AssignStmt:
+			for i, lhs := range n.Lhs {
+				ident, ok := lhs.(*ast.Ident)
+				if ok && len(n.Lhs) == len(n.Rhs) && c.isHTTPClientValue(n.Rhs[i]) {
+					names[ident.Name] = true
+				}
+			}
+		}
+		return true
+	})
+	return names
+}
+
+func (c *contextChecker) isHTTPClientValue(expr ast.Expr) bool {
+	if unary, ok := expr.(*ast.UnaryExpr); ok && unary.Op == token.AND {
+		expr = unary.X
+	}
+	lit, ok := expr.(*ast.CompositeLit)
+	return ok && c.isHTTPClientType(lit.Type)
+}
+
+func (c *contextChecker) isHTTPClientType(expr ast.Expr) bool {
+	if star, ok := expr.(*ast.StarExpr); ok {
+		expr = star.X
+	}
+	selector, ok := expr.(*ast.SelectorExpr)
+	return ok && c.packageOf(selector) == "net/http" && selector.Sel.Name == "Client"
+}
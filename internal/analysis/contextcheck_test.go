@@ -0,0 +1,177 @@
+package analysis
+
+import "testing"
+
+const contextSource = `package store
+
+import (
+	"context"
+	"database/sql"
+	"net/http"
+)
+
+type Store struct {
+	db *sql.DB
+}
+
+func (s *Store) Load(ctx context.Context, id int) error {
+	_, err := s.db.QueryContext(context.Background(), "SELECT 1 WHERE id = $1", id)
+	return err
+}
+
+func (s *Store) Count() (int, error) {
+	var n int
+	err := s.db.QueryRow("SELECT COUNT(*) FROM items").Scan(&n)
+	return n, err
+}
+
+func Fetch(url string) (*http.Response, error) {
+	req, err := http.NewRequest(http.MethodGet, url, nil)
+	if err != nil {
+		return nil, err
+	}
+	return http.DefaultClient.Do(req)
+}
+
+func Handle(w http.ResponseWriter, r *http.Request) {
+	_ = context.TODO()
+}
+`
+
+func TestContextPropagationChecks(t *testing.T) {
+	file := fileWithAllLinesAdded("store/store.go", contextSource)
+	issues := RunStaticAnalysis([]FileDiff{file}, map[string]string{file.Path: contextSource})
+
+	found := map[string][]int{}
+	for _, issue := range issues {
+		found[issue.RuleID] = append(found[issue.RuleID], issue.Line)
+	}
+	expected := map[string][]int{
+		"context-dropped":      {14, 33},
+		"sql-without-context":  {20},
+		"http-without-context": {25},
+		"io-without-context":   {20, 25},
+	}
+	for ruleID, lines := range expected {
+		if len(found[ruleID]) != len(lines) {
+			t.Fatalf("expected %s on lines %v, got %v", ruleID, lines, found[ruleID])
+		}
+		for i, line := range lines {
+			if found[ruleID][i] != line {
+				t.Fatalf("expected %s on lines %v, got %v", ruleID, lines, found[ruleID])
+			}
+		}
+	}
+}
+
+func TestDoNeedsAnHTTPClient(t *testing.T) {
+	source := `package cache
+
+import (
+	"net/http"
+	"sync"
+)
+
+type Cache struct {
+	once   sync.Once
+	client *http.Client
+}
+
+func (c *Cache) Init() {
+	c.once.Do(c.load)
+}
+
+func (c *Cache) Refresh(req *http.Request) error {
+	_, err := c.client.Do(req)
+	return err
+}
+
+func Send() error {
+	client := &http.Client{}
+	_, err := client.Do(pending)
+	return err
+}
+
+var pending *http.Request
+`
+	file := fileWithAllLinesAdded("cache/cache.go", source)
+	var lines []int
+	for _, issue := range RunStaticAnalysis([]FileDiff{file}, map[string]string{file.Path: source}) {
+		if issue.RuleID == "io-without-context" {
+			lines = append(lines, issue.Line)
+		}
+	}
+	if len(lines) != 1 || lines[0] != 24 {
+		t.Fatalf("expected io-without-context only for the Do call in Send on line 24, got %v", lines)
+	}
+}
+
+func TestSQLCallsNeedADatabaseSQLReceiver(t *testing.T) {
+	source := `package search
+
+import "database/sql"
+
+type Index struct{}
+
+func (Index) Query(q string) []string { return nil }
+
+type Repo struct {
+	*sql.DB
+	index Index
+}
+
+func (r *Repo) Search(q string) []string {
+	return r.index.Query(q)
+}
+
+func (r *Repo) Delete(id int) error {
+	_, err := r.Exec("DELETE FROM items WHERE id = $1", id)
+	return err
+}
+`
+	file := fileWithAllLinesAdded("search/search.go", source)
+	found := map[string][]int{}
+	for _, issue := range RunStaticAnalysis([]FileDiff{file}, map[string]string{file.Path: source}) {
+		found[issue.RuleID] = append(found[issue.RuleID], issue.Line)
+	}
+	if len(found["sql-without-context"]) != 1 || found["sql-without-context"][0] != 19 {
+		t.Fatalf("expected sql-without-context only for the promoted Exec on line 19, got %v", found)
+	}
+	if len(found["io-without-context"]) != 1 || found["io-without-context"][0] != 19 {
+		t.Fatalf("expected io-without-context only for the Exec in Delete on line 19, got %v", found)
+	}
+}
+
+func TestIOWithoutContextOnAddedCall(t *testing.T) {
+	source := `package client
+
+import "net/http"
+
+func Refresh(client *http.Client) error {
+	resp, err := client.Do(pending)
+	if err != nil {
+		return err
+	}
+	return resp.Body.Close()
+}
+
+var pending *http.Request
+`
+	file := FileDiff{Path: "client/client.go", Type: FileTypeProd, AddedLines: []Line{{Number: 6}}}
+	var lines []int
+	for _, issue := range RunStaticAnalysis([]FileDiff{file}, map[string]string{file.Path: source}) {
+		if issue.RuleID == "io-without-context" {
+			lines = append(lines, issue.Line)
+		}
+	}
+	if len(lines) != 1 || lines[0] != 6 {
+		t.Fatalf("expected io-without-context on the added Do call on line 6, got %v", lines)
+	}
+
+	file.AddedLines = []Line{{Number: 9}}
+	for _, issue := range RunStaticAnalysis([]FileDiff{file}, map[string]string{file.Path: source}) {
+		if issue.RuleID == "io-without-context" {
+			t.Fatalf("expected no finding when neither the call nor the signature changed, got %+v", issue)
+		}
+	}
+}
@@ -2,9 +2,17 @@ package analysis
 
 import (
 	"fmt"
+	"regexp"
 	"strings"
 )
 
+var exportedFuncPattern = regexp.MustCompile(`^func\s+(?:\([^)]*\)\s*)?([A-Z]\w*)\s*[\[(]`)
+
+type ExportedFunc struct {
+	Name string
+	Line int
+}
+
 func ParseUnifiedDiff(diff string) ([]FileDiff, error) {
 	if diff == "" {
 		return nil, nil
@@ -14,6 +22,7 @@ func ParseUnifiedDiff(diff string) ([]FileDiff, error) {
 	var current *FileDiff
 	var lineBuffer []string
 	newLine := 0
+	oldLine := 0
 
 	flush := func() {
 		if current == nil {
@@ -24,6 +33,7 @@ func ParseUnifiedDiff(diff string) ([]FileDiff, error) {
 		current = nil
 		lineBuffer = nil
 		newLine = 0
+		oldLine = 0
 	}
 
 	for _, line := range strings.Split(diff, "\n") {
@@ -34,7 +44,7 @@ func ParseUnifiedDiff(diff string) ([]FileDiff, error) {
 				return nil, err
 			}
 			fileType := ClassifyPath(path)
-			current = &FileDiff{Path: path, Type: fileType}
+			current = &FileDiff{Path: path, Type: fileType, Status: FileModified}
 		}
 
 		if current == nil {
@@ -43,9 +53,20 @@ func ParseUnifiedDiff(diff string) ([]FileDiff, error) {
 
 		lineBuffer = append(lineBuffer, line)
 
+		if newLine == 0 {
+			switch {
+			case strings.HasPrefix(line, "new file mode"):
+				current.Status = FileAdded
+			case strings.HasPrefix(line, "deleted file mode"):
+				current.Status = FileDeleted
+			case strings.HasPrefix(line, "rename from "):
+				current.Status = FileRenamed
+			}
+		}
+
 		if strings.HasPrefix(line, "@@") {
 			var err error
-			newLine, err = parseHunkHeader(line)
+			oldLine, newLine, err = parseHunkHeader(line)
 			if err != nil {
 				return nil, err
 			}
@@ -62,11 +83,17 @@ func ParseUnifiedDiff(diff string) ([]FileDiff, error) {
 		}
 
 		if strings.HasPrefix(line, "-") && !strings.HasPrefix(line, "---") {
+			current.RemovedLines = append(current.RemovedLines, Line{
+				Number:  oldLine,
+				Content: strings.TrimPrefix(line, "-"),
+			})
+			oldLine++
 			continue
 		}
 
 		if newLine > 0 && !strings.HasPrefix(line, "\\") {
 			newLine++
+			oldLine++
 		}
 	}
 
@@ -86,24 +113,41 @@ func parseDiffPath(line string) (string, error) {
 	return path, nil
 }
 
-func parseHunkHeader(line string) (int, error) {
-	parts := strings.Split(line, " ")
-	for _, part := range parts {
-		if strings.HasPrefix(part, "+") {
-			rangePart := strings.TrimPrefix(part, "+")
-			rangePart = strings.TrimPrefix(rangePart, "-")
-			values := strings.Split(rangePart, ",")
-			if len(values) == 0 {
-				break
-			}
-			start, err := parseInt(values[0])
-			if err != nil {
-				return 0, err
-			}
-			return start, nil
+// parseHunkHeader returns the first old and new line numbers of a hunk
+// header such as "@@ -12,4 +12,6 @@ func name()". Only the ranges between
+// the first pair of "@@" markers are read; git's function context after
+// them is arbitrary text.
+func parseHunkHeader(line string) (int, int, error) {
+	ranges, _, found := strings.Cut(strings.TrimPrefix(line, "@@"), "@@")
+	if !found {
+		return 0, 0, fmt.Errorf("invalid hunk header: %s", line)
+	}
+	oldStart, newStart := -1, -1
+	for _, part := range strings.Fields(ranges) {
+		if oldStart >= 0 && newStart >= 0 {
+			break
 		}
+		if len(part) < 2 || (part[0] != '-' && part[0] != '+') {
+			continue
+		}
+		start, err := parseInt(strings.SplitN(part[1:], ",", 2)[0])
+		if err != nil {
+			return 0, 0, err
+		}
+		if part[0] == '-' && oldStart < 0 {
+			oldStart = start
+		}
+		if part[0] == '+' && newStart < 0 {
+			newStart = start
+		}
+	}
+	if newStart < 0 {
+		return 0, 0, fmt.Errorf("invalid hunk header: %s", line)
 	}
-	return 0, fmt.Errorf("invalid hunk header: %s", line)
+	if oldStart < 0 {
+		oldStart = 0
+	}
+	return oldStart, newStart, nil
 }
 
 func parseInt(value string) (int, error) {
@@ -116,3 +160,18 @@ func parseInt(value string) (int, error) {
 	}
 	return num, nil
 }
+
+// AddedExportedFuncs lists exported Go functions and methods declared on
+// added lines of a diff.
+func AddedExportedFuncs(file FileDiff) []ExportedFunc {
+	if !strings.HasSuffix(file.Path, ".go") || file.Type == FileTypeTest {
+		return nil
+	}
+	var funcs []ExportedFunc
+	for _, line := range file.AddedLines {
+		if match := exportedFuncPattern.FindStringSubmatch(line.Content); match != nil {
+			funcs = append(funcs, ExportedFunc{Name: match[1], Line: line.Number})
+		}
+	}
+	return funcs
+}
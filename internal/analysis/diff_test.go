@@ -41,3 +41,93 @@ func TestParseUnifiedDiff(t *testing.T) {
 		t.Fatalf("expected config file type, got %s", files[1].Type)
 	}
 }
+
+func TestFilterToChangedLines(t *testing.T) {
+	files := []FileDiff{
+		{Path: "web/src/app.ts", AddedLines: []Line{{Number: 3, Content: "const x = 1;"}}},
+	}
+	issues := []Issue{
+		{File: "/home/runner/work/app/web/src/app.ts", Line: 3, RuleID: "no-unused-vars"},
+		{File: "web/src/app.ts", Line: 4, RuleID: "unchanged-line"},
+		{File: "web/src/other.ts", Line: 3, RuleID: "unchanged-file"},
+	}
+
+	filtered := FilterToChangedLines(issues, files)
+	if len(filtered) != 1 {
+		t.Fatalf("expected 1 issue, got %d", len(filtered))
+	}
+	if filtered[0].File != "web/src/app.ts" {
+		t.Fatalf("expected path rewritten to diff path, got %q", filtered[0].File)
+	}
+}
+
+func TestMatchPath(t *testing.T) {
+	cases := []struct {
+		pattern string
+		path    string
+		want    bool
+	}{
+		{"*.pb.go", "api/v1/service.pb.go", true},
+		{"vendor/**", "vendor/github.com/lib/pq/conn.go", true},
+		{"db/migrations/**/*.sql", "db/migrations/2024/001_init.sql", true},
+		{"db/migrations/**/*.sql", "db/migrations/001_init.sql", true},
+		{"cmd/*", "cmd/server/main.go", false},
+		{"docs/**", "internal/docs.go", false},
+	}
+	for _, tc := range cases {
+		if got := MatchPath(tc.pattern, tc.path); got != tc.want {
+			t.Fatalf("MatchPath(%q, %q) = %v, want %v", tc.pattern, tc.path, got, tc.want)
+		}
+	}
+}
+
+func TestParseUnifiedDiffRemovedLines(t *testing.T) {
+	diff := strings.Join([]string{
+		"diff --git a/go.mod b/go.mod",
+		"--- a/go.mod",
+		"+++ b/go.mod",
+		"@@ -3,3 +3,3 @@ module example.com/app",
+		" go 1.21",
+		"-require github.com/lib/pq v1.10.9",
+		"+require github.com/lib/pq v1.11.0",
+		" ",
+	}, "\n")
+
+	files, err := ParseUnifiedDiff(diff)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if files[0].Type != FileTypeDependency {
+		t.Fatalf("expected dependency file type, got %s", files[0].Type)
+	}
+	if len(files[0].RemovedLines) != 1 || files[0].RemovedLines[0].Number != 4 {
+		t.Fatalf("unexpected removed lines: %+v", files[0].RemovedLines)
+	}
+	if len(files[0].AddedLines) != 1 || files[0].AddedLines[0].Number != 4 {
+		t.Fatalf("unexpected added lines: %+v", files[0].AddedLines)
+	}
+}
+
+func TestParseUnifiedDiffHunkContext(t *testing.T) {
+	diff := strings.Join([]string{
+		"diff --git a/math.go b/math.go",
+		"--- a/math.go",
+		"+++ b/math.go",
+		"@@ -1,2 +1,3 @@ func neg(x int) int { return -x }",
+		" package math",
+		"+// neg negates x.",
+		" ",
+		"@@ -10,1 +11,2 @@ flags: --verbose +1",
+		" var a = 1",
+		"+var b = 2",
+	}, "\n")
+
+	files, err := ParseUnifiedDiff(diff)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	added := files[0].AddedLines
+	if len(added) != 2 || added[0].Number != 2 || added[1].Number != 12 {
+		t.Fatalf("unexpected added lines: %+v", added)
+	}
+}
@@ -0,0 +1,116 @@
+package analysis
+
+import (
+	"path"
+	"strings"
+)
+
+// DockerInstruction is one logical Dockerfile instruction. Continuation
+// lines are joined, so StartLine and EndLine may differ.
+type DockerInstruction struct {
+	Command   string
+	Flags     []string
+	Args      string
+	StartLine int
+	EndLine   int
+	Stage     int
+}
+
+func isDockerfile(lower string) bool {
+	base := path.Base(lower)
+	return base == "dockerfile" || base == "containerfile" ||
+		strings.HasPrefix(base, "dockerfile.") || strings.HasSuffix(base, ".dockerfile")
+}
+
+// ParseDockerfile splits a Dockerfile into instructions. Comments and blank
+// lines are skipped; Stage counts FROM instructions starting at 0. Only the
+// default backslash escape is supported, and heredoc bodies are kept as part
+// of the instruction that opened them.
+func ParseDockerfile(source string) []DockerInstruction {
+	var instructions []DockerInstruction
+	var current *DockerInstruction
+	var text []string
+	heredoc := ""
+	stage := -1
+
+	finish := func() {
+		if current == nil {
+			return
+		}
+		fields := strings.Fields(strings.Join(text, " "))
+		if len(fields) > 0 {
+			current.Command = strings.ToUpper(fields[0])
+			rest := fields[1:]
+			for len(rest) > 0 && strings.HasPrefix(rest[0], "--") {
+				current.Flags = append(current.Flags, rest[0])
+				rest = rest[1:]
+			}
+			current.Args = strings.Join(rest, " ")
+			if current.Command == "FROM" {
+				stage++
+			}
+			current.Stage = stage
+			instructions = append(instructions, *current)
+		}
+		current = nil
+		text = nil
+	}
+
+	for i, line := range strings.Split(source, "\n") {
+		number := i + 1
+		trimmed := strings.TrimSpace(line)
+		if heredoc != "" {
+			current.EndLine = number
+			if trimmed == heredoc {
+				heredoc = ""
+				finish()
+			}
+			continue
+		}
+		if current == nil && (trimmed == "" || strings.HasPrefix(trimmed, "#")) {
+			continue
+		}
+		if current != nil && strings.HasPrefix(trimmed, "#") {
+			current.EndLine = number
+			continue
+		}
+		if current == nil {
+			current = &DockerInstruction{StartLine: number}
+		}
+		current.EndLine = number
+		continued := strings.HasSuffix(trimmed, "\\")
+		trimmed = strings.TrimSuffix(trimmed, "\\")
+		text = append(text, trimmed)
+		if marker := heredocMarker(trimmed); marker != "" {
+			heredoc = marker
+			continue
+		}
+		if !continued {
+			finish()
+		}
+	}
+	finish()
+	return instructions
+}
+
+func heredocMarker(line string) string {
+	idx := strings.Index(line, "<<")
+	if idx < 0 {
+		return ""
+	}
+	fields := strings.Fields(strings.TrimLeft(line[idx+2:], "-"))
+	if len(fields) == 0 {
+		// A shift at the end of a continued line, as in $((1 << \).
+		return ""
+	}
+	marker := strings.Trim(fields[0], `"'`)
+	if marker == "" {
+		return ""
+	}
+	for _, r := range marker {
+		if r != '_' && (r < 'A' || r > 'Z') && (r < 'a' || r > 'z') && (r < '0' || r > '9') {
+			return ""
+		}
+	}
+	return marker
+}
@@ -0,0 +1,51 @@
+package analysis
+
+import "testing"
+
+func TestParseDockerfile(t *testing.T) {
+	source := `# syntax=docker/dockerfile:1
+FROM golang:1.22 AS build
+RUN apt-get update && \
+    # tools for cgo
+    apt-get install -y gcc
+RUN <<SCRIPT
+echo building
+SCRIPT
+COPY --from=build --chown=app /out/app /app
+
+from gcr.io/distroless/static
+USER 65532`
+
+	instructions := ParseDockerfile(source)
+	if len(instructions) != 6 {
+		t.Fatalf("expected 6 instructions, got %d: %+v", len(instructions), instructions)
+	}
+	run := instructions[1]
+	if run.Command != "RUN" || run.StartLine != 3 || run.EndLine != 5 || run.Args != "apt-get update && apt-get install -y gcc" {
+		t.Fatalf("unexpected continued RUN: %+v", run)
+	}
+	if heredoc := instructions[2]; heredoc.StartLine != 6 || heredoc.EndLine != 8 {
+		t.Fatalf("unexpected heredoc RUN: %+v", heredoc)
+	}
+	copyStep := instructions[3]
+	if len(copyStep.Flags) != 2 || copyStep.Args != "/out/app /app" || copyStep.Stage != 0 {
+		t.Fatalf("unexpected COPY: %+v", copyStep)
+	}
+	if last := instructions[5]; last.Command != "USER" || last.Stage != 1 {
+		t.Fatalf("unexpected final instruction: %+v", last)
+	}
+	if ClassifyPath("deploy/api.Dockerfile") != FileTypeDockerfile || ClassifyPath("Dockerfile") != FileTypeDockerfile {
+		t.Fatalf("expected Dockerfiles to be classified as dockerfile")
+	}
+}
+
+func TestParseDockerfileShiftAtLineEnd(t *testing.T) {
+	source := "FROM alpine\nRUN echo $((1 << \\\n  4))\nUSER app"
+	instructions := ParseDockerfile(source)
+	if len(instructions) != 3 || instructions[1].StartLine != 2 || instructions[1].EndLine != 3 {
+		t.Fatalf("unexpected instructions: %+v", instructions)
+	}
+	if marker := heredocMarker("RUN echo $((1 <<"); marker != "" {
+		t.Fatalf("expected no heredoc marker, got %q", marker)
+	}
+}
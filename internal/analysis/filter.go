@@ -0,0 +1,53 @@
+package analysis
+
+import "strings"
+
+// FilterToChangedLines keeps issues on added lines. Reported paths may be
+// absolute CI paths, so they also match a diff path on a directory boundary.
+func FilterToChangedLines(issues []Issue, files []FileDiff) []Issue {
+	changed := make(map[string]map[int]struct{}, len(files))
+	for _, file := range files {
+		lines := make(map[int]struct{}, len(file.AddedLines))
+		for _, line := range file.AddedLines {
+			lines[line.Number] = struct{}{}
+		}
+		changed[file.Path] = lines
+	}
+
+	var filtered []Issue
+	for _, issue := range issues {
+		path, ok := matchDiffPath(issue.File, changed)
+		if !ok {
+			continue
+		}
+		if _, ok := changed[path][issue.Line]; !ok {
+			continue
+		}
+		issue.File = path
+		filtered = append(filtered, issue)
+	}
+	return filtered
+}
+
+func matchDiffPath(path string, changed map[string]map[int]struct{}) (string, bool) {
+	normalized := normalizeReportedPath(path)
+	if _, ok := changed[normalized]; ok {
+		return normalized, true
+	}
+	best := ""
+	for candidate := range changed {
+		if strings.HasSuffix(normalized, "/"+candidate) && len(candidate) > len(best) {
+			best = candidate
+		}
+	}
+	return best, best != ""
+}
+
+func normalizeReportedPath(path string) string {
+	path = strings.TrimPrefix(path, "file://")
+	path = strings.ReplaceAll(path, "\\", "/")
+	for strings.HasPrefix(path, "./") {
+		path = strings.TrimPrefix(path, "./")
+	}
+	return path
+}
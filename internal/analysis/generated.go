@@ -0,0 +1,151 @@
+package analysis
+
+import (
+	"regexp"
+	"strings"
+)
+
+// generatedHeader matches the standard "Code generated ... DO NOT EDIT."
+// marker (https://go.dev/s/generatedcode) in any common comment style.
+var generatedHeader = regexp.MustCompile(`^\s*(?://|#|/\*|\*|--|;)\s*Code generated .* DO NOT EDIT\.?`)
+
+// vendoredDirs are path segments whose contents are third-party code.
+var vendoredDirs = []string{"vendor", "node_modules", "third_party"}
+
+func isVendored(lower string) bool {
+	for _, segment := range strings.Split(lower, "/") {
+		for _, dir := range vendoredDirs {
+			if segment == dir {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// IsExcluded reports file types that are skipped by rules, static analysis
+// and the AI reviewer.
+func (t FileType) IsExcluded() bool {
+	return t == FileTypeGenerated || t == FileTypeVendored
+}
+
+// IsGeneratedSource reports whether a file carries a generated-code header
+// before its first line of code.
+func IsGeneratedSource(source string) bool {
+	for _, line := range strings.Split(source, "\n") {
+		trimmed := strings.TrimSpace(line)
+		if generatedHeader.MatchString(trimmed) {
+			return true
+		}
+		if trimmed != "" && !isCommentLine(trimmed) {
+			return false
+		}
+	}
+	return false
+}
+
+func isCommentLine(trimmed string) bool {
+	for _, prefix := range []string{"//", "#", "/*", "*", "--", ";"} {
+		if strings.HasPrefix(trimmed, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// GitAttributes holds the path patterns a repository's .gitattributes marks
+// as linguist-generated or linguist-vendored.
+type GitAttributes struct {
+	Generated []string
+	Vendored  []string
+}
+
+func ParseGitAttributes(content string) GitAttributes {
+	var attributes GitAttributes
+	for _, line := range strings.Split(content, "\n") {
+		fields := strings.Fields(line)
+		if len(fields) < 2 || strings.HasPrefix(fields[0], "#") {
+			continue
+		}
+		pattern := strings.TrimPrefix(fields[0], "/")
+		if strings.HasSuffix(pattern, "/") {
+			pattern += "**"
+		}
+		for _, attribute := range fields[1:] {
+			switch attribute {
+			case "linguist-generated", "linguist-generated=true":
+				attributes.Generated = append(attributes.Generated, pattern)
+			case "linguist-vendored", "linguist-vendored=true":
+				attributes.Vendored = append(attributes.Vendored, pattern)
+			}
+		}
+	}
+	return attributes
+}
+
+// ClassifyByPatterns marks files matching the configured generated globs or
+// .gitattributes linguist entries, before any content is fetched. Both come
+// from the base revision, so they apply to files the PR adds as well. The
+// built-in vendored directories do not: a file the PR adds or renames under
+// vendor/ is reviewed unless the base revision's patterns cover it.
+func ClassifyByPatterns(files []FileDiff, generated []string, attributes GitAttributes) {
+	for i := range files {
+		if (files[i].Status == FileAdded || files[i].Status == FileRenamed) && files[i].Type == FileTypeVendored {
+			files[i].Type = classifySource(strings.ToLower(files[i].Path))
+		}
+		switch {
+		case files[i].Type.IsExcluded():
+		case MatchAnyPath(attributes.Vendored, files[i].Path):
+			files[i].Type = FileTypeVendored
+		case MatchAnyPath(generated, files[i].Path) || MatchAnyPath(attributes.Generated, files[i].Path):
+			files[i].Type = FileTypeGenerated
+		}
+	}
+}
+
+// ClassifyByContent marks files with a generated-code header that the base
+// revision in baseContents already had. A header added by the PR itself is
+// not trusted, so a PR cannot skip review by claiming its code is generated;
+// new generated files have to match the configured generated paths or the
+// base revision's .gitattributes.
+func ClassifyByContent(files []FileDiff, contents map[string]string, baseContents map[string]string) {
+	for i := range files {
+		if files[i].Type.IsExcluded() || !HasGeneratedHeader(files[i], contents) {
+			continue
+		}
+		if base, ok := baseContents[files[i].Path]; ok && IsGeneratedSource(base) {
+			files[i].Type = FileTypeGenerated
+		}
+	}
+}
+
+// HasGeneratedHeader reports whether the head revision of file starts with a
+// generated-code header, using the fetched content or, for files that were
+// not fetched, the added lines when the diff starts at the top of the file.
+func HasGeneratedHeader(file FileDiff, contents map[string]string) bool {
+	source, ok := contents[file.Path]
+	if !ok {
+		if len(file.AddedLines) == 0 || file.AddedLines[0].Number != 1 {
+			return false
+		}
+		var lines []string
+		for _, line := range file.AddedLines {
+			lines = append(lines, line.Content)
+		}
+		source = strings.Join(lines, "\n")
+	}
+	return IsGeneratedSource(source)
+}
+
+// SplitExcluded separates generated and vendored files from the rest.
+func SplitExcluded(files []FileDiff) ([]FileDiff, []FileDiff) {
+	var kept, excluded []FileDiff
+	for _, file := range files {
+		if file.Type.IsExcluded() {
+			excluded = append(excluded, file)
+		} else {
+			kept = append(kept, file)
+		}
+	}
+	return kept, excluded
+}
@@ -0,0 +1,84 @@
+package analysis
+
+import "testing"
+
+func TestIsGeneratedSource(t *testing.T) {
+	cases := map[string]bool{
+		"// Code generated by protoc-gen-go. DO NOT EDIT.\n\npackage pb\n":                true,
+		"// Copyright 2024 Acme\n\n// Code generated by mockgen. DO NOT EDIT.\npackage x": true,
+		"# Code generated by sqlc. DO NOT EDIT.\nimport os\n":                             true,
+		"package main\n\n// Code generated by hand. DO NOT EDIT.\n":                       false,
+		"// Code generated by a person, please edit freely.\npackage x\n":                 false,
+	}
+	for source, want := range cases {
+		if got := IsGeneratedSource(source); got != want {
+			t.Fatalf("IsGeneratedSource(%q) = %v, want %v", source, got, want)
+		}
+	}
+}
+
+func TestClassifyGeneratedAndVendoredFiles(t *testing.T) {
+	attributes := ParseGitAttributes(`# linguist overrides
+/api/openapi.gen.ts linguist-generated=true
+assets/ linguist-vendored
+docs/** linguist-documentation
+`)
+	if len(attributes.Generated) != 1 || attributes.Generated[0] != "api/openapi.gen.ts" {
+		t.Fatalf("unexpected generated patterns: %v", attributes.Generated)
+	}
+	if len(attributes.Vendored) != 1 || attributes.Vendored[0] != "assets/**" {
+		t.Fatalf("unexpected vendored patterns: %v", attributes.Vendored)
+	}
+
+	var files []FileDiff
+	for _, path := range []string{
+		"vendor/github.com/lib/pq/conn.go",
+		"web/node_modules/react/index.js",
+		"assets/jquery.js",
+		"api/openapi.gen.ts",
+		"proto/user.pb.go",
+		"internal/mocks/store.go",
+		"internal/store/store.go",
+		"internal/api/handler.go",
+	} {
+		files = append(files, FileDiff{Path: path, Type: ClassifyPath(path)})
+	}
+	header := "// Code generated by MockGen. DO NOT EDIT."
+	files[5].AddedLines = []Line{{Number: 1, Content: header}, {Number: 2, Content: "package mocks"}}
+	// A header the PR adds, to an existing file or a new one, does not
+	// exclude it.
+	files[7].Status = FileAdded
+
+	ClassifyByPatterns(files, []string{"*.pb.go"}, attributes)
+	ClassifyByContent(files,
+		map[string]string{"internal/store/store.go": header + "\npackage store\n", "internal/api/handler.go": header + "\npackage api\n"},
+		map[string]string{"internal/mocks/store.go": header + "\npackage mocks\n", "internal/store/store.go": "package store\n"})
+	kept, excluded := SplitExcluded(files)
+
+	want := []FileType{FileTypeVendored, FileTypeVendored, FileTypeVendored, FileTypeGenerated, FileTypeGenerated, FileTypeGenerated}
+	if len(excluded) != len(want) || len(kept) != 2 || kept[0].Path != "internal/store/store.go" || kept[1].Path != "internal/api/handler.go" {
+		t.Fatalf("unexpected split: kept %+v, excluded %+v", kept, excluded)
+	}
+	for i, file := range excluded {
+		if file.Type != want[i] {
+			t.Fatalf("%s: got type %s, want %s", file.Path, file.Type, want[i])
+		}
+	}
+}
+
+func TestClassifyByPatternsForFilesThePRAdds(t *testing.T) {
+	var files []FileDiff
+	for _, path := range []string{"internal/evil.go", "vendor/github.com/evil/pkg/pkg.go", "api/v1/service.pb.go", "third_party/lib.go"} {
+		files = append(files, FileDiff{Path: path, Type: ClassifyPath(path), Status: FileAdded})
+	}
+	files[3].Status = FileRenamed
+
+	ClassifyByPatterns(files, []string{"*.pb.go"}, GitAttributes{Vendored: []string{"third_party/**"}})
+
+	want := []FileType{FileTypeProd, FileTypeProd, FileTypeGenerated, FileTypeVendored}
+	for i, file := range files {
+		if file.Type != want[i] {
+			t.Fatalf("%s: got type %s, want %s", file.Path, file.Type, want[i])
+		}
+	}
+}
@@ -0,0 +1,67 @@
+package analysis
+
+import (
+	"path"
+	"strings"
+)
+
+// MatchPath reports whether a repo-relative path matches a glob. Patterns
+// without a slash match the base name at any depth ("*.pb.go"); otherwise the
+// whole path is matched and "**" spans any number of directories
+// ("vendor/**", "db/migrations/**/*.sql").
+func MatchPath(pattern string, name string) bool {
+	pattern = strings.TrimPrefix(strings.TrimSpace(pattern), "/")
+	name = strings.TrimPrefix(name, "/")
+	if pattern == "" {
+		return false
+	}
+	if !strings.Contains(pattern, "/") {
+		matched, _ := path.Match(pattern, path.Base(name))
+		return matched
+	}
+	return matchSegments(strings.Split(pattern, "/"), strings.Split(name, "/"))
+}
+
+func ValidGlob(pattern string) bool {
+	for _, segment := range strings.Split(strings.TrimSpace(pattern), "/") {
+		if segment == "**" {
+			continue
+		}
+		if _, err := path.Match(segment, ""); err != nil {
+			return false
+		}
+	}
+	return strings.TrimSpace(pattern) != ""
+}
+
+func MatchAnyPath(patterns []string, name string) bool {
+	for _, pattern := range patterns {
+		if MatchPath(pattern, name) {
+			return true
+		}
+	}
+	return false
+}
+
+func matchSegments(pattern []string, name []string) bool {
+	for len(pattern) > 0 {
+		if pattern[0] == "**" {
+			rest := pattern[1:]
+			for i := 0; i <= len(name); i++ {
+				if matchSegments(rest, name[i:]) {
+					return true
+				}
+			}
+			return false
+		}
+		if len(name) == 0 {
+			return false
+		}
+		if matched, _ := path.Match(pattern[0], name[0]); !matched {
+			return false
+		}
+		pattern = pattern[1:]
+		name = name[1:]
+	}
+	return len(name) == 0
+}
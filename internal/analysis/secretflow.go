@@ -0,0 +1,431 @@
+package analysis
+
+import (
+	"fmt"
+	"go/ast"
+	"go/token"
+	"go/types"
+	"strings"
+	"unicode"
+)
+
+var DefaultSensitiveNames = []string{"password", "passwd", "secret", "token", "apikey", "privatekey", "credential"}
+
+var (
+	fmtSinks = map[string]bool{
+		"Print": true, "Printf": true, "Println": true,
+		"Errorf": true,
+	}
+	// fmtWriterSinks only log when they write to standard output or error,
+	// or to the log package's writer; writing a token to an
+	// http.ResponseWriter is usually the point.
+	fmtWriterSinks = map[string]bool{"Fprint": true, "Fprintf": true, "Fprintln": true}
+	logMethods     = map[string]bool{
+		"Print": true, "Printf": true, "Println": true,
+		"Fatal": true, "Fatalf": true, "Fatalln": true,
+		"Panic": true, "Panicf": true, "Panicln": true,
+		"Debug": true, "Debugf": true, "Debugw": true,
+		"Info": true, "Infof": true, "Infow": true,
+		"Warn": true, "Warnf": true, "Warnw": true,
+		"Error": true, "Errorf": true, "Errorw": true,
+		"Log": true, "Logf": true, "With": true,
+		"WithField": true, "WithFields": true,
+		"DebugContext": true, "InfoContext": true, "WarnContext": true, "ErrorContext": true,
+	}
+	// benignNameMarkers keep metadata about secrets (tokenCount, ErrBadToken,
+	// password_policy) from being treated as the secret itself. They match
+	// whole camelCase or snake_case words, so usernamePassword is still a
+	// secret; "expir" matches any word starting with it.
+	benignNameMarkers = []string{"count", "len", "type", "name", "url", "path", "file", "ttl", "expir", "policy", "hash", "usage", "limit", "tokens", "tokenizer", "err", "field"}
+	loggerPackages    = map[string]bool{
+		"log":                        true,
+		"log/slog":                   true,
+		"github.com/sirupsen/logrus": true,
+	}
+	// zapLoggerTypes are the zap types whose methods log. zap's package
+	// functions build fields (zap.String, zap.Error) and never log.
+	zapLoggerTypes = map[string]bool{"Logger": true, "SugaredLogger": true}
+)
+
+const zapPackage = "go.uber.org/zap"
+
+func analyzeSecretFlow(parsed *ast.File, fset *token.FileSet, info *types.Info, file FileDiff, sensitiveNames []string) []Issue {
+	if len(sensitiveNames) == 0 {
+		sensitiveNames = DefaultSensitiveNames
+	}
+	flow := secretFlow{
+		fset:      fset,
+		info:      info,
+		file:      file,
+		changed:   addedLineSet(file),
+		sensitive: normalizeNames(sensitiveNames),
+	}
+	flow.zapLoggers = flow.zapLoggerNames(parsed)
+	for _, decl := range parsed.Decls {
+		fn, ok := decl.(*ast.FuncDecl)
+		if !ok || fn.Body == nil {
+			continue
+		}
+		flow.checkFunction(fn)
+	}
+	return flow.issues
+}
+
+type secretFlow struct {
+	fset    *token.FileSet
+	info    *types.Info
+	file    FileDiff
+	changed map[int]bool
+	// zapLoggers are the fields, parameters and variables in the file
+	// declared as a zap Logger or SugaredLogger. zap is not importable
+	// while type checking, so its types are matched by declaration.
+	zapLoggers map[string]bool
+	sensitive  []string
+	tainted    map[string]string
+	issues     []Issue
+}
+
+// checkFunction walks statements in source order. Assignments from a
+// sensitive expression taint their targets, so the source name survives
+// copies such as `header := "Bearer " + token`.
+func (f *secretFlow) checkFunction(fn *ast.FuncDecl) {
+	f.tainted = map[string]string{}
+	ast.Inspect(fn.Body, func(node ast.Node) bool {
+		switch n := node.(type) {
+		case *ast.AssignStmt:
+			for i, lhs := range n.Lhs {
+				ident, ok := lhs.(*ast.Ident)
+				if !ok || ident.Name == "_" {
+					continue
+				}
+				rhs := n.Rhs[0]
+				if len(n.Rhs) == len(n.Lhs) {
+					rhs = n.Rhs[i]
+				}
+				if source, ok := f.source(rhs); ok {
+					f.tainted[ident.Name] = source
+				} else {
+					delete(f.tainted, ident.Name)
+				}
+			}
+		case *ast.ValueSpec:
+			for i, name := range n.Names {
+				if i < len(n.Values) {
+					if source, ok := f.source(n.Values[i]); ok {
+						f.tainted[name.Name] = source
+					}
+				}
+			}
+		case *ast.CallExpr:
+			f.checkSink(n)
+		}
+		return true
+	})
+}
+
+func (f *secretFlow) checkSink(call *ast.CallExpr) {
+	sink, ok := f.sinkName(call)
+	if !ok {
+		return
+	}
+	args := call.Args
+	if strings.HasPrefix(sink, "fmt.F") && len(args) > 0 {
+		args = args[1:]
+	}
+	for _, arg := range args {
+		source, ok := f.source(arg)
+		if !ok {
+			continue
+		}
+		line := f.fset.Position(call.Pos()).Line
+		if !f.changed[line] {
+			return
+		}
+		f.issues = append(f.issues, Issue{
+			File:       f.file.Path,
+			Line:       line,
+			RuleID:     "secret-logging",
+			Severity:   "high",
+			Message:    fmt.Sprintf("Sensitive value %s flows into %s; it will end up in logs or error messages.", source, sink),
+			Suggestion: "Log a redacted or hashed form, or drop the value from the message.",
+		})
+		return
+	}
+}
+
+func (f *secretFlow) sinkName(call *ast.CallExpr) (string, bool) {
+	selector, ok := call.Fun.(*ast.SelectorExpr)
+	if !ok {
+		return "", false
+	}
+	method := selector.Sel.Name
+	switch pkg := selectedPackage(f.info, selector); {
+	case pkg == "fmt" && fmtWriterSinks[method]:
+		return "fmt." + method, len(call.Args) > 0 && f.isLogWriter(call.Args[0])
+	case pkg == "fmt":
+		return "fmt." + method, fmtSinks[method]
+	case pkg == zapPackage:
+		return "", false
+	case loggerPackages[pkg]:
+		return exprName(selector.X) + "." + method, logMethods[method]
+	}
+	if logMethods[method] && (f.isZapLogger(selector) || strings.Contains(strings.ToLower(exprName(selector.X)), "log")) {
+		return exprName(selector.X) + "." + method, true
+	}
+	return "", false
+}
+
+// isLogWriter reports os.Stdout, os.Stderr and log.Writer().
+func (f *secretFlow) isLogWriter(expr ast.Expr) bool {
+	if call, ok := expr.(*ast.CallExpr); ok {
+		selector, ok := call.Fun.(*ast.SelectorExpr)
+		return ok && selectedPackage(f.info, selector) == "log" && selector.Sel.Name == "Writer"
+	}
+	selector, ok := expr.(*ast.SelectorExpr)
+	return ok && selectedPackage(f.info, selector) == "os" && (selector.Sel.Name == "Stdout" || selector.Sel.Name == "Stderr")
+}
+
+// isZapLogger reports whether a method call's receiver is a zap Logger or
+// SugaredLogger: resolved by go/types, declared as one in the file, or
+// returned by zap.L() or zap.S().
+func (f *secretFlow) isZapLogger(selector *ast.SelectorExpr) bool {
+	if selection := f.info.Selections[selector]; selection != nil {
+		recv := selection.Recv()
+		if pointer, ok := recv.(*types.Pointer); ok {
+			recv = pointer.Elem()
+		}
+		named, ok := recv.(*types.Named)
+		return ok && named.Obj().Pkg() != nil && named.Obj().Pkg().Path() == zapPackage && zapLoggerTypes[named.Obj().Name()]
+	}
+	switch x := selector.X.(type) {
+	case *ast.Ident:
+		return f.zapLoggers[x.Name]
+	case *ast.SelectorExpr:
+		return f.zapLoggers[x.Sel.Name]
+	case *ast.CallExpr:
+		fun, ok := x.Fun.(*ast.SelectorExpr)
+		return ok && selectedPackage(f.info, fun) == zapPackage && (fun.Sel.Name == "L" || fun.Sel.Name == "S")
+	}
+	return false
+}
+
+func (f *secretFlow) zapLoggerNames(parsed *ast.File) map[string]bool {
+	names := map[string]bool{}
+	isLoggerType := func(expr ast.Expr) bool {
+		if star, ok := expr.(*ast.StarExpr); ok {
+			expr = star.X
+		}
+		selector, ok := expr.(*ast.SelectorExpr)
+		return ok && selectedPackage(f.info, selector) == zapPackage && zapLoggerTypes[selector.Sel.Name]
+	}
+	ast.Inspect(parsed, func(node ast.Node) bool {
+		switch n := node.(type) {
+		case *ast.Field:
+			if isLoggerType(n.Type) {
+				for _, name := range n.Names {
+					names[name.Name] = true
+				}
+			}
+		case *ast.ValueSpec:
+			if n.Type != nil && isLoggerType(n.Type) {
+				for _, name := range n.Names {
+					names[name.Name] = true
+				}
+			}
+		}
+		return true
+	})
+	return names
+}
+
+// source reports the sensitive identifier an expression is derived from.
+func (f *secretFlow) source(expr ast.Expr) (string, bool) {
+	var found string
+	ast.Inspect(expr, func(node ast.Node) bool {
+		if found != "" {
+			return false
+		}
+		switch n := node.(type) {
+		case *ast.FuncLit:
+			return false
+		case *ast.BinaryExpr:
+			switch n.Op {
+			case token.EQL, token.NEQ, token.LSS, token.GTR, token.LEQ, token.GEQ, token.LAND, token.LOR:
+				return false
+			}
+		case *ast.CallExpr:
+			if ident, ok := n.Fun.(*ast.Ident); ok && (ident.Name == "len" || ident.Name == "cap") {
+				return false
+			}
+			if f.isSanitizer(n.Fun) {
+				return false
+			}
+			for _, arg := range n.Args {
+				if source, ok := f.source(arg); ok {
+					found = source
+					return false
+				}
+			}
+			switch fun := n.Fun.(type) {
+			case *ast.Ident:
+				if f.isSensitive(fun.Name) {
+					found = fun.Name + "()"
+				}
+			case *ast.SelectorExpr:
+				if f.isSensitive(fun.Sel.Name) {
+					found = exprName(n)
+				} else if source, ok := f.source(fun.X); ok {
+					found = source
+				}
+			}
+			return false
+		case *ast.SelectorExpr:
+			if f.isSensitive(n.Sel.Name) {
+				found = exprName(n)
+			} else if source, ok := f.source(n.X); ok {
+				found = source
+			}
+			return false
+		case *ast.Ident:
+			if source, ok := f.tainted[n.Name]; ok {
+				found = source
+			} else if f.isSensitive(n.Name) {
+				found = n.Name
+			}
+		}
+		return true
+	})
+	return found, found != ""
+}
+
+// sanitizerWords mark functions whose result no longer reveals the value
+// passed in, such as redactToken(token) or hashPassword(password).
+var sanitizerWords = map[string]bool{
+	"hash": true, "hashed": true, "redact": true, "redacted": true, "mask": true, "masked": true,
+	"digest": true, "hmac": true, "encrypt": true, "encrypted": true, "fingerprint": true,
+	"sanitize": true, "sanitized": true, "scrub": true, "obfuscate": true,
+}
+
+// isSanitizer reports calls to hashing or encryption packages, such as
+// sha256.Sum256, and to functions named after redacting or hashing.
+func (f *secretFlow) isSanitizer(fun ast.Expr) bool {
+	name := ""
+	switch fun := fun.(type) {
+	case *ast.Ident:
+		name = fun.Name
+	case *ast.SelectorExpr:
+		pkg := selectedPackage(f.info, fun)
+		if strings.HasPrefix(pkg, "crypto/") || strings.HasPrefix(pkg, "hash/") || strings.HasPrefix(pkg, "golang.org/x/crypto/") {
+			return true
+		}
+		name = fun.Sel.Name
+	}
+	for _, word := range nameWords(name) {
+		if sanitizerWords[word] {
+			return true
+		}
+	}
+	return false
+}
+
+func (f *secretFlow) isSensitive(name string) bool {
+	return matchesSensitive(name, f.sensitive)
+}
+
+// IsSensitiveName reports whether an identifier, env var or build arg name
+// looks like it holds a secret. An empty list uses DefaultSensitiveNames.
+func IsSensitiveName(name string, sensitiveNames []string) bool {
+	if len(sensitiveNames) == 0 {
+		sensitiveNames = DefaultSensitiveNames
+	}
+	return matchesSensitive(name, normalizeNames(sensitiveNames))
+}
+
+func matchesSensitive(name string, sensitive []string) bool {
+	for _, word := range nameWords(name) {
+		for _, marker := range benignNameMarkers {
+			if word == marker || (marker == "expir" && strings.HasPrefix(word, marker)) {
+				return false
+			}
+		}
+	}
+	normalized := normalizeName(name)
+	for _, fragment := range sensitive {
+		if strings.Contains(normalized, fragment) {
+			return true
+		}
+	}
+	return false
+}
+
+// nameWords splits an identifier into lower-case words at underscores,
+// hyphens, digits and camelCase boundaries: HTTPAuthToken gives http, auth
+// and token.
+func nameWords(name string) []string {
+	var words []string
+	var current []rune
+	runes := []rune(name)
+	flush := func() {
+		if len(current) > 0 {
+			words = append(words, strings.ToLower(string(current)))
+			current = nil
+		}
+	}
+	for i, r := range runes {
+		switch {
+		case !unicode.IsLetter(r):
+			flush()
+			continue
+		case unicode.IsUpper(r) && i > 0 && unicode.IsLetter(runes[i-1]):
+			lowerBefore := unicode.IsLower(runes[i-1])
+			lowerAfter := i+1 < len(runes) && unicode.IsLower(runes[i+1])
+			if lowerBefore || (lowerAfter && len(current) > 0) {
+				flush()
+			}
+		}
+		current = append(current, r)
+	}
+	flush()
+	return words
+}
+
+func normalizeNames(names []string) []string {
+	normalized := make([]string, 0, len(names))
+	for _, name := range names {
+		if name = normalizeName(name); name != "" {
+			normalized = append(normalized, name)
+		}
+	}
+	return normalized
+}
+
+func normalizeName(name string) string {
+	return strings.ToLower(strings.NewReplacer("_", "", "-", "").Replace(name))
+}
+
+func exprName(expr ast.Expr) string {
+	switch e := expr.(type) {
+	case *ast.Ident:
+		return e.Name
+	case *ast.SelectorExpr:
+		return exprName(e.X) + "." + e.Sel.Name
+	case *ast.StarExpr:
+		return exprName(e.X)
+	case *ast.CallExpr:
+		return exprName(e.Fun) + "()"
+	}
+	return "value"
+}
+
+// selectedPackage returns the import path of the package a selector refers
+// to, or "" when it selects from a value.
+func selectedPackage(info *types.Info, selector *ast.SelectorExpr) string {
+	ident, ok := selector.X.(*ast.Ident)
+	if !ok {
+		return ""
+	}
+	if name, ok := info.Uses[ident].(*types.PkgName); ok {
+		return name.Imported().Path()
+	}
+	return ""
+}
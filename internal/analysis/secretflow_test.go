@@ -0,0 +1,145 @@
+package analysis
+
+import "testing"
+
+const secretFlowSource = `package auth
+
+import (
+	"fmt"
+	"log"
+	"log/slog"
+)
+
+type Credentials struct {
+	User     string
+	Password string
+}
+
+func Login(creds Credentials, apiKey string, tokenCount int) error {
+	log.Printf("login attempt for %s", creds.User)
+	log.Printf("login with %s", creds.Password)
+	header := "Bearer " + apiKey
+	slog.Info("calling upstream", "auth", header)
+	log.Printf("used %d tokens", tokenCount)
+	return fmt.Errorf("login failed for %s with %s", creds.User, creds.Password)
+}
+`
+
+func TestSecretFlow(t *testing.T) {
+	file := fileWithAllLinesAdded("auth/login.go", secretFlowSource)
+	issues := RunStaticAnalysis([]FileDiff{file}, map[string]string{file.Path: secretFlowSource})
+
+	var lines []int
+	var messages []string
+	for _, issue := range issues {
+		if issue.RuleID == "secret-logging" {
+			lines = append(lines, issue.Line)
+			messages = append(messages, issue.Message)
+		}
+	}
+	want := []int{16, 18, 20}
+	if len(lines) != len(want) {
+		t.Fatalf("expected secret-logging on lines %v, got %v (%v)", want, lines, messages)
+	}
+	for i := range want {
+		if lines[i] != want[i] {
+			t.Fatalf("expected secret-logging on lines %v, got %v", want, lines)
+		}
+	}
+	if messages[1] != "Sensitive value apiKey flows into slog.Info; it will end up in logs or error messages." {
+		t.Fatalf("expected source variable named in message, got %q", messages[1])
+	}
+}
+
+func TestIsSensitiveNameMatchesWholeWords(t *testing.T) {
+	for name, want := range map[string]bool{
+		"userPassword":     true,
+		"usernamePassword": true,
+		"profileSecret":    true,
+		"typedToken":       true,
+		"HTTPAuthToken":    true,
+		"tokenCount":       false,
+		"ErrBadToken":      false,
+		"password_policy":  false,
+		"secretFilePath":   false,
+		"tokenExpiresAt":   false,
+		"APIKeyName":       false,
+	} {
+		if got := IsSensitiveName(name, nil); got != want {
+			t.Errorf("%s: got %v, want %v", name, got, want)
+		}
+	}
+}
+
+func TestSecretFlowSinks(t *testing.T) {
+	source := `package auth
+
+import (
+	"fmt"
+	"net/http"
+	"os"
+
+	"go.uber.org/zap"
+)
+
+type Server struct {
+	audit *zap.SugaredLogger
+}
+
+func (s *Server) Issue(w http.ResponseWriter, logger *zap.Logger, token string) {
+	logger.Info("issued", zap.String("token", token))
+	fmt.Fprintf(w, "%s", token)
+	fmt.Fprintln(os.Stderr, "issued", token)
+	s.audit.Infow("issued", "token", token)
+	zap.L().Debug("issued", zap.String("token", token))
+}
+`
+	file := fileWithAllLinesAdded("auth/issue.go", source)
+	var lines []int
+	for _, issue := range RunStaticAnalysis([]FileDiff{file}, map[string]string{file.Path: source}) {
+		if issue.RuleID == "secret-logging" {
+			lines = append(lines, issue.Line)
+		}
+	}
+	want := []int{16, 18, 19, 20}
+	if len(lines) != len(want) {
+		t.Fatalf("expected secret-logging once each on lines %v, got %v", want, lines)
+	}
+	for i := range want {
+		if lines[i] != want[i] {
+			t.Fatalf("expected secret-logging once each on lines %v, got %v", want, lines)
+		}
+	}
+}
+
+func TestSecretFlowSanitizers(t *testing.T) {
+	source := `package auth
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"log"
+)
+
+func Login(password string, token string) {
+	log.Printf("%x", sha256.Sum256([]byte(password)))
+	log.Print(redact(token))
+	log.Print(maskToken(token))
+	log.Print(hex.EncodeToString([]byte(token)))
+}
+
+func redact(value string) string { return "***" }
+
+func maskToken(value string) string { return "***" }
+`
+	file := fileWithAllLinesAdded("auth/login.go", source)
+	var lines []int
+	for _, issue := range RunStaticAnalysis([]FileDiff{file}, map[string]string{file.Path: source}) {
+		if issue.RuleID == "secret-logging" {
+			lines = append(lines, issue.Line)
+		}
+	}
+	if len(lines) != 1 || lines[0] != 13 {
+		t.Fatalf("expected only the hex-encoded token on line 13 to be reported, got %v", lines)
+	}
+}
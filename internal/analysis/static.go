@@ -8,7 +8,23 @@ import (
 	"strings"
 )
 
+type StaticOptions struct {
+	// GoVersions maps each Go file to the go directive of the nearest go.mod
+	// above it, used for checks whose semantics changed between releases.
+	GoVersions map[string]string
+	// SensitiveNames are identifier fragments treated as secrets by the
+	// secret-logging check; empty uses DefaultSensitiveNames.
+	SensitiveNames []string
+	// Suppressions are shared with the rules engine so the orchestrator can
+	// report every directive that was used; nil builds them from contents.
+	Suppressions *Suppressions
+}
+
 func RunStaticAnalysis(files []FileDiff, contents map[string]string) []Issue {
+	return RunStaticAnalysisWithOptions(files, contents, StaticOptions{})
+}
+
+func RunStaticAnalysisWithOptions(files []FileDiff, contents map[string]string, opts StaticOptions) []Issue {
 	var issues []Issue
 	for _, file := range files {
 		if filepath.Ext(file.Path) != ".go" {
@@ -18,12 +34,18 @@ func RunStaticAnalysis(files []FileDiff, contents map[string]string) []Issue {
 		if !ok || strings.TrimSpace(source) == "" {
 			continue
 		}
-		issues = append(issues, analyzeGoFile(file.Path, source)...)
+		issues = append(issues, analyzeGoFile(file, source, opts)...)
 	}
-	return issues
+
+	suppressions := opts.Suppressions
+	if suppressions == nil {
+		suppressions = BuildSuppressions(files, contents)
+	}
+	return suppressions.Filter(issues)
 }
 
-func analyzeGoFile(path string, source string) []Issue {
+func analyzeGoFile(file FileDiff, source string, opts StaticOptions) []Issue {
+	path := file.Path
 	fset := token.NewFileSet()
 	parsed, err := parser.ParseFile(fset, path, source, parser.ParseComments)
 	if err != nil {
@@ -38,6 +60,7 @@ func analyzeGoFile(path string, source string) []Issue {
 		}
 	}
 
+	info, typed := typeCheck(fset, parsed)
 	var issues []Issue
 	ast.Inspect(parsed, func(node ast.Node) bool {
 		switch n := node.(type) {
@@ -79,6 +102,9 @@ func analyzeGoFile(path string, source string) []Issue {
 		return true
 	})
 
+	issues = append(issues, analyzeConcurrency(fset, parsed, info, typed, file, opts)...)
+	issues = append(issues, analyzeContextPropagation(fset, parsed, info, typed, file)...)
+	issues = append(issues, analyzeSecretFlow(parsed, fset, info, file, opts.SensitiveNames)...)
 	return issues
 }
 
@@ -0,0 +1,234 @@
+package analysis
+
+import (
+	"fmt"
+	"go/parser"
+	"go/token"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// Directives look like `// ai-teammate:ignore panic reason="init-only"`. A
+// line directive covers its own line and the next one; ignore-file covers the
+// whole file. Only real comments count: Go comments found by the parser, and
+// in other files lines that start with a comment marker, so a directive in a
+// string literal or YAML value is ignored.
+var (
+	directivePattern = regexp.MustCompile(`^ai-teammate:(ignore-file|ignore)\b(.*)`)
+	commentMarker    = regexp.MustCompile(`^\s*(?://|#|--)\s*`)
+	reasonPattern    = regexp.MustCompile(`reason=(?:"([^"]*)"|(\S+))`)
+)
+
+// unsuppressibleRules cannot be silenced by a directive the PR adds, since
+// their findings also decide what is redacted before the AI reviewer sees
+// the diff.
+var unsuppressibleRules = map[string]bool{"secrets": true}
+
+type Suppression struct {
+	File      string
+	Line      int
+	RuleIDs   []string
+	Reason    string
+	FileLevel bool
+	Added     bool
+	used      bool
+}
+
+func (s *Suppression) matches(issue Issue) bool {
+	if issue.File != s.File {
+		return false
+	}
+	if !s.FileLevel && issue.Line != s.Line && issue.Line != s.Line+1 {
+		return false
+	}
+	if s.Added && unsuppressibleRules[issue.RuleID] {
+		return false
+	}
+	for _, id := range s.RuleIDs {
+		if id == "*" || id == "all" || id == issue.RuleID {
+			return true
+		}
+	}
+	return false
+}
+
+func (s Suppression) String() string {
+	scope := fmt.Sprintf("`%s:%d`", s.File, s.Line)
+	if s.FileLevel {
+		scope = fmt.Sprintf("`%s` (whole file)", s.File)
+	}
+	reason := s.Reason
+	if reason == "" {
+		reason = "no reason given"
+	}
+	return fmt.Sprintf("%s ignores `%s` — %s", scope, strings.Join(s.RuleIDs, ", "), reason)
+}
+
+type Suppressions struct {
+	byFile map[string][]*Suppression
+}
+
+// BuildSuppressions collects directives from full file contents when they
+// were fetched and from the diff's added lines otherwise.
+func BuildSuppressions(files []FileDiff, contents map[string]string) *Suppressions {
+	suppressions := &Suppressions{byFile: map[string][]*Suppression{}}
+	for _, file := range files {
+		added := addedLineSet(file)
+		source, ok := contents[file.Path]
+		if !ok {
+			for _, line := range file.AddedLines {
+				suppressions.addLine(file.Path, line.Number, line.Content, true)
+			}
+			continue
+		}
+		if strings.HasSuffix(strings.ToLower(file.Path), ".go") && suppressions.addGoComments(file.Path, source, added) {
+			continue
+		}
+		for i, line := range strings.Split(source, "\n") {
+			suppressions.addLine(file.Path, i+1, line, added[i+1])
+		}
+	}
+	return suppressions
+}
+
+// addGoComments reads directives from the comments of a Go file. It reports
+// false when the file does not parse, so the caller can scan lines instead.
+func (s *Suppressions) addGoComments(path string, source string, added map[int]bool) bool {
+	fset := token.NewFileSet()
+	file, err := parser.ParseFile(fset, path, source, parser.ParseComments|parser.SkipObjectResolution)
+	if err != nil {
+		return false
+	}
+	for _, group := range file.Comments {
+		for _, comment := range group.List {
+			line := fset.Position(comment.Slash).Line
+			if text, ok := strings.CutPrefix(comment.Text, "//"); ok {
+				s.add(path, line, strings.TrimSpace(text), added[line])
+				continue
+			}
+			body := strings.TrimSuffix(strings.TrimPrefix(comment.Text, "/*"), "*/")
+			for i, text := range strings.Split(body, "\n") {
+				text = strings.TrimSpace(strings.TrimLeft(strings.TrimSpace(text), "*"))
+				s.add(path, line+i, text, added[line+i])
+			}
+		}
+	}
+	return true
+}
+
+// addLine reads a directive from a line that starts with a comment marker.
+func (s *Suppressions) addLine(path string, number int, content string, added bool) {
+	marker := commentMarker.FindStringIndex(content)
+	if marker == nil {
+		return
+	}
+	s.add(path, number, content[marker[1]:], added)
+}
+
+func (s *Suppressions) add(path string, number int, text string, added bool) {
+	match := directivePattern.FindStringSubmatch(text)
+	if match == nil {
+		return
+	}
+	var ruleIDs []string
+	if fields := strings.Fields(match[2]); len(fields) > 0 && !strings.HasPrefix(fields[0], "reason=") {
+		for _, id := range strings.Split(fields[0], ",") {
+			if id = strings.TrimSpace(id); id != "" {
+				ruleIDs = append(ruleIDs, id)
+			}
+		}
+	}
+	reason := ""
+	if reasonMatch := reasonPattern.FindStringSubmatch(match[2]); reasonMatch != nil {
+		reason = reasonMatch[1] + reasonMatch[2]
+	}
+	s.byFile[path] = append(s.byFile[path], &Suppression{
+		File:      path,
+		Line:      number,
+		RuleIDs:   ruleIDs,
+		Reason:    strings.TrimSpace(reason),
+		FileLevel: match[1] == "ignore-file",
+		Added:     added,
+	})
+}
+
+// Filter drops suppressed issues and remembers which directives were used.
+func (s *Suppressions) Filter(issues []Issue) []Issue {
+	if s == nil {
+		return issues
+	}
+	var kept []Issue
+	for _, issue := range issues {
+		suppressed := false
+		for _, suppression := range s.byFile[issue.File] {
+			if suppression.matches(issue) {
+				suppression.used = true
+				suppressed = true
+			}
+		}
+		if !suppressed {
+			kept = append(kept, issue)
+		}
+	}
+	return kept
+}
+
+func (s *Suppressions) Used() []Suppression {
+	var used []Suppression
+	for _, suppression := range s.sorted() {
+		if suppression.used {
+			used = append(used, *suppression)
+		}
+	}
+	return used
+}
+
+// Issues flags directives added in this PR that are malformed or carry no
+// reason. Directives that already existed are not re-reported.
+func (s *Suppressions) Issues() []Issue {
+	var issues []Issue
+	for _, suppression := range s.sorted() {
+		if !suppression.Added {
+			continue
+		}
+		switch {
+		case len(suppression.RuleIDs) == 0:
+			issues = append(issues, Issue{
+				File:       suppression.File,
+				Line:       suppression.Line,
+				RuleID:     "suppression-invalid",
+				Severity:   "low",
+				Message:    "Suppression directive does not name the rule it ignores.",
+				Suggestion: `Name the rule, e.g. // ai-teammate:ignore panic reason="init-only".`,
+			})
+		case suppression.Reason == "":
+			issues = append(issues, Issue{
+				File:       suppression.File,
+				Line:       suppression.Line,
+				RuleID:     "suppression-without-reason",
+				Severity:   "low",
+				Message:    fmt.Sprintf("Suppression of %s has no reason.", strings.Join(suppression.RuleIDs, ", ")),
+				Suggestion: `Add reason="..." so reviewers know why the finding is intentional.`,
+			})
+		}
+	}
+	return issues
+}
+
+func (s *Suppressions) sorted() []*Suppression {
+	if s == nil {
+		return nil
+	}
+	var all []*Suppression
+	for _, suppressions := range s.byFile {
+		all = append(all, suppressions...)
+	}
+	sort.Slice(all, func(i, j int) bool {
+		if all[i].File == all[j].File {
+			return all[i].Line < all[j].Line
+		}
+		return all[i].File < all[j].File
+	})
+	return all
+}
@@ -0,0 +1,97 @@
+package analysis
+
+import "testing"
+
+func TestSuppressions(t *testing.T) {
+	source := `package main
+
+func init() {
+	// ai-teammate:ignore panic reason="init-only"
+	panic("boom")
+}
+
+func run() {
+	panic("again") // ai-teammate:ignore panic
+}
+`
+	file := fileWithAllLinesAdded("main.go", source)
+	contents := map[string]string{"main.go": source}
+	suppressions := BuildSuppressions([]FileDiff{file}, contents)
+
+	issues := RunStaticAnalysisWithOptions([]FileDiff{file}, contents, StaticOptions{Suppressions: suppressions})
+	for _, issue := range issues {
+		if issue.RuleID == "panic" {
+			t.Fatalf("expected panic findings to be suppressed, got %+v", issue)
+		}
+	}
+
+	used := suppressions.Used()
+	if len(used) != 2 || used[0].Reason != "init-only" || used[1].Line != 9 {
+		t.Fatalf("unexpected used suppressions: %+v", used)
+	}
+	flagged := suppressions.Issues()
+	if len(flagged) != 1 || flagged[0].RuleID != "suppression-without-reason" || flagged[0].Line != 9 {
+		t.Fatalf("expected missing reason flagged on line 9, got %+v", flagged)
+	}
+}
+
+func TestFileLevelSuppression(t *testing.T) {
+	source := "# ai-teammate:ignore-file secrets,todo reason=\"test fixtures\"\npassword: hunter2\n"
+	file := FileDiff{
+		Path:       "fixtures/keys.yaml",
+		AddedLines: []Line{{Number: 2, Content: "password: hunter2"}},
+	}
+	suppressions := BuildSuppressions([]FileDiff{file}, map[string]string{file.Path: source})
+
+	kept := suppressions.Filter([]Issue{
+		{File: file.Path, Line: 2, RuleID: "secrets"},
+		{File: file.Path, Line: 2, RuleID: "todo"},
+	})
+	if len(kept) != 0 {
+		t.Fatalf("expected the existing directive to suppress both findings, got %+v", kept)
+	}
+}
+
+func TestAddedDirectivesCannotSuppressSecrets(t *testing.T) {
+	file := FileDiff{
+		Path: "fixtures/keys.go",
+		AddedLines: []Line{
+			{Number: 1, Content: `// ai-teammate:ignore-file * reason="test fixtures"`},
+			{Number: 40, Content: `const password = "hunter2"`},
+		},
+	}
+	suppressions := BuildSuppressions([]FileDiff{file}, nil)
+
+	kept := suppressions.Filter([]Issue{
+		{File: "fixtures/keys.go", Line: 40, RuleID: "secrets"},
+		{File: "fixtures/keys.go", Line: 40, RuleID: "todo"},
+	})
+	if len(kept) != 1 || kept[0].RuleID != "secrets" {
+		t.Fatalf("expected only the secrets finding to remain, got %+v", kept)
+	}
+}
+
+func TestDirectivesOnlyCountInComments(t *testing.T) {
+	source := `package main
+
+const usage = "add // ai-teammate:ignore panic to silence it"
+
+func run() {
+	panic("boom")
+}
+`
+	yamlSource := "message: \"# ai-teammate:ignore-file * reason=x\"\n"
+	files := []FileDiff{
+		fileWithAllLinesAdded("main.go", source),
+		fileWithAllLinesAdded("config.yaml", yamlSource),
+	}
+	suppressions := BuildSuppressions(files, map[string]string{"main.go": source, "config.yaml": yamlSource})
+
+	kept := suppressions.Filter([]Issue{
+		{File: "main.go", Line: 3, RuleID: "panic"},
+		{File: "config.yaml", Line: 1, RuleID: "todo"},
+	})
+	if len(kept) != 2 || len(suppressions.Used()) != 0 {
+		t.Fatalf("expected directives in a string literal and a YAML value to be ignored, got %+v", kept)
+	}
+}
@@ -0,0 +1,71 @@
+package analysis
+
+import (
+	"fmt"
+	"go/ast"
+	"go/importer"
+	"go/token"
+	"go/types"
+	"log"
+	"strings"
+	"sync"
+)
+
+// stdImporter loads standard library packages from GOROOT source and caches
+// them. Images without $GOROOT/src cannot resolve anything, which typeCheck
+// reports so checks can fall back to syntactic matching.
+var stdImporter types.Importer = &lockedImporter{importer: importer.ForCompiler(token.NewFileSet(), "source", nil)}
+
+// lockedImporter serializes imports, since the source importer is not safe
+// for concurrent use. Type checking itself runs without the lock.
+type lockedImporter struct {
+	mu       sync.Mutex
+	importer types.Importer
+}
+
+func (l *lockedImporter) Import(path string) (*types.Package, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.importer.Import(path)
+}
+
+// stdlibImporter only imports the standard library; a reviewed file's
+// other imports are not available. It remembers the first standard library
+// package that failed to import.
+type stdlibImporter struct {
+	failed error
+}
+
+func (s *stdlibImporter) Import(path string) (*types.Package, error) {
+	if first, _, _ := strings.Cut(path, "/"); strings.Contains(first, ".") || path == "C" {
+		return nil, fmt.Errorf("%s is not in the standard library", path)
+	}
+	pkg, err := stdImporter.Import(path)
+	if err != nil && s.failed == nil {
+		s.failed = err
+	}
+	return pkg, err
+}
+
+// typeCheck resolves the identifiers and types of a single file. Errors are
+// expected, since the rest of the package and non-standard imports are
+// missing: checks must treat an unresolved identifier or type as unknown.
+// The result reports whether the standard library imports resolved; when
+// they did not, method receivers and imported types are unknown and checks
+// have to match syntactically instead.
+func typeCheck(fset *token.FileSet, parsed *ast.File) (*types.Info, bool) {
+	info := &types.Info{
+		Types:      map[ast.Expr]types.TypeAndValue{},
+		Defs:       map[*ast.Ident]types.Object{},
+		Uses:       map[*ast.Ident]types.Object{},
+		Selections: map[*ast.SelectorExpr]*types.Selection{},
+	}
+	imports := &stdlibImporter{}
+	config := types.Config{Importer: imports, Error: func(error) {}}
+	_, _ = config.Check(parsed.Name.Name, fset, []*ast.File{parsed}, info)
+	if imports.failed != nil {
+		log.Printf("%s: standard library not available for type checking, using syntactic checks: %v", fset.Position(parsed.Pos()).Filename, imports.failed)
+		return info, false
+	}
+	return info, true
+}
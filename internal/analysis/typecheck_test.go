@@ -0,0 +1,97 @@
+package analysis
+
+import (
+	"errors"
+	"go/types"
+	"testing"
+)
+
+type failingImporter struct{}
+
+func (failingImporter) Import(path string) (*types.Package, error) {
+	return nil, errors.New("GOROOT/src not available")
+}
+
+// withoutStdlib simulates a server image without $GOROOT/src.
+func withoutStdlib(t *testing.T) {
+	previous := stdImporter
+	stdImporter = failingImporter{}
+	t.Cleanup(func() { stdImporter = previous })
+}
+
+func TestChecksFallBackWithoutStandardLibrary(t *testing.T) {
+	withoutStdlib(t)
+
+	file := fileWithAllLinesAdded("store/store.go", contextSource)
+	found := map[string][]int{}
+	for _, issue := range RunStaticAnalysis([]FileDiff{file}, map[string]string{file.Path: contextSource}) {
+		found[issue.RuleID] = append(found[issue.RuleID], issue.Line)
+	}
+	expected := map[string][]int{
+		"context-dropped":      {14, 33},
+		"sql-without-context":  {20},
+		"http-without-context": {25},
+		"io-without-context":   {20, 25},
+	}
+	for ruleID, lines := range expected {
+		if len(found[ruleID]) != len(lines) {
+			t.Fatalf("expected %s on lines %v, got %v", ruleID, lines, found[ruleID])
+		}
+		for i, line := range lines {
+			if found[ruleID][i] != line {
+				t.Fatalf("expected %s on lines %v, got %v", ruleID, lines, found[ruleID])
+			}
+		}
+	}
+
+	worker := fileWithAllLinesAdded("worker.go", concurrencySource)
+	issues := RunStaticAnalysisWithOptions([]FileDiff{worker}, map[string]string{"worker.go": concurrencySource}, StaticOptions{GoVersions: map[string]string{"worker.go": "1.21"}})
+	lines := map[string]int{}
+	for _, issue := range issues {
+		lines[issue.RuleID] = issue.Line
+	}
+	for ruleID, line := range map[string]int{"lock-copied": 10, "waitgroup-add-in-goroutine": 18, "loop-var-capture": 20} {
+		if lines[ruleID] != line {
+			t.Fatalf("expected %s on line %d without the standard library, got %+v", ruleID, line, issues)
+		}
+	}
+}
+
+func TestDoFallbackNeedsAnHTTPClient(t *testing.T) {
+	withoutStdlib(t)
+
+	source := `package cache
+
+import (
+	"net/http"
+	"sync"
+)
+
+type Cache struct {
+	once   sync.Once
+	client *http.Client
+}
+
+func (c *Cache) Init() {
+	c.once.Do(c.load)
+}
+
+func Send() error {
+	client := &http.Client{}
+	_, err := client.Do(pending)
+	return err
+}
+
+var pending *http.Request
+`
+	file := fileWithAllLinesAdded("cache/cache.go", source)
+	var lines []int
+	for _, issue := range RunStaticAnalysis([]FileDiff{file}, map[string]string{file.Path: source}) {
+		if issue.RuleID == "io-without-context" {
+			lines = append(lines, issue.Line)
+		}
+	}
+	if len(lines) != 1 || lines[0] != 19 {
+		t.Fatalf("expected io-without-context only for the Do call in Send on line 19, got %v", lines)
+	}
+}
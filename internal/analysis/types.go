@@ -6,6 +6,33 @@ const (
 	FileTypeProd   FileType = "prod"
 	FileTypeTest   FileType = "test"
 	FileTypeConfig FileType = "config"
+	// FileTypeDependency covers dependency manifests and lockfiles.
+	FileTypeDependency FileType = "dependency"
+	FileTypeDockerfile FileType = "dockerfile"
+	// FileTypeKubernetes is assigned by RefineFileType once content shows a
+	// YAML file holds Kubernetes objects.
+	FileTypeKubernetes FileType = "kubernetes"
+	FileTypeWorkflow   FileType = "workflow"
+	FileTypeGenerated  FileType = "generated"
+	FileTypeVendored   FileType = "vendored"
+)
+
+func (t FileType) Valid() bool {
+	switch t {
+	case FileTypeProd, FileTypeTest, FileTypeConfig, FileTypeDependency, FileTypeDockerfile, FileTypeKubernetes, FileTypeWorkflow, FileTypeGenerated, FileTypeVendored:
+		return true
+	}
+	return false
+}
+
+// FileStatus tells whether a diff creates, edits, deletes or renames a file.
+type FileStatus string
+
+const (
+	FileAdded    FileStatus = "added"
+	FileModified FileStatus = "modified"
+	FileDeleted  FileStatus = "deleted"
+	FileRenamed  FileStatus = "renamed"
 )
 
 type Line struct {
@@ -14,16 +41,22 @@ type Line struct {
 }
 
 type FileDiff struct {
-	Path       string
-	AddedLines []Line
-	Raw        string
-	Type       FileType
+	Path         string
+	AddedLines   []Line
+	RemovedLines []Line
+	Raw          string
+	Type         FileType
+	Status       FileStatus
 }
 
 type Issue struct {
-	File     string
-	Line     int
-	RuleID   string
-	Severity string
-	Message  string
+	File       string
+	Line       int
+	RuleID     string
+	Severity   string
+	Message    string
+	Suggestion string
+	Source     string
+	// PromptVersion names the prompt templates an AI finding came from.
+	PromptVersion string
 }
@@ -0,0 +1,324 @@
+package analysis
+
+import (
+	"regexp"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+type YAMLKind int
+
+const (
+	YAMLScalar YAMLKind = iota
+	YAMLMapping
+	YAMLSequence
+)
+
+// YAMLNode is a parsed YAML value with the source lines it spans. Block
+// scalars keep one line of Value per source line starting at Line, so a
+// position inside a `run: |` script maps back to the file. Template marks a
+// value that is a Helm/Go template expression such as
+// `{{ toYaml .Values.resources | nindent 12 }}`.
+type YAMLNode struct {
+	Kind     YAMLKind
+	Line     int
+	EndLine  int
+	Value    string
+	Block    bool
+	Template bool
+	Pairs    []YAMLPair
+	Items    []*YAMLNode
+}
+
+type YAMLPair struct {
+	Key   string
+	Line  int
+	Value *YAMLNode
+}
+
+// Get returns the value of a mapping key, or nil for missing keys and
+// non-mappings.
+func (n *YAMLNode) Get(key string) *YAMLNode {
+	pair, ok := n.Pair(key)
+	if !ok {
+		return nil
+	}
+	return pair.Value
+}
+
+func (n *YAMLNode) Pair(key string) (YAMLPair, bool) {
+	if n == nil || n.Kind != YAMLMapping {
+		return YAMLPair{}, false
+	}
+	for _, pair := range n.Pairs {
+		if pair.Key == key {
+			return pair, true
+		}
+	}
+	return YAMLPair{}, false
+}
+
+func (n *YAMLNode) Path(keys ...string) *YAMLNode {
+	for _, key := range keys {
+		n = n.Get(key)
+	}
+	return n
+}
+
+// List returns a sequence's items and nil for anything else.
+func (n *YAMLNode) List() []*YAMLNode {
+	if n == nil || n.Kind != YAMLSequence {
+		return nil
+	}
+	return n.Items
+}
+
+// Entries returns a mapping's pairs and nil for anything else.
+func (n *YAMLNode) Entries() []YAMLPair {
+	if n == nil || n.Kind != YAMLMapping {
+		return nil
+	}
+	return n.Pairs
+}
+
+// Text returns a scalar's value and "" for collections and missing nodes.
+func (n *YAMLNode) Text() string {
+	if n == nil || n.Kind != YAMLScalar {
+		return ""
+	}
+	return n.Value
+}
+
+// IsEmpty reports a missing node, a null scalar or an empty collection.
+// Template values count as present since their content is unknown.
+func (n *YAMLNode) IsEmpty() bool {
+	if n == nil {
+		return true
+	}
+	switch n.Kind {
+	case YAMLMapping:
+		return len(n.Pairs) == 0
+	case YAMLSequence:
+		return len(n.Items) == 0
+	}
+	if n.Template {
+		return false
+	}
+	switch n.Value {
+	case "", "~", "null", "Null", "NULL":
+		return true
+	}
+	return false
+}
+
+// ParseYAMLDocuments parses every document of a multi-document YAML file
+// with yaml.v3. Helm template directives are rewritten first so templates
+// parse (see untemplateYAML). Aliases stay as "*name" scalars rather than
+// being expanded. Parsing stops at the first document that is still not
+// valid YAML, keeping the documents before it.
+func ParseYAMLDocuments(source string) []*YAMLNode {
+	lines := strings.Split(strings.ReplaceAll(source, "\r\n", "\n"), "\n")
+	decoder := yaml.NewDecoder(strings.NewReader(strings.Join(untemplateYAML(lines), "\n")))
+	var documents []*YAMLNode
+	for {
+		var document yaml.Node
+		if err := decoder.Decode(&document); err != nil {
+			return documents
+		}
+		if len(document.Content) == 0 {
+			continue
+		}
+		root := document.Content[0]
+		if root.Kind == yaml.ScalarNode && root.Tag == "!!null" {
+			continue
+		}
+		documents = append(documents, convertYAML(root, lines))
+	}
+}
+
+func convertYAML(n *yaml.Node, lines []string) *YAMLNode {
+	switch n.Kind {
+	case yaml.MappingNode:
+		node := &YAMLNode{Kind: YAMLMapping, Line: n.Line, EndLine: n.Line}
+		for i := 0; i+1 < len(n.Content); i += 2 {
+			key, value := n.Content[i], convertYAML(n.Content[i+1], lines)
+			node.Pairs = append(node.Pairs, YAMLPair{Key: key.Value, Line: key.Line, Value: value})
+			node.EndLine = max(node.EndLine, key.Line, value.EndLine)
+		}
+		return node
+	case yaml.SequenceNode:
+		node := &YAMLNode{Kind: YAMLSequence, Line: n.Line, EndLine: n.Line}
+		for _, item := range n.Content {
+			value := convertYAML(item, lines)
+			node.Items = append(node.Items, value)
+			node.EndLine = max(node.EndLine, value.EndLine)
+		}
+		return node
+	case yaml.AliasNode:
+		return &YAMLNode{Kind: YAMLScalar, Line: n.Line, EndLine: n.Line, Value: "*" + n.Value}
+	}
+	node := &YAMLNode{Kind: YAMLScalar, Line: n.Line, EndLine: n.Line, Value: n.Value, Template: strings.HasPrefix(n.Value, "{{")}
+	if n.Style&(yaml.LiteralStyle|yaml.FoldedStyle) != 0 {
+		node.Block = true
+		if n.Value != "" {
+			node.Line, node.Value = blockScalarLines(lines, n.Line)
+			node.EndLine = node.Line + strings.Count(node.Value, "\n")
+		}
+	}
+	return node
+}
+
+// blockScalarLines returns the first content line of the block scalar whose
+// header is on line header, and its source lines with the block's
+// indentation removed. Unlike the parsed value, folded scalars are not
+// joined, so each line of the value is a line of the file.
+func blockScalarLines(lines []string, header int) (int, string) {
+	first, indent := header, -1
+	var body []string
+	for i := header; i < len(lines); i++ {
+		line := lines[i]
+		if strings.TrimSpace(line) == "" {
+			if indent >= 0 {
+				body = append(body, "")
+			}
+			continue
+		}
+		lineIndent := len(line) - len(strings.TrimLeft(line, " "))
+		if indent < 0 {
+			first, indent = i+1, lineIndent
+		} else if lineIndent < indent {
+			break
+		}
+		body = append(body, line[indent:])
+	}
+	for len(body) > 0 && body[len(body)-1] == "" {
+		body = body[:len(body)-1]
+	}
+	return first, strings.Join(body, "\n")
+}
+
+var (
+	yamlTemplateKey   = regexp.MustCompile(`^(\{\{.*?\}\})(:(?:\s.*)?)$`)
+	yamlTemplateValue = regexp.MustCompile(`^((?:"[^"]*"|'[^']*'|[^\s"'{#][^#]*?):\s+)(\{\{.*\}\})\s*$`)
+	yamlBlockHeader   = regexp.MustCompile(`(?:^|:\s)[|>][-+0-9]*\s*(?:#.*)?$`)
+)
+
+// untemplateYAML rewrites Helm/Go template syntax, line by line, into YAML
+// that parses without moving any line:
+//   - a template that is a whole value or key, as in `image: {{ .Values.image }}`,
+//     is quoted;
+//   - a line that is only a template and sits deeper than a key or "-" with
+//     no value, as in `{{- toYaml .Values.resources | nindent 12 }}` under
+//     `resources:`, is quoted and becomes that key's value, unless the key
+//     has children after it;
+//   - any other line that is only a template, such as `{{- if .Values.x }}`,
+//     is blanked.
+//
+// Block scalar content is left alone. Templates that generate structure in
+// other ways, such as a key and value from one expression, still fail to
+// parse.
+func untemplateYAML(lines []string) []string {
+	out := make([]string, len(lines))
+	blockIndent := -1
+	openIndent := -1
+	for i, line := range lines {
+		out[i] = line
+		indent, content := yamlContent(line)
+		trimmed := strings.TrimSpace(line)
+		if blockIndent >= 0 {
+			if trimmed == "" || len(line)-len(strings.TrimLeft(line, " ")) > blockIndent {
+				continue
+			}
+			blockIndent = -1
+		}
+		if trimmed == "" || strings.HasPrefix(trimmed, "#") {
+			continue
+		}
+		if isYAMLTemplateLine(trimmed) {
+			if openIndent >= 0 && indent > openIndent && nextYAMLIndent(lines, i+1) <= openIndent {
+				out[i] = line[:indent] + quoteYAML(trimmed)
+				openIndent = -1
+			} else {
+				out[i] = ""
+			}
+			continue
+		}
+
+		prefix := line[:len(line)-len(content)]
+		switch {
+		case strings.HasPrefix(content, "{{") && strings.HasSuffix(strings.TrimSpace(content), "}}"):
+			out[i] = prefix + quoteYAML(strings.TrimSpace(content))
+		case yamlTemplateKey.MatchString(content):
+			match := yamlTemplateKey.FindStringSubmatch(content)
+			out[i] = prefix + quoteYAML(match[1]) + match[2]
+			content = match[2]
+		case yamlTemplateValue.MatchString(content):
+			match := yamlTemplateValue.FindStringSubmatch(content)
+			out[i] = prefix + match[1] + quoteYAML(match[2])
+		}
+
+		openIndent = -1
+		if value := strings.TrimSpace(stripYAMLComment(content)); value == "" || strings.HasSuffix(value, ":") {
+			openIndent = indent
+		}
+		if yamlBlockHeader.MatchString(content) {
+			blockIndent = indent
+			if strings.HasPrefix(content, "|") || strings.HasPrefix(content, ">") {
+				// "- |": the item itself is the block.
+				blockIndent = strings.LastIndex(prefix, "-")
+			}
+		}
+	}
+	return out
+}
+
+func isYAMLTemplateLine(trimmed string) bool {
+	return strings.HasPrefix(trimmed, "{{") && strings.HasSuffix(trimmed, "}}") && !strings.Contains(trimmed[2:len(trimmed)-2], "}}")
+}
+
+// nextYAMLIndent returns the indentation of the next line from start that
+// is not blank, a comment or only a template, or -1 at the end.
+func nextYAMLIndent(lines []string, start int) int {
+	for _, line := range lines[start:] {
+		trimmed := strings.TrimSpace(line)
+		if trimmed == "" || strings.HasPrefix(trimmed, "#") || isYAMLTemplateLine(trimmed) {
+			continue
+		}
+		return len(line) - len(strings.TrimLeft(line, " "))
+	}
+	return -1
+}
+
+// yamlContent splits a line after its indentation and any "- " sequence
+// markers, returning the column the content starts at.
+func yamlContent(line string) (int, string) {
+	content := strings.TrimLeft(line, " ")
+	for content == "-" || strings.HasPrefix(content, "- ") {
+		content = strings.TrimLeft(content[1:], " ")
+	}
+	return len(line) - len(content), content
+}
+
+func quoteYAML(text string) string {
+	return "'" + strings.ReplaceAll(text, "'", "''") + "'"
+}
+
+// stripYAMLComment drops a trailing comment outside quotes.
+func stripYAMLComment(text string) string {
+	var quote byte
+	for i := 0; i < len(text); i++ {
+		c := text[i]
+		switch {
+		case quote != 0:
+			if c == quote {
+				quote = 0
+			}
+		case (c == '"' || c == '\'') && (i == 0 || text[i-1] == ' ' || text[i-1] == ':' || text[i-1] == '[' || text[i-1] == '{' || text[i-1] == ','):
+			quote = c
+		case c == '#' && (i == 0 || text[i-1] == ' ' || text[i-1] == '\t'):
+			return text[:i]
+		}
+	}
+	return text
+}
@@ -0,0 +1,133 @@
+package analysis
+
+import "testing"
+
+func TestParseYAMLDocuments(t *testing.T) {
+	source := `# app manifests
+apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: "api"   # quoted
+  labels: {app: api, tier: "backend"}
+spec:
+  template:
+    spec:
+      containers:
+      - name: api
+        image: ghcr.io/acme/api:1.4.2
+        args: [--port, "8080"]
+        {{- with .Values.resources }}
+        resources:
+          {{- toYaml . | nindent 10 }}
+        {{- end }}
+      - name: sidecar
+        command:
+          - sh
+          - -c
+          - |
+            echo start
+            # keep comments in scripts
+
+            exec proxy
+---
+apiVersion: v1
+kind: Service
+`
+
+	documents := ParseYAMLDocuments(source)
+	if len(documents) != 2 {
+		t.Fatalf("expected 2 documents, got %d", len(documents))
+	}
+	deployment := documents[0]
+	if deployment.Get("kind").Text() != "Deployment" || deployment.Path("metadata", "name").Text() != "api" {
+		t.Fatalf("unexpected metadata: %+v", deployment.Get("metadata"))
+	}
+	if tier := deployment.Path("metadata", "labels", "tier"); tier.Text() != "backend" || tier.Line != 6 {
+		t.Fatalf("unexpected flow mapping value: %+v", tier)
+	}
+
+	containers := deployment.Path("spec", "template", "spec", "containers").Items
+	if len(containers) != 2 {
+		t.Fatalf("expected 2 containers, got %d", len(containers))
+	}
+	api := containers[0]
+	if image, ok := api.Pair("image"); !ok || image.Line != 12 || image.Value.Text() != "ghcr.io/acme/api:1.4.2" {
+		t.Fatalf("unexpected image pair: %+v", image)
+	}
+	if args := api.Get("args"); len(args.Items) != 2 || args.Items[1].Text() != "8080" {
+		t.Fatalf("unexpected args: %+v", args)
+	}
+	if resources := api.Get("resources"); resources == nil || !resources.Template || resources.IsEmpty() {
+		t.Fatalf("expected templated resources, got %+v", resources)
+	}
+	if api.Line != 11 || api.EndLine != 16 {
+		t.Fatalf("unexpected container span %d-%d", api.Line, api.EndLine)
+	}
+
+	script := containers[1].Get("command").Items[2]
+	if !script.Block || script.Line != 23 || script.Value != "echo start\n# keep comments in scripts\n\nexec proxy" {
+		t.Fatalf("unexpected block scalar: %+v", script)
+	}
+	if documents[1].Get("kind").Text() != "Service" {
+		t.Fatalf("unexpected second document: %+v", documents[1])
+	}
+}
+
+func TestParseYAMLDocumentsMultiLineFlowAndTemplates(t *testing.T) {
+	source := `apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: {{ include "app.fullname" . }}
+  annotations:
+    {{- if .Values.annotations }}
+    owner: {{ .Values.owner | quote }}
+    {{- end }}
+spec:
+  template:
+    spec:
+      containers:
+        - name: api
+          image: "{{ .Values.image.repository }}:{{ .Values.image.tag }}"
+          args: [
+            "--port", "8080",
+            "--verbose"
+          ]
+          env: {
+            LEVEL: debug,
+            MODE: fast }
+          command:
+            - sh
+            - -c
+            - >
+              echo one
+              echo two
+{{ .Values.extraSpec | toYaml }}: ignored
+`
+	documents := ParseYAMLDocuments(source)
+	if len(documents) != 1 {
+		t.Fatalf("expected 1 document, got %d", len(documents))
+	}
+	deployment := documents[0]
+	if name := deployment.Path("metadata", "name"); !name.Template || name.Line != 4 {
+		t.Fatalf("expected a templated name, got %+v", name)
+	}
+	if owner := deployment.Path("metadata", "annotations", "owner"); owner == nil || owner.Line != 7 {
+		t.Fatalf("expected the annotations under the if directive, got %+v", deployment.Path("metadata", "annotations"))
+	}
+
+	api := deployment.Path("spec", "template", "spec", "containers").Items[0]
+	args := api.Get("args").Items
+	if len(args) != 3 || args[1].Text() != "8080" || args[2].Line != 17 {
+		t.Fatalf("unexpected multi-line flow sequence: %+v", args)
+	}
+	if mode := api.Path("env", "MODE"); mode.Text() != "fast" || mode.Line != 21 {
+		t.Fatalf("unexpected multi-line flow mapping: %+v", api.Get("env"))
+	}
+	script := api.Get("command").Items[2]
+	if !script.Block || script.Line != 26 || script.Value != "echo one\necho two" || script.EndLine != 27 {
+		t.Fatalf("expected folded block lines to map to the source, got %+v", script)
+	}
+	if api.EndLine != 27 {
+		t.Fatalf("unexpected container end line %d", api.EndLine)
+	}
+}
@@ -6,25 +6,35 @@ import (
 	"crypto/sha256"
 	"encoding/hex"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"log"
 	"net/http"
+	"strconv"
 	"strings"
 
 	"github.com/example/pr-ai-teammate/internal/orchestrator"
 	"github.com/example/pr-ai-teammate/internal/types"
+	"github.com/example/pr-ai-teammate/internal/vulndb"
 )
 
 type Handlers struct {
 	orchestrator  Analyzer
 	webhookSecret string
+	adminToken    string
 }
 
 type Analyzer interface {
 	AnalyzePR(ctx context.Context, input orchestrator.AnalyzeInput) (orchestrator.AnalyzeResult, error)
+	IngestFindings(ctx context.Context, input orchestrator.IngestInput) (orchestrator.IngestResult, error)
+	RefreshVulnDB(ctx context.Context) (vulndb.Stats, error)
+	AIUsage(ctx context.Context, input orchestrator.UsageInput) (orchestrator.UsageReport, error)
+	WriteMetrics(w io.Writer) error
 }
 
+const maxReportBytes = 10 << 20
+
 var _ Analyzer = (*orchestrator.Service)(nil)
 
 func NewHandlers(orchestrator Analyzer, webhookSecret string) *Handlers {
@@ -34,6 +44,13 @@ func NewHandlers(orchestrator Analyzer, webhookSecret string) *Handlers {
 	}
 }
 
+// SetAdminToken enables the /admin endpoints and /analyze/findings for
+// requests carrying "Authorization: Bearer <token>". Without a token they
+// always return 403.
+func (h *Handlers) SetAdminToken(token string) {
+	h.adminToken = token
+}
+
 func (h *Handlers) Health(w http.ResponseWriter, r *http.Request) {
 	respondJSON(w, http.StatusOK, types.HealthResponse{Status: "ok"})
 }
@@ -114,6 +131,142 @@ func (h *Handlers) AnalyzePR(w http.ResponseWriter, r *http.Request) {
 	})
 }
 
+// IngestFindings stores a linter report for a PR commit. Its findings are
+// posted as review comments, so it needs the admin token like the /admin
+// endpoints.
+func (h *Handlers) IngestFindings(w http.ResponseWriter, r *http.Request) {
+	if !h.authorizeAdmin(r) {
+		respondError(w, http.StatusForbidden, "admin token required")
+		return
+	}
+
+	query := r.URL.Query()
+	pullNumber, err := strconv.Atoi(query.Get("pull_number"))
+	if err != nil {
+		respondError(w, http.StatusBadRequest, "invalid pull_number")
+		return
+	}
+
+	report, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxReportBytes))
+	if err != nil {
+		respondError(w, http.StatusBadRequest, "unable to read report body")
+		return
+	}
+
+	result, err := h.orchestrator.IngestFindings(r.Context(), orchestrator.IngestInput{
+		Repository: query.Get("repository"),
+		PullNumber: pullNumber,
+		CommitSHA:  query.Get("commit_sha"),
+		Format:     query.Get("format"),
+		Tool:       query.Get("tool"),
+		Report:     report,
+	})
+	if errors.Is(err, orchestrator.ErrInvalidFindings) {
+		respondError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	if err != nil {
+		log.Printf("ingest findings error: %v", err)
+		respondError(w, http.StatusInternalServerError, "unable to store findings")
+		return
+	}
+
+	respondJSON(w, http.StatusAccepted, types.IngestResponse{
+		Status:   "stored",
+		Source:   result.Source,
+		Accepted: result.Accepted,
+	})
+}
+
+func (h *Handlers) RefreshVulnDB(w http.ResponseWriter, r *http.Request) {
+	if !h.authorizeAdmin(r) {
+		respondError(w, http.StatusForbidden, "admin token required")
+		return
+	}
+
+	stats, err := h.orchestrator.RefreshVulnDB(r.Context())
+	if errors.Is(err, vulndb.ErrNotConfigured) {
+		respondError(w, http.StatusConflict, err.Error())
+		return
+	}
+	if err != nil {
+		respondError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	respondJSON(w, http.StatusOK, types.VulnDBRefreshResponse{
+		Status:     "refreshed",
+		Source:     stats.Source,
+		Advisories: stats.Advisories,
+		Packages:   stats.Packages,
+		Skipped:    stats.Skipped,
+		LoadedAt:   stats.LoadedAt,
+	})
+}
+
+func (h *Handlers) AIUsage(w http.ResponseWriter, r *http.Request) {
+	if !h.authorizeAdmin(r) {
+		respondError(w, http.StatusForbidden, "admin token required")
+		return
+	}
+
+	query := r.URL.Query()
+	pullNumber := 0
+	if value := query.Get("pull_number"); value != "" {
+		number, err := strconv.Atoi(value)
+		if err != nil || number <= 0 {
+			respondError(w, http.StatusBadRequest, "invalid pull_number")
+			return
+		}
+		pullNumber = number
+	}
+
+	report, err := h.orchestrator.AIUsage(r.Context(), orchestrator.UsageInput{
+		Repository: query.Get("repository"),
+		PullNumber: pullNumber,
+		Month:      query.Get("month"),
+	})
+	if errors.Is(err, orchestrator.ErrInvalidUsageQuery) {
+		respondError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	if err != nil {
+		log.Printf("ai usage error: %v", err)
+		respondError(w, http.StatusInternalServerError, "unable to load AI usage")
+		return
+	}
+
+	respondJSON(w, http.StatusOK, types.AIUsageResponse{
+		Repository:       query.Get("repository"),
+		PullNumber:       pullNumber,
+		Month:            query.Get("month"),
+		Calls:            report.Calls,
+		InputTokens:      report.InputTokens,
+		OutputTokens:     report.OutputTokens,
+		CostUSD:          report.CostUSD,
+		FindingsChecked:  report.FindingsChecked,
+		FindingsDropped:  report.FindingsDropped,
+		FindingsDropRate: report.DropRate(),
+		MonthToDateUSD:   report.MonthToDateUSD,
+		MonthlyBudgetUSD: report.MonthlyBudgetUSD,
+	})
+}
+
+func (h *Handlers) Metrics(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4")
+	if err := h.orchestrator.WriteMetrics(w); err != nil {
+		log.Printf("metrics error: %v", err)
+	}
+}
+
+func (h *Handlers) authorizeAdmin(r *http.Request) bool {
+	if h.adminToken == "" {
+		return false
+	}
+	token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+	return ok && hmac.Equal([]byte(token), []byte(h.adminToken))
+}
+
 func respondJSON(w http.ResponseWriter, status int, payload any) {
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(status)
@@ -7,18 +7,28 @@ import (
 	"crypto/sha256"
 	"encoding/hex"
 	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
 	"net/http"
 	"net/http/httptest"
+	"strings"
 	"testing"
 
 	"github.com/example/pr-ai-teammate/internal/orchestrator"
+	"github.com/example/pr-ai-teammate/internal/vulndb"
 )
 
 type stubAnalyzer struct {
-	called bool
-	input  orchestrator.AnalyzeInput
-	result orchestrator.AnalyzeResult
-	err    error
+	called       bool
+	input        orchestrator.AnalyzeInput
+	result       orchestrator.AnalyzeResult
+	ingestInput  orchestrator.IngestInput
+	ingestResult orchestrator.IngestResult
+	vulnStats    vulndb.Stats
+	usageInput   orchestrator.UsageInput
+	usageReport  orchestrator.UsageReport
+	err          error
 }
 
 func (s *stubAnalyzer) AnalyzePR(ctx context.Context, input orchestrator.AnalyzeInput) (orchestrator.AnalyzeResult, error) {
@@ -27,6 +37,28 @@ func (s *stubAnalyzer) AnalyzePR(ctx context.Context, input orchestrator.Analyze
 	return s.result, s.err
 }
 
+func (s *stubAnalyzer) IngestFindings(ctx context.Context, input orchestrator.IngestInput) (orchestrator.IngestResult, error) {
+	s.called = true
+	s.ingestInput = input
+	return s.ingestResult, s.err
+}
+
+func (s *stubAnalyzer) RefreshVulnDB(ctx context.Context) (vulndb.Stats, error) {
+	s.called = true
+	return s.vulnStats, s.err
+}
+
+func (s *stubAnalyzer) AIUsage(ctx context.Context, input orchestrator.UsageInput) (orchestrator.UsageReport, error) {
+	s.called = true
+	s.usageInput = input
+	return s.usageReport, s.err
+}
+
+func (s *stubAnalyzer) WriteMetrics(w io.Writer) error {
+	_, err := io.WriteString(w, "ai_teammate_ai_budget_exceeded_total 0\n")
+	return err
+}
+
 func TestHealth(t *testing.T) {
 	handlers := NewHandlers(&stubAnalyzer{}, "")
 	req := httptest.NewRequest(http.MethodGet, "/health", nil)
@@ -197,6 +229,203 @@ func TestAnalyzePRAcceptsRequest(t *testing.T) {
 	}
 }
 
+func TestIngestFindingsStoresReport(t *testing.T) {
+	stub := &stubAnalyzer{ingestResult: orchestrator.IngestResult{Source: "semgrep", Accepted: 2}}
+	handlers := NewHandlers(stub, "")
+	handlers.SetAdminToken("s3cret")
+
+	req := httptest.NewRequest(http.MethodPost, "/analyze/findings?repository=acme/demo&pull_number=7&commit_sha=abc123&format=sarif&tool=semgrep", bytes.NewBufferString(`{"version":"2.1.0","runs":[]}`))
+	req.Header.Set("Authorization", "Bearer s3cret")
+	res := httptest.NewRecorder()
+
+	handlers.IngestFindings(res, req)
+
+	if res.Code != http.StatusAccepted {
+		t.Fatalf("expected status 202, got %d", res.Code)
+	}
+	if stub.ingestInput.Repository != "acme/demo" || stub.ingestInput.PullNumber != 7 || stub.ingestInput.CommitSHA != "abc123" {
+		t.Fatalf("unexpected ingest input: %+v", stub.ingestInput)
+	}
+	if stub.ingestInput.Format != "sarif" || stub.ingestInput.Tool != "semgrep" {
+		t.Fatalf("unexpected format or tool: %+v", stub.ingestInput)
+	}
+	if string(stub.ingestInput.Report) != `{"version":"2.1.0","runs":[]}` {
+		t.Fatalf("unexpected report body: %s", stub.ingestInput.Report)
+	}
+}
+
+func TestIngestFindingsInvalidPullNumber(t *testing.T) {
+	stub := &stubAnalyzer{}
+	handlers := NewHandlers(stub, "")
+	handlers.SetAdminToken("s3cret")
+	req := httptest.NewRequest(http.MethodPost, "/analyze/findings?repository=acme/demo&pull_number=abc", nil)
+	req.Header.Set("Authorization", "Bearer s3cret")
+	res := httptest.NewRecorder()
+
+	handlers.IngestFindings(res, req)
+
+	if res.Code != http.StatusBadRequest {
+		t.Fatalf("expected status 400, got %d", res.Code)
+	}
+	if stub.called {
+		t.Fatalf("expected analyzer not to be called")
+	}
+}
+
+func TestIngestFindingsErrors(t *testing.T) {
+	for _, tc := range []struct {
+		err  error
+		code int
+	}{
+		{fmt.Errorf("%w: unknown format \"xml\"", orchestrator.ErrInvalidFindings), http.StatusBadRequest},
+		{errors.New("pq: connection refused"), http.StatusInternalServerError},
+	} {
+		handlers := NewHandlers(&stubAnalyzer{err: tc.err}, "")
+		handlers.SetAdminToken("s3cret")
+		req := httptest.NewRequest(http.MethodPost, "/analyze/findings?repository=acme/demo&pull_number=7&commit_sha=abc123&format=xml", bytes.NewBufferString("{}"))
+		req.Header.Set("Authorization", "Bearer s3cret")
+		res := httptest.NewRecorder()
+		handlers.IngestFindings(res, req)
+		if res.Code != tc.code {
+			t.Fatalf("expected %d for %v, got %d", tc.code, tc.err, res.Code)
+		}
+		if tc.code == http.StatusInternalServerError && strings.Contains(res.Body.String(), "pq:") {
+			t.Fatalf("store error leaked to the client: %s", res.Body.String())
+		}
+	}
+}
+
+func TestIngestFindingsRequiresAdminToken(t *testing.T) {
+	stub := &stubAnalyzer{}
+	handlers := NewHandlers(stub, "")
+	handlers.SetAdminToken("s3cret")
+
+	for _, header := range []string{"", "Bearer wrong"} {
+		req := httptest.NewRequest(http.MethodPost, "/analyze/findings?repository=acme/demo&pull_number=7&commit_sha=abc123&format=sarif", bytes.NewBufferString(`{"version":"2.1.0","runs":[]}`))
+		if header != "" {
+			req.Header.Set("Authorization", header)
+		}
+		res := httptest.NewRecorder()
+		handlers.IngestFindings(res, req)
+		if res.Code != http.StatusForbidden || stub.called {
+			t.Fatalf("expected 403 without calling the analyzer for %q, got %d", header, res.Code)
+		}
+	}
+}
+
+func TestRefreshVulnDBRequiresAdminToken(t *testing.T) {
+	stub := &stubAnalyzer{vulnStats: vulndb.Stats{Source: "/data/osv", Advisories: 3, Packages: 2}}
+	handlers := NewHandlers(stub, "")
+
+	req := httptest.NewRequest(http.MethodPost, "/admin/vulndb/refresh", nil)
+	req.Header.Set("Authorization", "Bearer anything")
+	res := httptest.NewRecorder()
+	handlers.RefreshVulnDB(res, req)
+	if res.Code != http.StatusForbidden || stub.called {
+		t.Fatalf("expected 403 without a configured token, got %d", res.Code)
+	}
+
+	handlers.SetAdminToken("s3cret")
+	req = httptest.NewRequest(http.MethodPost, "/admin/vulndb/refresh", nil)
+	req.Header.Set("Authorization", "Bearer wrong")
+	res = httptest.NewRecorder()
+	handlers.RefreshVulnDB(res, req)
+	if res.Code != http.StatusForbidden || stub.called {
+		t.Fatalf("expected 403 for a wrong token, got %d", res.Code)
+	}
+
+	req = httptest.NewRequest(http.MethodPost, "/admin/vulndb/refresh", nil)
+	req.Header.Set("Authorization", "Bearer s3cret")
+	res = httptest.NewRecorder()
+	handlers.RefreshVulnDB(res, req)
+	if res.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d", res.Code)
+	}
+	var body map[string]any
+	if err := json.Unmarshal(res.Body.Bytes(), &body); err != nil {
+		t.Fatalf("invalid response: %v", err)
+	}
+	if body["advisories"] != float64(3) || body["source"] != "/data/osv" {
+		t.Fatalf("unexpected response: %v", body)
+	}
+}
+
+func TestAIUsage(t *testing.T) {
+	stub := &stubAnalyzer{}
+	stub.usageReport.Calls = 4
+	stub.usageReport.CostUSD = 1.25
+	stub.usageReport.MonthlyBudgetUSD = 50
+	stub.usageReport.FindingsChecked = 8
+	stub.usageReport.FindingsDropped = 2
+	handlers := NewHandlers(stub, "")
+	handlers.SetAdminToken("s3cret")
+
+	req := httptest.NewRequest(http.MethodGet, "/admin/ai/usage?repository=acme/api&pull_number=7&month=2024-05", nil)
+	res := httptest.NewRecorder()
+	handlers.AIUsage(res, req)
+	if res.Code != http.StatusForbidden || stub.called {
+		t.Fatalf("expected 403 without a token, got %d", res.Code)
+	}
+
+	req.Header.Set("Authorization", "Bearer s3cret")
+	res = httptest.NewRecorder()
+	handlers.AIUsage(res, req)
+	if res.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d: %s", res.Code, res.Body.String())
+	}
+	if stub.usageInput != (orchestrator.UsageInput{Repository: "acme/api", PullNumber: 7, Month: "2024-05"}) {
+		t.Fatalf("unexpected usage input: %+v", stub.usageInput)
+	}
+	var body map[string]any
+	if err := json.Unmarshal(res.Body.Bytes(), &body); err != nil {
+		t.Fatalf("invalid response: %v", err)
+	}
+	if body["calls"] != float64(4) || body["cost_usd"] != 1.25 || body["monthly_budget_usd"] != float64(50) || body["findings_drop_rate"] != 0.25 {
+		t.Fatalf("unexpected response: %v", body)
+	}
+
+	stub.called = false
+	req = httptest.NewRequest(http.MethodGet, "/admin/ai/usage?repository=acme/api&pull_number=x", nil)
+	req.Header.Set("Authorization", "Bearer s3cret")
+	res = httptest.NewRecorder()
+	handlers.AIUsage(res, req)
+	if res.Code != http.StatusBadRequest || stub.called {
+		t.Fatalf("expected 400 for an invalid pull_number, got %d", res.Code)
+	}
+
+	for _, tc := range []struct {
+		err  error
+		code int
+	}{
+		{fmt.Errorf("%w: month %q is not YYYY-MM", orchestrator.ErrInvalidUsageQuery, "May"), http.StatusBadRequest},
+		{errors.New("pq: connection refused"), http.StatusInternalServerError},
+	} {
+		stub.err = tc.err
+		req = httptest.NewRequest(http.MethodGet, "/admin/ai/usage?repository=acme/api", nil)
+		req.Header.Set("Authorization", "Bearer s3cret")
+		res = httptest.NewRecorder()
+		handlers.AIUsage(res, req)
+		if res.Code != tc.code {
+			t.Fatalf("expected %d for %v, got %d", tc.code, tc.err, res.Code)
+		}
+		if tc.code == http.StatusInternalServerError && strings.Contains(res.Body.String(), "pq:") {
+			t.Fatalf("store error leaked to the client: %s", res.Body.String())
+		}
+	}
+}
+
+func TestMetrics(t *testing.T) {
+	handlers := NewHandlers(&stubAnalyzer{}, "")
+	res := httptest.NewRecorder()
+	handlers.Metrics(res, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	if res.Code != http.StatusOK || !strings.HasPrefix(res.Header().Get("Content-Type"), "text/plain") {
+		t.Fatalf("unexpected response %d %q", res.Code, res.Header().Get("Content-Type"))
+	}
+	if !strings.Contains(res.Body.String(), "ai_teammate_ai_budget_exceeded_total 0") {
+		t.Fatalf("unexpected body: %s", res.Body.String())
+	}
+}
+
 func signPayload(secret string, payload []byte) string {
 	mac := hmac.New(sha256.New, []byte(secret))
 	mac.Write(payload)
@@ -0,0 +1,455 @@
+package config
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"path"
+	"regexp"
+	"strings"
+	"text/template"
+
+	"github.com/example/pr-ai-teammate/internal/ai"
+	"github.com/example/pr-ai-teammate/internal/analysis"
+)
+
+// Path is where repositories keep their review configuration. It is read
+// from the PR's base revision so a PR cannot relax its own checks.
+const Path = ".github/ai-teammate.json"
+
+type RepoConfig struct {
+	Complexity    ComplexityConfig    `json:"complexity"`
+	MissingTests  MissingTestsConfig  `json:"missing_tests"`
+	CustomRules   []CustomRule        `json:"custom_rules"`
+	SecretLogging SecretLoggingConfig `json:"secret_logging"`
+	Dependencies  DependenciesConfig  `json:"dependencies"`
+	Migrations    MigrationsConfig    `json:"migrations"`
+	Generated     GeneratedConfig     `json:"generated"`
+	AIContext     AIContextConfig     `json:"ai_context"`
+	AI            AIConfig            `json:"ai"`
+}
+
+// AIProviders are the backends a repository can ask for by name.
+var AIProviders = []string{"openai", "azure", "anthropic", "ollama", "llamacpp"}
+
+// builtinRuleIDs are the rule IDs the built-in rules and analyzers report.
+// Suppression, redaction and the summary treat some of them specially, so a
+// custom rule may not reuse one.
+var builtinRuleIDs = map[string]bool{
+	"todo": true, "secrets": true, "large-diff": true, "missing-tests": true,
+	"migrations": true, "dockerfile": true, "kubernetes": true, "workflow": true,
+
+	"cognitive-complexity": true, "cyclomatic-complexity": true, "func-length": true,
+	"empty-error-check": true, "go-parse": true, "panic": true,
+	"context-dropped": true, "http-without-context": true, "io-without-context": true, "sql-without-context": true,
+	"defer-in-loop": true, "lock-copied": true, "loop-var-capture": true, "waitgroup-add-in-goroutine": true,
+	"secret-logging": true, "prompt-injection": true,
+	"suppression-invalid": true, "suppression-without-reason": true,
+
+	"dependency-denied": true, "dependency-major-bump": true, "dependency-not-allowed": true,
+	"dependency-pseudo-version": true, "dependency-replace": true, "dependency-unpinned": true,
+	"vulnerable-dependency": true,
+
+	"docker-add-remote": true, "docker-apt-no-cleanup": true, "docker-latest-tag": true,
+	"docker-missing-healthcheck": true, "docker-root-user": true, "docker-secret-in-env": true,
+	"k8s-hostpath": true, "k8s-latest-tag": true, "k8s-missing-probes": true,
+	"k8s-missing-resources": true, "k8s-privileged": true,
+	"migration-drop": true, "migration-edited": true, "migration-index-not-concurrent": true,
+	"migration-large-table-lock": true, "migration-not-null-without-default": true, "migration-rename": true,
+	"workflow-pr-target-checkout": true, "workflow-script-injection": true,
+	"workflow-unpinned-action": true, "workflow-write-all": true,
+}
+
+// IsBuiltinRuleID reports whether id belongs to a built-in rule or to the AI
+// review, whose findings use the "ai-" prefix.
+func IsBuiltinRuleID(id string) bool {
+	return builtinRuleIDs[id] || strings.HasPrefix(id, "ai-")
+}
+
+// AIConfig picks the LLM provider for a repository. SelfHostedOnly skips the
+// AI review rather than send code to a third-party provider. Files matching
+// NeverSend globs are left out of the AI prompt entirely, and text matching
+// RedactPatterns is replaced like detected secrets.
+type AIConfig struct {
+	Provider       string             `json:"provider"`
+	SelfHostedOnly bool               `json:"self_hosted_only"`
+	NeverSend      []string           `json:"never_send"`
+	RedactPatterns []string           `json:"redact_patterns"`
+	Prompts        PromptsConfig      `json:"prompts"`
+	Passes         []PassConfig       `json:"passes"`
+	Verification   VerificationConfig `json:"verification"`
+}
+
+// VerificationConfig controls the pass that asks the model to re-check its
+// findings against the code. Findings below MinConfidence are listed as
+// possible concerns in the summary, or dropped when LowConfidence is "drop".
+// MinConfidence is a pointer so an explicit 0, which keeps every confirmed
+// finding, can be told apart from leaving it unset.
+type VerificationConfig struct {
+	Disabled      bool     `json:"disabled"`
+	MinConfidence *float64 `json:"min_confidence"`
+	LowConfidence string   `json:"low_confidence"`
+	Provider      string   `json:"provider"`
+}
+
+func (c VerificationConfig) AI() ai.VerificationConfig {
+	minConfidence := ai.DefaultMinConfidence
+	if c.MinConfidence != nil {
+		minConfidence = *c.MinConfidence
+	}
+	return ai.VerificationConfig{
+		Enabled:           !c.Disabled,
+		MinConfidence:     minConfidence,
+		DropLowConfidence: c.LowConfidence == "drop",
+		Provider:          c.Provider,
+	}
+}
+
+// PassConfig is a focused review pass run alongside the general review: the
+// prompt variant, an optional provider for it, and the changed paths (globs)
+// or added-line regular expressions that make it run.
+type PassConfig struct {
+	Variant  string   `json:"variant"`
+	Provider string   `json:"provider"`
+	Paths    []string `json:"paths"`
+	Content  []string `json:"content"`
+}
+
+// ReviewPasses returns the specialist passes the repository opted in to. A
+// built-in variant listed without paths or content gets the built-in
+// triggers.
+func (c AIConfig) ReviewPasses() []ai.Pass {
+	passes := make([]ai.Pass, 0, len(c.Passes))
+	for _, pass := range c.Passes {
+		converted := ai.Pass{Variant: pass.Variant, Provider: pass.Provider, Paths: pass.Paths, Content: pass.Content}
+		if len(pass.Paths) == 0 && len(pass.Content) == 0 {
+			for _, builtin := range ai.DefaultPasses() {
+				if builtin.Variant == pass.Variant {
+					converted.Paths, converted.Content = builtin.Paths, builtin.Content
+				}
+			}
+		}
+		passes = append(passes, converted)
+	}
+	return passes
+}
+
+// PromptsConfig picks the focus of the AI review (general, security,
+// performance or api-design) and customizes its prompt. Templates are Go
+// text/templates that replace "guidelines" or define "focus/<variant>";
+// Instructions are appended to every prompt.
+type PromptsConfig struct {
+	Variant      string            `json:"variant"`
+	Templates    map[string]string `json:"templates"`
+	Instructions string            `json:"instructions"`
+}
+
+func (c PromptsConfig) AI() ai.PromptConfig {
+	return ai.PromptConfig{Variant: c.Variant, Templates: c.Templates, Instructions: c.Instructions}
+}
+
+// AIContextConfig lists repository documents, read from the base branch,
+// that the AI reviewer sees alongside the diff, and the token budget shared
+// by those documents, rule findings and touched file contents.
+type AIContextConfig struct {
+	Documents []string `json:"documents"`
+	MaxTokens int      `json:"max_tokens"`
+}
+
+// GeneratedConfig lists globs of generated files to skip in addition to
+// files with a "Code generated ... DO NOT EDIT." header and
+// linguist-generated entries in .gitattributes.
+type GeneratedConfig struct {
+	Paths []string `json:"paths"`
+}
+
+// MigrationsConfig selects SQL migration files by glob. LargeTables lists
+// tables (optionally schema-qualified) where any ALTER TABLE is flagged.
+type MigrationsConfig struct {
+	Disabled    bool     `json:"disabled"`
+	Paths       []string `json:"paths"`
+	LargeTables []string `json:"large_tables"`
+	Dialect     string   `json:"dialect"`
+}
+
+// DependenciesConfig lists dependency names or path.Match globs that PRs may
+// (allow) or must not (deny) add. An empty allow list allows everything.
+type DependenciesConfig struct {
+	Allow []string `json:"allow"`
+	Deny  []string `json:"deny"`
+}
+
+// SecretLoggingConfig replaces the built-in list of identifier fragments
+// (password, token, secret, ...) that mark a value as sensitive.
+type SecretLoggingConfig struct {
+	SensitiveNames []string `json:"sensitive_names"`
+}
+
+type ComplexityConfig struct {
+	MaxCyclomatic int `json:"max_cyclomatic"`
+	MaxCognitive  int `json:"max_cognitive"`
+}
+
+type MissingTestsConfig struct {
+	Disabled        bool     `json:"disabled"`
+	MinChangedLines int      `json:"min_changed_lines"`
+	ExemptPaths     []string `json:"exempt_paths"`
+}
+
+const (
+	CustomRuleRegex = "regex"
+	CustomRuleAST   = "ast"
+)
+
+// CustomRule is a team-defined rule. Regex rules match Pattern against added
+// lines; AST rules match Go calls whose callee matches the Call glob (for
+// example "log.Printf" or "*.Exec") and, when ArgName is set, that pass an
+// identifier or field whose name matches the ArgName regex. Fix is an
+// optional text/template rendered with .File, .Line, .Match, .Call and .Arg.
+type CustomRule struct {
+	ID        string   `json:"id"`
+	Type      string   `json:"type"`
+	Pattern   string   `json:"pattern"`
+	Call      string   `json:"call"`
+	ArgName   string   `json:"arg_name"`
+	Paths     []string `json:"paths"`
+	FileTypes []string `json:"file_types"`
+	Message   string   `json:"message"`
+	Severity  string   `json:"severity"`
+	Fix       string   `json:"fix"`
+}
+
+func Default() RepoConfig {
+	return RepoConfig{
+		Complexity: ComplexityConfig{
+			MaxCyclomatic: 10,
+			MaxCognitive:  15,
+		},
+		MissingTests: MissingTestsConfig{
+			MinChangedLines: 10,
+			ExemptPaths:     []string{"*.md", "docs/**", "cmd/**"},
+		},
+		Migrations: MigrationsConfig{
+			Paths:   []string{"**/migrations/**/*.sql", "**/migrate/**/*.sql"},
+			Dialect: "postgres",
+		},
+		Generated: GeneratedConfig{
+			Paths: []string{"*.pb.go", "*.pb.gw.go", "*_pb2.py", "*.gen.go", "zz_generated.*.go"},
+		},
+		AIContext: AIContextConfig{
+			Documents: []string{"README.md", "CONTRIBUTING.md", "ARCHITECTURE.md", "CONVENTIONS.md"},
+			MaxTokens: 12000,
+		},
+		AI: AIConfig{
+			Verification: VerificationConfig{LowConfidence: "collapse"},
+		},
+	}
+}
+
+// Parse decodes a repo config on top of the defaults, so omitted fields keep
+// their default values.
+func Parse(data []byte) (RepoConfig, error) {
+	cfg := Default()
+	if len(bytes.TrimSpace(data)) == 0 {
+		return cfg, nil
+	}
+
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&cfg); err != nil {
+		return Default(), fmt.Errorf("%s: %w", Path, err)
+	}
+	if err := cfg.Validate(); err != nil {
+		return Default(), fmt.Errorf("%s: %w", Path, err)
+	}
+	return cfg, nil
+}
+
+func (c RepoConfig) Validate() error {
+	var problems []string
+	if c.Complexity.MaxCyclomatic < 0 {
+		problems = append(problems, "complexity.max_cyclomatic must not be negative")
+	}
+	if c.Complexity.MaxCognitive < 0 {
+		problems = append(problems, "complexity.max_cognitive must not be negative")
+	}
+	if c.MissingTests.MinChangedLines < 0 {
+		problems = append(problems, "missing_tests.min_changed_lines must not be negative")
+	}
+	problems = append(problems, invalidGlobs("missing_tests.exempt_paths", c.MissingTests.ExemptPaths)...)
+	problems = append(problems, invalidPatterns("dependencies.allow", c.Dependencies.Allow)...)
+	problems = append(problems, invalidPatterns("dependencies.deny", c.Dependencies.Deny)...)
+	problems = append(problems, invalidGlobs("migrations.paths", c.Migrations.Paths)...)
+	problems = append(problems, invalidGlobs("generated.paths", c.Generated.Paths)...)
+	if c.AIContext.MaxTokens < 0 {
+		problems = append(problems, "ai_context.max_tokens must not be negative")
+	}
+	if c.AI.Provider != "" && !containsString(AIProviders, c.AI.Provider) {
+		problems = append(problems, fmt.Sprintf("ai.provider must be one of %s, got %q", strings.Join(AIProviders, ", "), c.AI.Provider))
+	}
+	problems = append(problems, invalidGlobs("ai.never_send", c.AI.NeverSend)...)
+	for i, pattern := range c.AI.RedactPatterns {
+		if _, err := regexp.Compile(pattern); err != nil {
+			problems = append(problems, fmt.Sprintf("ai.redact_patterns[%d]: invalid pattern: %v", i, err))
+		}
+	}
+	if prompts, err := ai.NewPrompts(c.AI.Prompts.AI()); err != nil {
+		problems = append(problems, fmt.Sprintf("ai.prompts: %v", err))
+	} else {
+		if c.AI.Prompts.Variant != "" && !prompts.HasVariant(c.AI.Prompts.Variant) {
+			problems = append(problems, fmt.Sprintf("ai.prompts.variant must be one of %s or a focus/<variant> template, got %q", strings.Join(ai.Variants(), ", "), c.AI.Prompts.Variant))
+		}
+		for i, pass := range c.AI.Passes {
+			problems = append(problems, pass.validate(i, prompts)...)
+		}
+	}
+	if minConfidence := c.AI.Verification.MinConfidence; minConfidence != nil && (*minConfidence < 0 || *minConfidence > 1) {
+		problems = append(problems, fmt.Sprintf("ai.verification.min_confidence must be between 0 and 1, got %g", *minConfidence))
+	}
+	if c.AI.Verification.LowConfidence != "collapse" && c.AI.Verification.LowConfidence != "drop" {
+		problems = append(problems, fmt.Sprintf("ai.verification.low_confidence must be collapse or drop, got %q", c.AI.Verification.LowConfidence))
+	}
+	if c.AI.Verification.Provider != "" && !containsString(AIProviders, c.AI.Verification.Provider) {
+		problems = append(problems, fmt.Sprintf("ai.verification.provider must be one of %s, got %q", strings.Join(AIProviders, ", "), c.AI.Verification.Provider))
+	}
+	for i, document := range c.AIContext.Documents {
+		if strings.TrimSpace(document) == "" || strings.ContainsAny(document, "*?[") {
+			problems = append(problems, fmt.Sprintf("ai_context.documents[%d]: expected a file path, got %q", i, document))
+		}
+	}
+	switch c.Migrations.Dialect {
+	case "postgres", "mysql", "sqlite":
+	default:
+		problems = append(problems, fmt.Sprintf("migrations.dialect must be postgres, mysql or sqlite, got %q", c.Migrations.Dialect))
+	}
+	seen := map[string]bool{}
+	for i, rule := range c.CustomRules {
+		problems = append(problems, rule.validate(i, seen)...)
+	}
+	if len(problems) > 0 {
+		return &ValidationError{Problems: problems}
+	}
+	return nil
+}
+
+func (p PassConfig) validate(i int, prompts *ai.Prompts) []string {
+	field := fmt.Sprintf("ai.passes[%d]", i)
+	var problems []string
+	if !prompts.HasVariant(p.Variant) {
+		problems = append(problems, fmt.Sprintf("%s.variant must be one of %s or a focus/<variant> template, got %q", field, strings.Join(ai.Variants(), ", "), p.Variant))
+	}
+	if p.Provider != "" && !containsString(AIProviders, p.Provider) {
+		problems = append(problems, fmt.Sprintf("%s.provider must be one of %s, got %q", field, strings.Join(AIProviders, ", "), p.Provider))
+	}
+	problems = append(problems, invalidGlobs(field+".paths", p.Paths)...)
+	for j, content := range p.Content {
+		if _, err := regexp.Compile(content); err != nil {
+			problems = append(problems, fmt.Sprintf("%s.content[%d]: invalid pattern: %v", field, j, err))
+		}
+	}
+	return problems
+}
+
+// ValidationError lists every problem found in a config so they can be
+// reported together instead of one per push.
+type ValidationError struct {
+	Problems []string
+}
+
+func (e *ValidationError) Error() string {
+	return "invalid config: " + strings.Join(e.Problems, "; ")
+}
+
+func invalidGlobs(field string, patterns []string) []string {
+	var problems []string
+	for i, pattern := range patterns {
+		if !analysis.ValidGlob(pattern) {
+			problems = append(problems, fmt.Sprintf("%s[%d]: invalid glob %q", field, i, pattern))
+		}
+	}
+	return problems
+}
+
+func invalidPatterns(field string, patterns []string) []string {
+	var problems []string
+	for i, pattern := range patterns {
+		if _, err := path.Match(pattern, ""); err != nil || pattern == "" {
+			problems = append(problems, fmt.Sprintf("%s[%d]: invalid pattern %q", field, i, pattern))
+		}
+	}
+	return problems
+}
+
+func (r CustomRule) validate(index int, seen map[string]bool) []string {
+	field := fmt.Sprintf("custom_rules[%d]", index)
+	if r.ID != "" {
+		field = fmt.Sprintf("custom_rules[%d] %q", index, r.ID)
+	}
+
+	var problems []string
+	fail := func(format string, args ...any) {
+		problems = append(problems, field+": "+fmt.Sprintf(format, args...))
+	}
+
+	switch {
+	case r.ID == "":
+		fail("id is required")
+	case seen[r.ID]:
+		fail("duplicate id")
+	case IsBuiltinRuleID(r.ID):
+		fail("id is reserved for a built-in rule")
+	}
+	seen[r.ID] = true
+
+	switch r.Type {
+	case CustomRuleRegex:
+		if r.Pattern == "" {
+			fail("pattern is required for regex rules")
+		} else if _, err := regexp.Compile(r.Pattern); err != nil {
+			fail("invalid pattern: %v", err)
+		}
+	case CustomRuleAST:
+		if r.Call == "" {
+			fail("call is required for ast rules")
+		} else if _, err := path.Match(r.Call, ""); err != nil {
+			fail("invalid call pattern %q", r.Call)
+		}
+		if r.ArgName != "" {
+			if _, err := regexp.Compile(r.ArgName); err != nil {
+				fail("invalid arg_name: %v", err)
+			}
+		}
+	default:
+		fail("type must be %q or %q, got %q", CustomRuleRegex, CustomRuleAST, r.Type)
+	}
+
+	if r.Message == "" {
+		fail("message is required")
+	}
+	switch r.Severity {
+	case "high", "medium", "low":
+	default:
+		fail("severity must be high, medium or low, got %q", r.Severity)
+	}
+	for _, fileType := range r.FileTypes {
+		if !analysis.FileType(fileType).Valid() {
+			fail("unknown file type %q", fileType)
+		}
+	}
+	problems = append(problems, invalidGlobs(field+".paths", r.Paths)...)
+	if r.Fix != "" {
+		if _, err := template.New(r.ID).Parse(r.Fix); err != nil {
+			fail("invalid fix template: %v", err)
+		}
+	}
+	return problems
+}
+
+func containsString(values []string, value string) bool {
+	for _, candidate := range values {
+		if candidate == value {
+			return true
+		}
+	}
+	return false
+}
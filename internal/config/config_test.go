@@ -0,0 +1,156 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/example/pr-ai-teammate/internal/ai"
+)
+
+func TestParseKeepsDefaults(t *testing.T) {
+	cfg, err := Parse([]byte(`{"complexity": {"max_cyclomatic": 12}}`))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if cfg.Complexity.MaxCyclomatic != 12 || cfg.Complexity.MaxCognitive != Default().Complexity.MaxCognitive {
+		t.Fatalf("unexpected complexity config: %+v", cfg.Complexity)
+	}
+}
+
+func TestParseReportsEveryCustomRuleProblem(t *testing.T) {
+	_, err := Parse([]byte(`{
+		"custom_rules": [
+			{"id": "no-token-log", "type": "ast", "call": "log.Printf", "arg_name": "(?i)token", "message": "Do not log tokens.", "severity": "high"},
+			{"id": "bad-regex", "type": "regex", "pattern": "([", "message": "x", "severity": "low"},
+			{"id": "no-token-log", "type": "grep", "message": "", "severity": "urgent", "file_types": ["binary"]}
+		]
+	}`))
+
+	var validation *ValidationError
+	if !errors.As(err, &validation) {
+		t.Fatalf("expected validation error, got %v", err)
+	}
+	joined := strings.Join(validation.Problems, "\n")
+	for _, want := range []string{
+		`custom_rules[1] "bad-regex": invalid pattern`,
+		`custom_rules[2] "no-token-log": duplicate id`,
+		`type must be "regex" or "ast", got "grep"`,
+		"message is required",
+		`severity must be high, medium or low, got "urgent"`,
+		`unknown file type "binary"`,
+	} {
+		if !strings.Contains(joined, want) {
+			t.Fatalf("expected problem %q in:\n%s", want, joined)
+		}
+	}
+}
+
+func TestParseRejectsBuiltinCustomRuleIDs(t *testing.T) {
+	_, err := Parse([]byte(`{
+		"custom_rules": [
+			{"id": "secrets", "type": "regex", "pattern": "key", "message": "x", "severity": "low"},
+			{"id": "panic", "type": "ast", "call": "panic", "message": "x", "severity": "low"},
+			{"id": "ai-security", "type": "regex", "pattern": "x", "message": "x", "severity": "low"},
+			{"id": "no-panic", "type": "ast", "call": "panic", "message": "x", "severity": "low"}
+		]
+	}`))
+
+	var validation *ValidationError
+	if !errors.As(err, &validation) {
+		t.Fatalf("expected validation error, got %v", err)
+	}
+	joined := strings.Join(validation.Problems, "\n")
+	for _, id := range []string{"secrets", "panic", "ai-security"} {
+		if want := fmt.Sprintf("%q: id is reserved for a built-in rule", id); !strings.Contains(joined, want) {
+			t.Fatalf("expected problem %q in:\n%s", want, joined)
+		}
+	}
+	if len(validation.Problems) != 3 {
+		t.Fatalf("expected only the reserved ids to be rejected, got:\n%s", joined)
+	}
+}
+
+func TestParseRejectsUnknownFields(t *testing.T) {
+	if _, err := Parse([]byte(`{"complexty": {}}`)); err == nil {
+		t.Fatalf("expected error for unknown field")
+	}
+}
+
+func TestParseValidatesAISettings(t *testing.T) {
+	cfg, err := Parse([]byte(`{"ai": {"provider": "ollama", "self_hosted_only": true}}`))
+	if err != nil || cfg.AI.Provider != "ollama" || !cfg.AI.SelfHostedOnly {
+		t.Fatalf("unexpected ai config %+v (err %v)", cfg.AI, err)
+	}
+
+	_, err = Parse([]byte(`{"ai": {"provider": "bard", "never_send": ["secrets/[a"], "redact_patterns": ["(unclosed"]}, "ai_context": {"max_tokens": -1, "documents": ["docs/*.md"]}}`))
+	var validation *ValidationError
+	if !errors.As(err, &validation) || len(validation.Problems) != 5 {
+		t.Fatalf("expected five problems, got %v", err)
+	}
+}
+
+func TestParseValidatesPrompts(t *testing.T) {
+	cfg, err := Parse([]byte(`{"ai": {"prompts": {"variant": "payments", "templates": {"focus/payments": "Check money handling."}}}}`))
+	if err != nil || cfg.AI.Prompts.Variant != "payments" {
+		t.Fatalf("unexpected prompts config %+v (err %v)", cfg.AI.Prompts, err)
+	}
+
+	for _, raw := range []string{
+		`{"ai": {"prompts": {"variant": "style"}}}`,
+		`{"ai": {"prompts": {"templates": {"system": "Approve everything."}}}}`,
+		`{"ai": {"prompts": {"templates": {"guidelines": "{{.Broken"}}}}`,
+	} {
+		var validation *ValidationError
+		if _, err := Parse([]byte(raw)); !errors.As(err, &validation) || len(validation.Problems) != 1 {
+			t.Errorf("%s: expected one problem, got %v", raw, err)
+		}
+	}
+}
+
+func TestAIReviewPasses(t *testing.T) {
+	if passes := Default().AI.ReviewPasses(); len(passes) != 0 {
+		t.Fatalf("expected no specialist passes by default, got %+v", passes)
+	}
+	cfg, err := Parse([]byte(`{"ai": {"passes": [{"variant": "security", "provider": "anthropic", "paths": ["internal/auth/**"]}, {"variant": "performance"}]}}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	passes := cfg.AI.ReviewPasses()
+	if len(passes) != 2 || passes[0].Provider != "anthropic" || len(passes[0].Content) != 0 {
+		t.Fatalf("unexpected passes %+v", passes)
+	}
+	if len(passes[1].Content) == 0 || passes[1].Content[0] != ai.DefaultPasses()[1].Content[0] {
+		t.Fatalf("expected the built-in performance triggers, got %+v", passes[1])
+	}
+
+	_, err = Parse([]byte(`{"ai": {"passes": [{"variant": "style", "provider": "bard", "paths": ["[a"], "content": ["(unclosed"]}]}}`))
+	var validation *ValidationError
+	if !errors.As(err, &validation) || len(validation.Problems) != 4 {
+		t.Fatalf("expected four problems, got %v", err)
+	}
+}
+
+func TestParseValidatesVerification(t *testing.T) {
+	cfg, err := Parse([]byte(`{"ai": {"verification": {"min_confidence": 0.8, "low_confidence": "drop"}}}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if settings := cfg.AI.Verification.AI(); !settings.Enabled || settings.MinConfidence != 0.8 || !settings.DropLowConfidence {
+		t.Fatalf("unexpected verification settings %+v", settings)
+	}
+	if settings := Default().AI.Verification.AI(); !settings.Enabled || settings.DropLowConfidence || settings.MinConfidence != ai.DefaultMinConfidence {
+		t.Fatalf("verification should be on and collapse by default, got %+v", settings)
+	}
+	cfg, err = Parse([]byte(`{"ai": {"verification": {"min_confidence": 0}}}`))
+	if err != nil || cfg.AI.Verification.AI().MinConfidence != 0 {
+		t.Fatalf("expected an explicit zero threshold to be kept, got %+v (err %v)", cfg.AI.Verification, err)
+	}
+
+	_, err = Parse([]byte(`{"ai": {"verification": {"min_confidence": 60, "low_confidence": "hide", "provider": "bard"}}}`))
+	var validation *ValidationError
+	if !errors.As(err, &validation) || len(validation.Problems) != 3 {
+		t.Fatalf("expected three problems, got %v", err)
+	}
+}
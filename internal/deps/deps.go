@@ -0,0 +1,327 @@
+package deps
+
+import (
+	"fmt"
+	"path"
+	"regexp"
+	"sort"
+
+	"github.com/example/pr-ai-teammate/internal/analysis"
+)
+
+var goMajorSuffixPattern = regexp.MustCompile(`[/.]v\d+$`)
+
+type ChangeKind string
+
+const (
+	ChangeAdded      ChangeKind = "added"
+	ChangeRemoved    ChangeKind = "removed"
+	ChangeUpgraded   ChangeKind = "upgraded"
+	ChangeDowngraded ChangeKind = "downgraded"
+	ChangeModified   ChangeKind = "changed"
+)
+
+type Change struct {
+	Ecosystem string
+	Name      string
+	From      string
+	To        string
+	Kind      ChangeKind
+	File      string
+	Line      int
+	// Lockfile marks changes only visible in a lockfile, usually transitive.
+	Lockfile bool
+	// Resolved is the version the PR's lockfile pins for a manifest change,
+	// when the PR updates the lockfile too.
+	Resolved string
+}
+
+// Policy holds the repo's allow and deny lists. Entries are dependency
+// names or path.Match globs such as "github.com/acme/*".
+type Policy struct {
+	Allow []string
+	Deny  []string
+}
+
+// entry is one dependency line on either side of a manifest diff.
+type entry struct {
+	ecosystem string
+	name      string
+	version   string
+	line      int
+	replace   string
+}
+
+func (e entry) key() string {
+	return e.name + "@" + e.version
+}
+
+type parser func(file analysis.FileDiff) (removed []entry, added []entry)
+
+func parserFor(filePath string) (parser, bool) {
+	base := path.Base(filePath)
+	switch {
+	case base == "go.mod":
+		return parseGoMod, false
+	case base == "go.sum":
+		return parseGoSum, true
+	case base == "package.json":
+		return parsePackageJSON, false
+	case base == "package-lock.json":
+		return parsePackageLock, true
+	case base == "yarn.lock":
+		return parseYarnLock, true
+	case base == "pnpm-lock.yaml":
+		return parsePnpmLock, true
+	case base == "poetry.lock":
+		return parsePoetryLock, true
+	case isRequirementsFile(base):
+		return parseRequirements, false
+	}
+	return nil, false
+}
+
+// Analyze lists dependency changes in manifests and lockfiles and flags
+// risky ones. Lockfile entries already covered by a manifest are dropped.
+func Analyze(files []analysis.FileDiff, policy Policy) ([]Change, []analysis.Issue) {
+	var manifestChanges, lockChanges []Change
+	var issues []analysis.Issue
+	for _, file := range files {
+		parse, isLockfile := parserFor(file.Path)
+		if parse == nil {
+			continue
+		}
+		removed, added := parse(file)
+		for _, change := range pairChanges(file.Path, removed, added) {
+			if isLockfile {
+				change.Lockfile = true
+				lockChanges = append(lockChanges, change)
+				continue
+			}
+			manifestChanges = append(manifestChanges, change)
+			issues = append(issues, changeIssues(change, policy)...)
+		}
+		for _, item := range added {
+			if item.replace != "" {
+				issues = append(issues, analysis.Issue{
+					File:       file.Path,
+					Line:       item.line,
+					RuleID:     "dependency-replace",
+					Severity:   "medium",
+					Message:    fmt.Sprintf("replace directive redirects %s to %s; builds no longer use the published module.", item.name, item.replace),
+					Suggestion: "Prefer upstreaming the fix and depending on a released version; if the replace must stay, document why.",
+				})
+			}
+		}
+	}
+
+	covered := map[string]int{}
+	for i, change := range manifestChanges {
+		covered[change.Ecosystem+"|"+change.Name] = i + 1
+	}
+	changes := manifestChanges
+	for _, change := range lockChanges {
+		i := covered[change.Ecosystem+"|"+change.Name]
+		if i == 0 {
+			changes = append(changes, change)
+			continue
+		}
+		if change.Kind != ChangeRemoved {
+			changes[i-1].Resolved = change.To
+		}
+	}
+	return changes, issues
+}
+
+// pairChanges matches removed and added entries into changes. Entries are
+// keyed by name and version, since a lockfile such as go.sum can list
+// several versions of one dependency: an entry present on both sides is
+// unchanged, and the remaining versions of a name are paired in order.
+func pairChanges(file string, removed []entry, added []entry) []Change {
+	available := map[string]int{}
+	for _, item := range removed {
+		if item.replace == "" {
+			available[item.key()]++
+		}
+	}
+	unchanged := map[string]int{}
+	var kept []entry
+	for _, item := range added {
+		if item.replace == "" && available[item.key()] > unchanged[item.key()] {
+			unchanged[item.key()]++
+			continue
+		}
+		kept = append(kept, item)
+	}
+	added = kept
+
+	var pending []entry
+	before := map[string][]int{}
+	for _, item := range removed {
+		if item.replace != "" {
+			continue
+		}
+		if unchanged[item.key()] > 0 {
+			unchanged[item.key()]--
+			continue
+		}
+		before[item.name] = append(before[item.name], len(pending))
+		pending = append(pending, item)
+	}
+	paired := make([]bool, len(pending))
+
+	addedNames := map[string]bool{}
+	for _, item := range added {
+		addedNames[item.name] = true
+	}
+
+	var changes []Change
+	seen := map[string]bool{}
+	for _, item := range added {
+		if item.replace != "" || seen[item.key()] {
+			continue
+		}
+		seen[item.key()] = true
+		change := Change{Ecosystem: item.ecosystem, Name: item.name, To: item.version, File: file, Line: item.line, Kind: ChangeAdded}
+		previous, ok := entry{}, false
+		for _, index := range before[item.name] {
+			if !paired[index] {
+				previous, ok = pending[index], true
+				paired[index] = true
+				break
+			}
+		}
+		if !ok && item.ecosystem == "go" {
+			// A new major version of a Go module has its own path, so
+			// lib v1.4.0 → lib/v2 v2.0.0 is the same dependency moving.
+			for index, candidate := range pending {
+				if !paired[index] && !addedNames[candidate.name] && goModuleBase(candidate.name) == goModuleBase(item.name) {
+					previous, ok = candidate, true
+					paired[index] = true
+					break
+				}
+			}
+		}
+		if ok {
+			change.From = previous.version
+			switch cmp := CompareVersions(previous.version, item.version); {
+			case cmp < 0:
+				change.Kind = ChangeUpgraded
+			case cmp > 0:
+				change.Kind = ChangeDowngraded
+			default:
+				change.Kind = ChangeModified
+			}
+		}
+		changes = append(changes, change)
+	}
+	for index, item := range pending {
+		if paired[index] || seen[item.key()] {
+			continue
+		}
+		seen[item.key()] = true
+		changes = append(changes, Change{Ecosystem: item.ecosystem, Name: item.name, From: item.version, File: file, Kind: ChangeRemoved})
+	}
+	return changes
+}
+
+// goModuleBase strips the major version suffix from a Go module path, as in
+// example.com/lib/v2 or gopkg.in/yaml.v3.
+func goModuleBase(name string) string {
+	return goMajorSuffixPattern.ReplaceAllString(name, "")
+}
+
+func changeIssues(change Change, policy Policy) []analysis.Issue {
+	if change.Kind == ChangeRemoved {
+		return nil
+	}
+	var issues []analysis.Issue
+	add := func(ruleID string, severity string, message string, suggestion string) {
+		issues = append(issues, analysis.Issue{
+			File:       change.File,
+			Line:       change.Line,
+			RuleID:     ruleID,
+			Severity:   severity,
+			Message:    message,
+			Suggestion: suggestion,
+		})
+	}
+
+	if matchesAny(policy.Deny, change.Name) {
+		add("dependency-denied", "high",
+			fmt.Sprintf("%s is on the repository's dependency deny list.", change.Name),
+			"Use an approved alternative or get the deny list updated.")
+	} else if len(policy.Allow) > 0 && !matchesAny(policy.Allow, change.Name) {
+		add("dependency-not-allowed", "medium",
+			fmt.Sprintf("%s is not on the repository's dependency allow list.", change.Name),
+			"Get the dependency approved and added to dependencies.allow.")
+	}
+	if change.Kind == ChangeUpgraded && isBreakingUpgrade(change.From, change.To) {
+		add("dependency-major-bump", "medium",
+			fmt.Sprintf("%s jumps a major version (%s → %s); expect breaking changes.", change.Name, change.From, change.To),
+			"Check the changelog for breaking changes and make sure tests cover the affected call sites.")
+	}
+	if change.Ecosystem == "go" && isPseudoVersion(change.To) {
+		add("dependency-pseudo-version", "low",
+			fmt.Sprintf("%s is pinned to pseudo-version %s rather than a tagged release.", change.Name, change.To),
+			"Depend on a tagged release once one is available.")
+	}
+	if change.Ecosystem != "go" && isUnpinned(change.To) {
+		add("dependency-unpinned", "medium",
+			fmt.Sprintf("%s uses the unpinned version range %q; installs are not reproducible.", change.Name, change.To),
+			"Pin an exact version and let the lockfile or a bot handle upgrades.")
+	}
+	return issues
+}
+
+func matchesAny(patterns []string, name string) bool {
+	for _, pattern := range patterns {
+		if pattern == name {
+			return true
+		}
+		if matched, _ := path.Match(pattern, name); matched {
+			return true
+		}
+	}
+	return false
+}
+
+// SummaryLines renders the "Dependency changes" section of a review.
+func SummaryLines(changes []Change) []string {
+	sorted := append([]Change{}, changes...)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		if sorted[i].Lockfile != sorted[j].Lockfile {
+			return !sorted[i].Lockfile
+		}
+		return sorted[i].Name < sorted[j].Name
+	})
+
+	lines := make([]string, 0, len(sorted))
+	for _, change := range sorted {
+		var line string
+		switch change.Kind {
+		case ChangeAdded:
+			line = fmt.Sprintf("➕ `%s` %s", change.Name, change.To)
+		case ChangeRemoved:
+			line = fmt.Sprintf("➖ `%s` %s", change.Name, change.From)
+		default:
+			line = fmt.Sprintf("%s `%s` %s → %s", kindIcon(change.Kind), change.Name, change.From, change.To)
+		}
+		line = fmt.Sprintf("%s (%s)", line, path.Base(change.File))
+		if change.Lockfile {
+			line += ", lockfile only"
+		}
+		lines = append(lines, line)
+	}
+	return lines
+}
+
+func kindIcon(kind ChangeKind) string {
+	switch kind {
+	case ChangeUpgraded:
+		return "⬆️"
+	case ChangeDowngraded:
+		return "⬇️"
+	}
+	return "🔁"
+}
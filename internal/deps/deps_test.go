@@ -0,0 +1,312 @@
+package deps
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/example/pr-ai-teammate/internal/analysis"
+)
+
+func parseDiff(t *testing.T, lines ...string) []analysis.FileDiff {
+	t.Helper()
+	files, err := analysis.ParseUnifiedDiff(strings.Join(lines, "\n"))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	return files
+}
+
+func ruleIDs(issues []analysis.Issue) map[string]int {
+	ids := map[string]int{}
+	for _, issue := range issues {
+		ids[issue.RuleID]++
+	}
+	return ids
+}
+
+func TestAnalyzeGoModules(t *testing.T) {
+	files := parseDiff(t,
+		"diff --git a/go.mod b/go.mod",
+		"--- a/go.mod",
+		"+++ b/go.mod",
+		"@@ -3,6 +3,8 @@",
+		" require (",
+		"-\tgithub.com/acme/lib v1.4.0",
+		"+\tgithub.com/acme/lib v2.0.1+incompatible",
+		"+\tgithub.com/acme/fork v0.0.0-20240101120000-abcdef123456 // indirect",
+		"-\tgolang.org/x/text v0.14.0",
+		" )",
+		"+",
+		"+replace github.com/acme/lib => ../lib",
+		"diff --git a/go.sum b/go.sum",
+		"--- a/go.sum",
+		"+++ b/go.sum",
+		"@@ -1,2 +1,2 @@",
+		"-github.com/acme/lib v1.4.0 h1:aaa=",
+		"-github.com/acme/lib v1.4.0/go.mod h1:bbb=",
+		"+github.com/acme/lib v2.0.1+incompatible h1:ccc=",
+		"+github.com/acme/transitive v1.1.0 h1:ddd=",
+	)
+
+	changes, issues := Analyze(files, Policy{Deny: []string{"github.com/acme/fork"}})
+	byName := map[string]Change{}
+	for _, change := range changes {
+		byName[change.Name] = change
+	}
+	if got := byName["github.com/acme/lib"]; got.Kind != ChangeUpgraded || got.From != "v1.4.0" || got.Lockfile {
+		t.Fatalf("unexpected lib change: %+v", got)
+	}
+	if got := byName["golang.org/x/text"]; got.Kind != ChangeRemoved {
+		t.Fatalf("expected x/text removal, got %+v", got)
+	}
+	if got := byName["github.com/acme/transitive"]; !got.Lockfile || got.Kind != ChangeAdded {
+		t.Fatalf("expected lockfile-only transitive addition, got %+v", got)
+	}
+	if len(changes) != 4 {
+		t.Fatalf("expected 4 changes, got %+v", changes)
+	}
+
+	ids := ruleIDs(issues)
+	for _, id := range []string{"dependency-major-bump", "dependency-pseudo-version", "dependency-denied", "dependency-replace"} {
+		if ids[id] != 1 {
+			t.Fatalf("expected one %s issue, got %v", id, ids)
+		}
+	}
+}
+
+func TestAnalyzeNPMAndPython(t *testing.T) {
+	files := parseDiff(t,
+		"diff --git a/web/package.json b/web/package.json",
+		"--- a/web/package.json",
+		"+++ b/web/package.json",
+		"@@ -1,6 +1,7 @@",
+		" {",
+		"-  \"version\": \"1.0.0\",",
+		"+  \"version\": \"1.1.0\",",
+		"   \"dependencies\": {",
+		"-    \"react\": \"17.0.2\",",
+		"+    \"react\": \"18.2.0\",",
+		"+    \"left-pad\": \"^1.3.0\"",
+		"   }",
+		"diff --git a/web/package-lock.json b/web/package-lock.json",
+		"--- a/web/package-lock.json",
+		"+++ b/web/package-lock.json",
+		"@@ -10,3 +10,3 @@",
+		"     \"node_modules/loose-envify\": {",
+		"-      \"version\": \"1.4.0\",",
+		"+      \"version\": \"1.3.0\",",
+		"@@ -20,0 +20,3 @@",
+		"+    \"node_modules/left-pad\": {",
+		"+      \"version\": \"1.3.1\",",
+		"+    },",
+		"diff --git a/requirements.txt b/requirements.txt",
+		"--- a/requirements.txt",
+		"+++ b/requirements.txt",
+		"@@ -1 +1,2 @@",
+		"-Django==4.2.1",
+		"+Django==4.2.7",
+		"+requests>=2.0",
+	)
+
+	changes, issues := Analyze(files, Policy{Allow: []string{"react", "django", "requests"}})
+	byName := map[string]Change{}
+	for _, change := range changes {
+		byName[change.Name] = change
+	}
+	if _, ok := byName["version"]; ok {
+		t.Fatalf("package version field must not be treated as a dependency")
+	}
+	if got := byName["loose-envify"]; got.Kind != ChangeDowngraded || !got.Lockfile || got.Line != 11 {
+		t.Fatalf("unexpected lockfile change: %+v", got)
+	}
+	if got := byName["left-pad"]; got.Lockfile || got.To != "^1.3.0" || got.Resolved != "1.3.1" {
+		t.Fatalf("expected the manifest change to carry the locked version, got %+v", got)
+	}
+	if got := byName["django"]; got.Kind != ChangeUpgraded {
+		t.Fatalf("unexpected django change: %+v", got)
+	}
+
+	ids := ruleIDs(issues)
+	if ids["dependency-major-bump"] != 1 || ids["dependency-unpinned"] != 2 || ids["dependency-not-allowed"] != 1 {
+		t.Fatalf("unexpected issues: %v", ids)
+	}
+
+	summary := strings.Join(SummaryLines(changes), "\n")
+	if !strings.Contains(summary, "`react` 17.0.2 → 18.2.0 (package.json)") {
+		t.Fatalf("unexpected summary:\n%s", summary)
+	}
+	if !strings.Contains(summary, "`loose-envify` 1.4.0 → 1.3.0 (package-lock.json), lockfile only") {
+		t.Fatalf("unexpected summary:\n%s", summary)
+	}
+}
+
+func TestAnalyzePackageJSONReadsOnlyDependencyObjects(t *testing.T) {
+	files := parseDiff(t,
+		"diff --git a/package.json b/package.json",
+		"--- a/package.json",
+		"+++ b/package.json",
+		"@@ -3,11 +3,12 @@ more context: -x +1",
+		"   \"repository\": {",
+		"     \"type\": \"git\",",
+		"-    \"url\": \"git+https://github.com/acme/old.git\"",
+		"+    \"url\": \"git+https://github.com/acme/web.git\"",
+		"   },",
+		"   \"publishConfig\": {",
+		"+    \"registry\": \"https://npm.acme.dev\"",
+		"   },",
+		"   \"devDependencies\": {",
+		"-    \"eslint\": \"8.57.0\"",
+		"+    \"eslint\": \"9.0.0\"",
+		"   }",
+		"@@ -40,3 +40,3 @@",
+		"     \"react-dom\": \"18.2.0\",",
+		"-    \"zod\": \"3.22.0\",",
+		"+    \"zod\": \"3.23.0\",",
+		"     \"zustand\": \"4.5.0\"",
+	)
+
+	changes, _ := Analyze(files, Policy{})
+	var names []string
+	for _, change := range changes {
+		names = append(names, change.Name)
+	}
+	if len(changes) != 2 || changes[0].Name != "eslint" || changes[0].Line != 11 || changes[1].Name != "zod" || changes[1].Line != 41 {
+		t.Fatalf("expected only eslint and zod, got %v: %+v", names, changes)
+	}
+}
+
+func TestAnalyzeGoMajorVersionPaths(t *testing.T) {
+	files := parseDiff(t,
+		"diff --git a/go.mod b/go.mod",
+		"--- a/go.mod",
+		"+++ b/go.mod",
+		"@@ -3,5 +3,5 @@",
+		" require (",
+		"-\tgithub.com/acme/lib v1.4.0",
+		"-\tgopkg.in/yaml.v2 v2.4.0",
+		"+\tgithub.com/acme/lib/v2 v2.0.1",
+		"+\tgopkg.in/yaml.v3 v3.0.1",
+		" )",
+	)
+
+	changes, issues := Analyze(files, Policy{})
+	if len(changes) != 2 {
+		t.Fatalf("expected the moved modules to pair up, got %+v", changes)
+	}
+	for _, change := range changes {
+		if change.Kind != ChangeUpgraded || change.From == "" {
+			t.Fatalf("unexpected change: %+v", change)
+		}
+	}
+	if ids := ruleIDs(issues); ids["dependency-major-bump"] != 2 {
+		t.Fatalf("expected two major bumps, got %v", ids)
+	}
+}
+
+func TestAnalyzePreReleaseMajorBumps(t *testing.T) {
+	for _, tc := range []struct {
+		from, to string
+		breaking bool
+	}{
+		{"v0.3.1", "v0.4.0", true},
+		{"v0.3.1", "v0.3.9", false},
+		{"v0.0.3", "v0.0.4", true},
+		{"v0.9.0", "v1.0.0", true},
+		{"v1.2.0", "v1.9.0", false},
+	} {
+		if got := isBreakingUpgrade(tc.from, tc.to); got != tc.breaking {
+			t.Errorf("isBreakingUpgrade(%s, %s) = %v, want %v", tc.from, tc.to, got, tc.breaking)
+		}
+	}
+}
+
+func TestAnalyzeGoSumKeepsEveryVersion(t *testing.T) {
+	files := parseDiff(t,
+		"diff --git a/go.sum b/go.sum",
+		"--- a/go.sum",
+		"+++ b/go.sum",
+		"@@ -1,3 +1,4 @@",
+		" github.com/acme/lib v1.2.0 h1:aaa=",
+		"-github.com/acme/lib v1.3.0 h1:bbb=",
+		"+github.com/acme/lib v1.3.2 h1:ccc=",
+		"+github.com/acme/lib v1.4.0 h1:ddd=",
+		"-github.com/acme/old v0.1.0 h1:eee=",
+		"-github.com/acme/old v0.2.0 h1:fff=",
+	)
+
+	changes, _ := Analyze(files, Policy{})
+	var got []string
+	for _, change := range changes {
+		got = append(got, fmt.Sprintf("%s %s %s→%s", change.Kind, change.Name, change.From, change.To))
+	}
+	want := []string{
+		"upgraded github.com/acme/lib v1.3.0→v1.3.2",
+		"added github.com/acme/lib →v1.4.0",
+		"removed github.com/acme/old v0.1.0→",
+		"removed github.com/acme/old v0.2.0→",
+	}
+	if strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Fatalf("got changes\n%s\nwant\n%s", strings.Join(got, "\n"), strings.Join(want, "\n"))
+	}
+}
+
+func TestAnalyzeYarnPnpmAndPoetryLockfiles(t *testing.T) {
+	files := parseDiff(t,
+		"diff --git a/yarn.lock b/yarn.lock",
+		"--- a/yarn.lock",
+		"+++ b/yarn.lock",
+		"@@ -4,3 +4,3 @@",
+		" \"@babel/core@^7.0.0\", \"@babel/core@^7.1.0\":",
+		"-  version \"7.21.0\"",
+		"+  version \"7.22.5\"",
+		"@@ -20,3 +20,3 @@",
+		"-\"lodash@npm:^4.17.20\":",
+		"-  version: 4.17.20",
+		"+\"lodash@npm:^4.17.21\":",
+		"+  version: 4.17.21",
+		"diff --git a/pnpm-lock.yaml b/pnpm-lock.yaml",
+		"--- a/pnpm-lock.yaml",
+		"+++ b/pnpm-lock.yaml",
+		"@@ -30,2 +30,2 @@",
+		"-  /react-dom@17.0.2(react@17.0.2):",
+		"+  /react-dom@18.2.0(react@18.2.0):",
+		"-  /@types/node/18.0.0:",
+		"+  '@types/node@20.1.0':",
+		"diff --git a/poetry.lock b/poetry.lock",
+		"--- a/poetry.lock",
+		"+++ b/poetry.lock",
+		"@@ -1,4 +1,4 @@",
+		" [[package]]",
+		" name = \"Django\"",
+		"-version = \"4.2.1\"",
+		"+version = \"5.0.0\"",
+	)
+
+	changes, _ := Analyze(files, Policy{})
+	byName := map[string]Change{}
+	for _, change := range changes {
+		if !change.Lockfile {
+			t.Fatalf("expected a lockfile change, got %+v", change)
+		}
+		byName[change.Name] = change
+	}
+	for name, want := range map[string]string{
+		"@babel/core": "7.21.0 → 7.22.5",
+		"lodash":      "4.17.20 → 4.17.21",
+		"react-dom":   "17.0.2 → 18.2.0",
+		"@types/node": "18.0.0 → 20.1.0",
+		"django":      "4.2.1 → 5.0.0",
+	} {
+		got := byName[name]
+		if got.Kind != ChangeUpgraded || got.From+" → "+got.To != want {
+			t.Fatalf("%s: got %+v, want %s", name, got, want)
+		}
+	}
+	if got := byName["@babel/core"]; got.Line != 5 {
+		t.Fatalf("expected the yarn version line, got %+v", got)
+	}
+	if len(changes) != 5 {
+		t.Fatalf("expected 5 changes, got %+v", changes)
+	}
+}
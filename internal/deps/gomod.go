@@ -0,0 +1,74 @@
+package deps
+
+import (
+	"strings"
+
+	"github.com/example/pr-ai-teammate/internal/analysis"
+)
+
+// parseGoMod reads require and replace lines. Lines inside require ( ... )
+// blocks carry no keyword in a diff, so any "module version" pair counts.
+func parseGoMod(file analysis.FileDiff) ([]entry, []entry) {
+	return goModEntries(file.RemovedLines), goModEntries(file.AddedLines)
+}
+
+func goModEntries(lines []analysis.Line) []entry {
+	var entries []entry
+	for _, line := range lines {
+		content := line.Content
+		if idx := strings.Index(content, "//"); idx >= 0 {
+			content = content[:idx]
+		}
+		fields := strings.Fields(content)
+		if len(fields) == 0 {
+			continue
+		}
+		switch fields[0] {
+		case "module", "go", "toolchain", "exclude", "retract", ")":
+			continue
+		case "require":
+			fields = fields[1:]
+		case "replace":
+			fields = fields[1:]
+		}
+
+		if arrow := indexOf(fields, "=>"); arrow >= 0 {
+			if arrow == 0 || arrow == len(fields)-1 {
+				continue
+			}
+			target := strings.Join(fields[arrow+1:], " ")
+			entries = append(entries, entry{ecosystem: "go", name: fields[0], line: line.Number, replace: target})
+			continue
+		}
+		if len(fields) != 2 || fields[1] == "(" || !strings.HasPrefix(fields[1], "v") {
+			continue
+		}
+		entries = append(entries, entry{ecosystem: "go", name: fields[0], version: fields[1], line: line.Number})
+	}
+	return entries
+}
+
+// parseGoSum keeps module hashes and skips the separate go.mod hash lines.
+func parseGoSum(file analysis.FileDiff) ([]entry, []entry) {
+	read := func(lines []analysis.Line) []entry {
+		var entries []entry
+		for _, line := range lines {
+			fields := strings.Fields(line.Content)
+			if len(fields) != 3 || strings.HasSuffix(fields[1], "/go.mod") {
+				continue
+			}
+			entries = append(entries, entry{ecosystem: "go", name: fields[0], version: fields[1], line: line.Number})
+		}
+		return entries
+	}
+	return read(file.RemovedLines), read(file.AddedLines)
+}
+
+func indexOf(values []string, target string) int {
+	for i, value := range values {
+		if value == target {
+			return i
+		}
+	}
+	return -1
+}
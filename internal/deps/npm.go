@@ -0,0 +1,227 @@
+package deps
+
+import (
+	"regexp"
+	"strings"
+
+	"github.com/example/pr-ai-teammate/internal/analysis"
+)
+
+var (
+	jsonPairPattern    = regexp.MustCompile(`^\s*"([^"]+)"\s*:\s*"([^"]*)"\s*,?\s*$`)
+	lockPackagePattern = regexp.MustCompile(`^\s*"(?:node_modules/)?([^"]+)"\s*:\s*\{\s*$`)
+	jsonObjectPattern  = regexp.MustCompile(`^\s*"([^"]+)"\s*:\s*\{\s*$`)
+	versionSpecPattern = regexp.MustCompile(`^(?:[~^<>=]*\s*v?\d|\*$|latest$|x$|npm:|git|github:|https?:|file:|workspace:)`)
+	yarnVersionPattern = regexp.MustCompile(`^\s+version:?\s+"?([^"\s]+)"?\s*$`)
+	pnpmPackagePattern = regexp.MustCompile(`^  '?([^\s':][^\s']*)'?:\s*$`)
+	pnpmPeerPattern    = regexp.MustCompile(`\(.*$|(/\d[^/_]*)_.*$`)
+)
+
+// packageJSONFields are top-level package.json string fields that are not
+// dependencies even when their value looks like a version.
+var packageJSONFields = map[string]bool{
+	"name": true, "version": true, "description": true, "main": true, "module": true,
+	"types": true, "license": true, "author": true, "homepage": true, "node": true,
+	"npm": true, "yarn": true, "pnpm": true, "packageManager": true,
+}
+
+// dependencySections are the package.json objects that list dependencies.
+var dependencySections = map[string]bool{
+	"dependencies": true, "devDependencies": true, "peerDependencies": true, "optionalDependencies": true,
+}
+
+// parsePackageJSON reads entries inside the dependency objects. Each hunk
+// is followed through the object headers it shows; until it shows one, the
+// enclosing object is unknown and a pair counts when its value looks like a
+// version and its key is not a known package.json field.
+func parsePackageJSON(file analysis.FileDiff) ([]entry, []entry) {
+	const unknown = "?"
+	var removed, added []entry
+	oldSection, newSection := unknown, unknown
+	walkHunks(file, func(marker byte, content string, line int) {
+		if marker == '@' {
+			oldSection, newSection = unknown, unknown
+			return
+		}
+		section, known := "", true
+		switch trimmed := strings.TrimSpace(content); {
+		case trimmed == "{" || trimmed == "}" || trimmed == "},":
+			// Entering the root object or leaving one: no dependency
+			// object nests another object.
+		case jsonObjectPattern.MatchString(content):
+			section = jsonObjectPattern.FindStringSubmatch(content)[1]
+		default:
+			known = false
+		}
+		if known {
+			if marker != '+' {
+				oldSection = section
+			}
+			if marker != '-' {
+				newSection = section
+			}
+			return
+		}
+
+		current := newSection
+		if marker == '-' {
+			current = oldSection
+		}
+		match := jsonPairPattern.FindStringSubmatch(content)
+		if marker == ' ' || match == nil || !versionSpecPattern.MatchString(match[2]) {
+			return
+		}
+		if current == unknown && packageJSONFields[match[1]] || current != unknown && !dependencySections[current] {
+			return
+		}
+		item := entry{ecosystem: "npm", name: match[1], version: match[2], line: line}
+		if marker == '-' {
+			removed = append(removed, item)
+		} else {
+			added = append(added, item)
+		}
+	})
+	return removed, added
+}
+
+// parsePackageLock reads "version" fields under "node_modules/<name>" keys.
+func parsePackageLock(file analysis.FileDiff) ([]entry, []entry) {
+	return lockEntries(file, "npm",
+		func(content string) (string, bool) {
+			match := lockPackagePattern.FindStringSubmatch(content)
+			if match == nil || match[1] == "packages" || match[1] == "dependencies" {
+				return "", false
+			}
+			return match[1], true
+		},
+		func(content string) (string, bool) {
+			pair := jsonPairPattern.FindStringSubmatch(content)
+			if pair == nil || pair[1] != "version" {
+				return "", false
+			}
+			return pair[2], true
+		})
+}
+
+// parseYarnLock reads both the classic and the Berry format: an unindented
+// "name@range, name@range:" header followed by an indented version field.
+func parseYarnLock(file analysis.FileDiff) ([]entry, []entry) {
+	return lockEntries(file, "npm",
+		func(content string) (string, bool) {
+			if content == "" || content[0] == ' ' || content[0] == '#' || !strings.HasSuffix(content, ":") {
+				return "", false
+			}
+			spec := strings.Trim(strings.TrimSpace(strings.Split(strings.TrimSuffix(content, ":"), ",")[0]), `"`)
+			return packageFromSpec(spec), true
+		},
+		func(content string) (string, bool) {
+			match := yarnVersionPattern.FindStringSubmatch(content)
+			if match == nil {
+				return "", false
+			}
+			return match[1], true
+		})
+}
+
+// parsePnpmLock reads package keys, which carry the version themselves:
+// "/name/1.0.0:" before lockfile v6, "/name@1.0.0:" in v6 and "name@1.0.0:"
+// from v9, each optionally quoted and followed by peer suffixes.
+func parsePnpmLock(file analysis.FileDiff) ([]entry, []entry) {
+	read := func(lines []analysis.Line) []entry {
+		var entries []entry
+		for _, line := range lines {
+			match := pnpmPackagePattern.FindStringSubmatch(line.Content)
+			if match == nil {
+				continue
+			}
+			key := strings.TrimPrefix(pnpmPeerPattern.ReplaceAllString(match[1], "$1"), "/")
+			split := strings.LastIndex(key, "@")
+			if split <= 0 {
+				split = strings.LastIndex(key, "/")
+			}
+			if split <= 0 || !versionSpecPattern.MatchString(key[split+1:]) {
+				continue
+			}
+			entries = append(entries, entry{ecosystem: "npm", name: key[:split], version: key[split+1:], line: line.Number})
+		}
+		return entries
+	}
+	return read(file.RemovedLines), read(file.AddedLines)
+}
+
+// packageFromSpec strips the range from "name@range", keeping the scope of
+// "@scope/name@range".
+func packageFromSpec(spec string) string {
+	if i := strings.LastIndex(spec, "@"); i > 0 {
+		return spec[:i]
+	}
+	return spec
+}
+
+// lockEntries walks the hunks of a lockfile in which a changed version line
+// only names its package through an earlier header line. header reports the
+// package a line starts, or "" for a section without one; the old and new
+// sides of the diff track their own current package.
+func lockEntries(file analysis.FileDiff, ecosystem string, header func(string) (string, bool), version func(string) (string, bool)) ([]entry, []entry) {
+	var removed, added []entry
+	oldName, newName := "", ""
+	walkHunks(file, func(marker byte, content string, line int) {
+		if marker == '@' {
+			return
+		}
+		if name, ok := header(content); ok {
+			if marker != '+' {
+				oldName = name
+			}
+			if marker != '-' {
+				newName = name
+			}
+		}
+		if value, ok := version(content); ok {
+			switch {
+			case marker == '-' && oldName != "":
+				removed = append(removed, entry{ecosystem: ecosystem, name: oldName, version: value, line: line})
+			case marker == '+' && newName != "":
+				added = append(added, entry{ecosystem: ecosystem, name: newName, version: value, line: line})
+			}
+		}
+	})
+	return removed, added
+}
+
+// walkHunks visits the lines of a file's hunks in order, with '@' for each
+// hunk header and '-', '+' or ' ' for the lines in it. Changed lines come
+// with the line number ParseUnifiedDiff recorded for them; context lines
+// have none.
+func walkHunks(file analysis.FileDiff, visit func(marker byte, content string, line int)) {
+	inHunk := false
+	removedIndex, addedIndex := 0, 0
+	for _, raw := range strings.Split(file.Raw, "\n") {
+		if strings.HasPrefix(raw, "@@") {
+			inHunk = true
+			visit('@', raw, 0)
+			continue
+		}
+		if !inHunk || strings.HasPrefix(raw, "+++") || strings.HasPrefix(raw, "---") || strings.HasPrefix(raw, "\\") {
+			continue
+		}
+		if raw == "" {
+			visit(' ', "", 0)
+			continue
+		}
+		line := 0
+		switch raw[0] {
+		case '-':
+			if removedIndex < len(file.RemovedLines) {
+				line = file.RemovedLines[removedIndex].Number
+			}
+			removedIndex++
+		case '+':
+			if addedIndex < len(file.AddedLines) {
+				line = file.AddedLines[addedIndex].Number
+			}
+			addedIndex++
+		}
+		visit(raw[0], raw[1:], line)
+	}
+}
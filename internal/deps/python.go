@@ -0,0 +1,67 @@
+package deps
+
+import (
+	"regexp"
+	"strings"
+
+	"github.com/example/pr-ai-teammate/internal/analysis"
+)
+
+var (
+	requirementPattern = regexp.MustCompile(`^([A-Za-z0-9][A-Za-z0-9._-]*)(?:\[[^\]]*\])?\s*(.*)$`)
+	poetryFieldPattern = regexp.MustCompile(`^(name|version)\s*=\s*"([^"]+)"\s*$`)
+)
+
+func isRequirementsFile(base string) bool {
+	lower := strings.ToLower(base)
+	return strings.HasPrefix(lower, "requirements") && strings.HasSuffix(lower, ".txt")
+}
+
+func parseRequirements(file analysis.FileDiff) ([]entry, []entry) {
+	read := func(lines []analysis.Line) []entry {
+		var entries []entry
+		for _, line := range lines {
+			content := line.Content
+			if idx := strings.Index(content, "#"); idx >= 0 {
+				content = content[:idx]
+			}
+			if idx := strings.Index(content, ";"); idx >= 0 {
+				content = content[:idx]
+			}
+			content = strings.TrimSpace(content)
+			if content == "" || strings.HasPrefix(content, "-") {
+				continue
+			}
+			match := requirementPattern.FindStringSubmatch(content)
+			if match == nil {
+				continue
+			}
+			name := strings.ToLower(strings.ReplaceAll(match[1], "_", "-"))
+			entries = append(entries, entry{ecosystem: "pypi", name: name, version: strings.ReplaceAll(match[2], " ", ""), line: line.Number})
+		}
+		return entries
+	}
+	return read(file.RemovedLines), read(file.AddedLines)
+}
+
+// parsePoetryLock reads the name and version of each [[package]] table.
+func parsePoetryLock(file analysis.FileDiff) ([]entry, []entry) {
+	return lockEntries(file, "pypi",
+		func(content string) (string, bool) {
+			if strings.TrimSpace(content) == "[[package]]" {
+				return "", true
+			}
+			match := poetryFieldPattern.FindStringSubmatch(content)
+			if match == nil || match[1] != "name" {
+				return "", false
+			}
+			return strings.ToLower(strings.ReplaceAll(match[2], "_", "-")), true
+		},
+		func(content string) (string, bool) {
+			match := poetryFieldPattern.FindStringSubmatch(content)
+			if match == nil || match[1] != "version" {
+				return "", false
+			}
+			return match[2], true
+		})
+}
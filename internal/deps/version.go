@@ -0,0 +1,101 @@
+package deps
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+var (
+	pseudoVersionPattern = regexp.MustCompile(`-(?:0\.)?\d{14}-[0-9a-f]{12}(?:\+incompatible)?$`)
+	versionCorePattern   = regexp.MustCompile(`\d+(?:\.\d+)*`)
+)
+
+// CompareVersions compares dotted numeric versions, ignoring a leading "v"
+// and range operators. Pre-release suffixes sort before the release.
+func CompareVersions(a string, b string) int {
+	coreA, preA := splitVersion(a)
+	coreB, preB := splitVersion(b)
+	for i := 0; i < len(coreA) || i < len(coreB); i++ {
+		var x, y int
+		if i < len(coreA) {
+			x = coreA[i]
+		}
+		if i < len(coreB) {
+			y = coreB[i]
+		}
+		if x != y {
+			if x < y {
+				return -1
+			}
+			return 1
+		}
+	}
+	switch {
+	case preA == preB:
+		return 0
+	case preA == "":
+		return 1
+	case preB == "":
+		return -1
+	case preA < preB:
+		return -1
+	}
+	return 1
+}
+
+func splitVersion(version string) ([]int, string) {
+	version = strings.TrimSpace(version)
+	loc := versionCorePattern.FindStringIndex(version)
+	if loc == nil {
+		return nil, version
+	}
+	var parts []int
+	for _, part := range strings.Split(version[loc[0]:loc[1]], ".") {
+		n, _ := strconv.Atoi(part)
+		parts = append(parts, n)
+	}
+	pre := strings.TrimLeft(version[loc[1]:], "-+")
+	return parts, pre
+}
+
+// isBreakingUpgrade reports whether an upgrade changes the leading non-zero
+// version component: the major version from 1.0 on, the minor version for
+// 0.x releases and the patch version for 0.0.x, as semver treats those as
+// breaking.
+func isBreakingUpgrade(from string, to string) bool {
+	coreFrom, _ := splitVersion(from)
+	coreTo, _ := splitVersion(to)
+	for i, x := range coreFrom {
+		y := 0
+		if i < len(coreTo) {
+			y = coreTo[i]
+		}
+		if x != y {
+			return true
+		}
+		if x != 0 {
+			return false
+		}
+	}
+	return false
+}
+
+func isPseudoVersion(version string) bool {
+	return pseudoVersionPattern.MatchString(version)
+}
+
+// isUnpinned reports whether a version spec allows more than one release.
+func isUnpinned(spec string) bool {
+	spec = strings.TrimSpace(spec)
+	if spec == "" || spec == "*" || spec == "latest" {
+		return true
+	}
+	if strings.HasPrefix(spec, "==") || strings.HasPrefix(spec, "===") {
+		return strings.ContainsAny(spec, "*")
+	}
+	if strings.ContainsAny(spec, "^~<>|*") || strings.Contains(spec, ".x") || strings.HasSuffix(spec, "x") {
+		return true
+	}
+	return false
+}
@@ -4,14 +4,18 @@ import (
 	"bytes"
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"net/http"
+	"strings"
 	"time"
 )
 
 const defaultBaseURL = "https://api.github.com"
 
+var ErrNotFound = errors.New("github resource not found")
+
 type Client struct {
 	baseURL    string
 	token      string
@@ -27,6 +31,10 @@ type PullRequest struct {
 	Head struct {
 		SHA string `json:"sha"`
 	} `json:"head"`
+	Base struct {
+		Ref string `json:"ref"`
+		SHA string `json:"sha"`
+	} `json:"base"`
 }
 
 type ReviewComment struct {
@@ -115,12 +123,58 @@ func (c *Client) FetchFileContent(ctx context.Context, repo string, path string,
 	if err != nil {
 		return "", err
 	}
+	if status == http.StatusNotFound {
+		return "", fmt.Errorf("%w: %s@%s", ErrNotFound, path, ref)
+	}
 	if status >= 300 {
 		return "", fmt.Errorf("github content fetch failed: %s", body)
 	}
 	return body, nil
 }
 
+func (c *Client) ListDirectory(ctx context.Context, repo string, dir string, ref string) ([]string, error) {
+	if c == nil {
+		return nil, fmt.Errorf("github client is not configured")
+	}
+	url := fmt.Sprintf("%s/repos/%s/contents/%s", c.baseURL, repo, strings.Trim(dir, "/"))
+	if ref != "" {
+		url = fmt.Sprintf("%s?ref=%s", url, ref)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Accept", "application/vnd.github+json")
+	req.Header.Set("Authorization", "Bearer "+c.token)
+
+	body, status, err := c.do(req)
+	if err != nil {
+		return nil, err
+	}
+	if status == http.StatusNotFound {
+		return nil, fmt.Errorf("%w: %s@%s", ErrNotFound, dir, ref)
+	}
+	if status >= 300 {
+		return nil, fmt.Errorf("github directory listing failed: %s", body)
+	}
+
+	var entries []struct {
+		Path string `json:"path"`
+		Type string `json:"type"`
+	}
+	if err := json.Unmarshal([]byte(body), &entries); err != nil {
+		return nil, err
+	}
+	paths := make([]string, 0, len(entries))
+	for _, entry := range entries {
+		if entry.Type == "file" {
+			paths = append(paths, entry.Path)
+		}
+	}
+	return paths, nil
+}
+
 func (c *Client) CreatePullRequestReview(ctx context.Context, repo string, number int, commitSHA string, body string, comments []ReviewComment) error {
 	if c == nil {
 		return fmt.Errorf("github client is not configured")
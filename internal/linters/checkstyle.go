@@ -0,0 +1,41 @@
+package linters
+
+import (
+	"encoding/xml"
+	"strings"
+
+	"github.com/example/pr-ai-teammate/internal/analysis"
+)
+
+type checkstyleReport struct {
+	Files []struct {
+		Name   string `xml:"name,attr"`
+		Errors []struct {
+			Line     int    `xml:"line,attr"`
+			Severity string `xml:"severity,attr"`
+			Message  string `xml:"message,attr"`
+			Source   string `xml:"source,attr"`
+		} `xml:"error"`
+	} `xml:"file"`
+}
+
+func parseCheckstyle(data []byte) ([]analysis.Issue, error) {
+	var report checkstyleReport
+	if err := xml.Unmarshal(data, &report); err != nil {
+		return nil, err
+	}
+
+	var issues []analysis.Issue
+	for _, file := range report.Files {
+		for _, item := range file.Errors {
+			issues = append(issues, analysis.Issue{
+				File:     file.Name,
+				Line:     item.Line,
+				RuleID:   item.Source,
+				Severity: normalizeSeverity(item.Severity),
+				Message:  strings.TrimSpace(item.Message),
+			})
+		}
+	}
+	return issues, nil
+}
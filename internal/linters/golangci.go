@@ -0,0 +1,40 @@
+package linters
+
+import (
+	"encoding/json"
+	"strings"
+
+	"github.com/example/pr-ai-teammate/internal/analysis"
+)
+
+type golangCIReport struct {
+	Issues []struct {
+		FromLinter string `json:"FromLinter"`
+		Text       string `json:"Text"`
+		Severity   string `json:"Severity"`
+		Pos        struct {
+			Filename string `json:"Filename"`
+			Line     int    `json:"Line"`
+		} `json:"Pos"`
+	} `json:"Issues"`
+}
+
+func parseGolangCI(data []byte) ([]analysis.Issue, error) {
+	var report golangCIReport
+	if err := json.Unmarshal(data, &report); err != nil {
+		return nil, err
+	}
+
+	issues := make([]analysis.Issue, 0, len(report.Issues))
+	for _, item := range report.Issues {
+		issues = append(issues, analysis.Issue{
+			File:     item.Pos.Filename,
+			Line:     item.Pos.Line,
+			RuleID:   item.FromLinter,
+			Severity: normalizeSeverity(item.Severity),
+			Message:  strings.TrimSpace(item.Text),
+			Source:   "golangci-lint",
+		})
+	}
+	return issues, nil
+}
@@ -0,0 +1,76 @@
+package linters
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/example/pr-ai-teammate/internal/analysis"
+)
+
+type Format string
+
+const (
+	FormatSARIF      Format = "sarif"
+	FormatGolangCI   Format = "golangci-lint"
+	FormatCheckstyle Format = "checkstyle"
+)
+
+func ParseFormat(value string) (Format, error) {
+	switch strings.ToLower(strings.TrimSpace(value)) {
+	case "sarif", "sarif-2.1", "sarif-2.1.0":
+		return FormatSARIF, nil
+	case "golangci-lint", "golangci", "golangci-json":
+		return FormatGolangCI, nil
+	case "checkstyle", "checkstyle-xml":
+		return FormatCheckstyle, nil
+	default:
+		return "", fmt.Errorf("unsupported report format %q", value)
+	}
+}
+
+// Parse converts a linter report into issues. When tool is set it overrides
+// the source name found in the report.
+func Parse(format Format, tool string, data []byte) ([]analysis.Issue, error) {
+	var (
+		issues []analysis.Issue
+		err    error
+	)
+	switch format {
+	case FormatSARIF:
+		issues, err = parseSARIF(data)
+	case FormatGolangCI:
+		issues, err = parseGolangCI(data)
+	case FormatCheckstyle:
+		issues, err = parseCheckstyle(data)
+	default:
+		return nil, fmt.Errorf("unsupported report format %q", format)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("parse %s report: %w", format, err)
+	}
+
+	tool = strings.TrimSpace(tool)
+	for i := range issues {
+		if tool != "" {
+			issues[i].Source = tool
+		}
+		if issues[i].Source == "" {
+			issues[i].Source = string(format)
+		}
+		if issues[i].RuleID == "" {
+			issues[i].RuleID = issues[i].Source
+		}
+	}
+	return issues, nil
+}
+
+func normalizeSeverity(level string) string {
+	switch strings.ToLower(strings.TrimSpace(level)) {
+	case "error", "high", "critical", "blocker":
+		return "high"
+	case "note", "none", "info", "low", "ignore":
+		return "low"
+	default:
+		return "medium"
+	}
+}
@@ -0,0 +1,74 @@
+package linters
+
+import "testing"
+
+func TestParseSARIF(t *testing.T) {
+	report := `{
+		"version": "2.1.0",
+		"runs": [{
+			"tool": {"driver": {"name": "Semgrep"}},
+			"results": [{
+				"ruleId": "go.lang.security.audit.sqli",
+				"level": "error",
+				"message": {"text": "SQL built from user input"},
+				"locations": [{"physicalLocation": {"artifactLocation": {"uri": "internal/storage/store.go"}, "region": {"startLine": 42}}}]
+			}]
+		}]
+	}`
+
+	issues, err := Parse(FormatSARIF, "", []byte(report))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(issues) != 1 {
+		t.Fatalf("expected 1 issue, got %d", len(issues))
+	}
+	issue := issues[0]
+	if issue.File != "internal/storage/store.go" || issue.Line != 42 {
+		t.Fatalf("unexpected location: %s:%d", issue.File, issue.Line)
+	}
+	if issue.Severity != "high" || issue.Source != "Semgrep" || issue.RuleID != "go.lang.security.audit.sqli" {
+		t.Fatalf("unexpected issue: %+v", issue)
+	}
+}
+
+func TestParseGolangCI(t *testing.T) {
+	report := `{"Issues":[{"FromLinter":"errcheck","Text":"Error return value is not checked","Pos":{"Filename":"main.go","Line":12}}]}`
+
+	issues, err := Parse(FormatGolangCI, "", []byte(report))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(issues) != 1 {
+		t.Fatalf("expected 1 issue, got %d", len(issues))
+	}
+	if issues[0].RuleID != "errcheck" || issues[0].Source != "golangci-lint" || issues[0].Severity != "medium" {
+		t.Fatalf("unexpected issue: %+v", issues[0])
+	}
+}
+
+func TestParseCheckstyleWithToolOverride(t *testing.T) {
+	report := `<?xml version="1.0" encoding="utf-8"?>
+<checkstyle version="4.3">
+  <file name="/home/runner/work/app/web/src/app.ts">
+    <error line="3" column="7" severity="warning" message="'x' is assigned a value but never used." source="eslint.rules.no-unused-vars"/>
+  </file>
+</checkstyle>`
+
+	issues, err := Parse(FormatCheckstyle, "eslint", []byte(report))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(issues) != 1 {
+		t.Fatalf("expected 1 issue, got %d", len(issues))
+	}
+	if issues[0].Source != "eslint" || issues[0].Line != 3 || issues[0].RuleID != "eslint.rules.no-unused-vars" {
+		t.Fatalf("unexpected issue: %+v", issues[0])
+	}
+}
+
+func TestParseFormatRejectsUnknown(t *testing.T) {
+	if _, err := ParseFormat("junit"); err == nil {
+		t.Fatalf("expected error for unsupported format")
+	}
+}
@@ -0,0 +1,71 @@
+package linters
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/example/pr-ai-teammate/internal/analysis"
+)
+
+type sarifLog struct {
+	Version string     `json:"version"`
+	Runs    []sarifRun `json:"runs"`
+}
+
+type sarifRun struct {
+	Tool struct {
+		Driver struct {
+			Name string `json:"name"`
+		} `json:"driver"`
+	} `json:"tool"`
+	Results []sarifResult `json:"results"`
+}
+
+type sarifResult struct {
+	RuleID  string `json:"ruleId"`
+	Level   string `json:"level"`
+	Message struct {
+		Text string `json:"text"`
+	} `json:"message"`
+	Locations []struct {
+		PhysicalLocation struct {
+			ArtifactLocation struct {
+				URI string `json:"uri"`
+			} `json:"artifactLocation"`
+			Region struct {
+				StartLine int `json:"startLine"`
+			} `json:"region"`
+		} `json:"physicalLocation"`
+	} `json:"locations"`
+}
+
+func parseSARIF(data []byte) ([]analysis.Issue, error) {
+	var report sarifLog
+	if err := json.Unmarshal(data, &report); err != nil {
+		return nil, err
+	}
+	if report.Version != "" && !strings.HasPrefix(report.Version, "2.1") {
+		return nil, fmt.Errorf("unsupported SARIF version %q", report.Version)
+	}
+
+	var issues []analysis.Issue
+	for _, run := range report.Runs {
+		source := run.Tool.Driver.Name
+		for _, result := range run.Results {
+			if len(result.Locations) == 0 {
+				continue
+			}
+			location := result.Locations[0].PhysicalLocation
+			issues = append(issues, analysis.Issue{
+				File:     location.ArtifactLocation.URI,
+				Line:     location.Region.StartLine,
+				RuleID:   result.RuleID,
+				Severity: normalizeSeverity(result.Level),
+				Message:  strings.TrimSpace(result.Message.Text),
+				Source:   source,
+			})
+		}
+	}
+	return issues, nil
+}
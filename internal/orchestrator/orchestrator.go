@@ -2,47 +2,101 @@ package orchestrator
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"io"
+	"log"
+	"path"
 	"strings"
+	"time"
 
 	"github.com/example/pr-ai-teammate/internal/ai"
 	"github.com/example/pr-ai-teammate/internal/analysis"
+	"github.com/example/pr-ai-teammate/internal/config"
+	"github.com/example/pr-ai-teammate/internal/deps"
 	"github.com/example/pr-ai-teammate/internal/github"
+	"github.com/example/pr-ai-teammate/internal/linters"
 	"github.com/example/pr-ai-teammate/internal/review"
 	"github.com/example/pr-ai-teammate/internal/rules"
+	"github.com/example/pr-ai-teammate/internal/vulndb"
 )
 
 type Service struct {
 	githubClient GitHubClient
 	reviewer     Reviewer
-	rulesEngine  *rules.Engine
 	store        Store
+	vulnDB       VulnerabilityDB
+	prices       ai.PriceTable
+	budget       ai.Budget
+	meter        *ai.Meter
+	now          func() time.Time
 }
 
 type GitHubClient interface {
 	FetchPullRequest(ctx context.Context, repo string, number int) (github.PullRequest, error)
 	FetchPullRequestDiff(ctx context.Context, repo string, number int) (string, error)
 	FetchFileContent(ctx context.Context, repo string, path string, ref string) (string, error)
+	ListDirectory(ctx context.Context, repo string, dir string, ref string) ([]string, error)
 	CreatePullRequestReview(ctx context.Context, repo string, number int, commitSHA string, body string, comments []github.ReviewComment) error
 }
 
 type Reviewer interface {
-	Review(ctx context.Context, input ai.ReviewInput) ([]analysis.Issue, string, error)
+	Review(ctx context.Context, input ai.ReviewInput) (ai.ReviewResult, error)
 }
 
 type Store interface {
 	UpsertPullRequest(ctx context.Context, repo string, number int, sha string, title string, status string) (int64, error)
 	UpdatePullRequestStatus(ctx context.Context, id int64, status string) error
 	SaveAnalysisResults(ctx context.Context, prID int64, issues []analysis.Issue) error
+	SaveExternalFindings(ctx context.Context, repo string, number int, sha string, source string, issues []analysis.Issue) error
+	ListExternalFindings(ctx context.Context, repo string, number int, sha string) ([]analysis.Issue, error)
+	SaveFunctionMetrics(ctx context.Context, prID int64, sha string, changes []analysis.ComplexityChange) error
+	SaveAIUsage(ctx context.Context, prID int64, records []ai.UsageRecord) error
+	SumAIUsage(ctx context.Context, query ai.UsageQuery) (ai.UsageTotals, error)
 }
 
-func NewService(githubClient GitHubClient, reviewer Reviewer, store Store) *Service {
-	return &Service{
+type VulnerabilityDB interface {
+	Check(changes []deps.Change) []analysis.Issue
+	Refresh() (vulndb.Stats, error)
+}
+
+type Option func(*Service)
+
+func WithVulnerabilityDB(db VulnerabilityDB) Option {
+	return func(s *Service) {
+		s.vulnDB = db
+	}
+}
+
+// WithPriceTable replaces the default model prices used to cost AI usage.
+func WithPriceTable(prices ai.PriceTable) Option {
+	return func(s *Service) {
+		s.prices = prices
+	}
+}
+
+// WithAIBudget limits monthly AI spend per repository. Once a repository's
+// spend reaches its limit, reviews fall back to rules and static analysis
+// until the next month.
+func WithAIBudget(budget ai.Budget) Option {
+	return func(s *Service) {
+		s.budget = budget
+	}
+}
+
+func NewService(githubClient GitHubClient, reviewer Reviewer, store Store, opts ...Option) *Service {
+	service := &Service{
 		githubClient: githubClient,
 		reviewer:     reviewer,
-		rulesEngine:  rules.NewDefaultEngine(),
 		store:        store,
+		prices:       ai.DefaultPrices(),
+		meter:        ai.NewMeter(),
+		now:          time.Now,
 	}
+	for _, opt := range opts {
+		opt(service)
+	}
+	return service
 }
 
 type AnalyzeInput struct {
@@ -84,6 +138,22 @@ func (s *Service) AnalyzePR(ctx context.Context, input AnalyzeInput) (AnalyzeRes
 		return AnalyzeResult{}, err
 	}
 
+	configRef := pr.Base.SHA
+	if configRef == "" {
+		configRef = input.CommitSHA
+	}
+	repoConfig, configErr := s.loadRepoConfig(ctx, input.Repository, configRef)
+	if configErr != nil {
+		log.Printf("config error for %s: %v", input.Repository, configErr)
+	}
+
+	attributes, err := s.loadGitAttributes(ctx, input.Repository, configRef)
+	if err != nil {
+		return AnalyzeResult{}, err
+	}
+	analysis.ClassifyByPatterns(files, repoConfig.Generated.Paths, attributes)
+	files, skipped := analysis.SplitExcluded(files)
+
 	prID := int64(0)
 	if s.store != nil {
 		storedID, err := s.store.UpsertPullRequest(ctx, input.Repository, input.PullNumber, input.CommitSHA, pr.Title, "processing")
@@ -95,7 +165,10 @@ func (s *Service) AnalyzePR(ctx context.Context, input AnalyzeInput) (AnalyzeRes
 
 	contents := map[string]string{}
 	for _, file := range files {
-		if strings.HasSuffix(strings.ToLower(file.Path), ".go") {
+		if file.Status == analysis.FileDeleted {
+			continue
+		}
+		if strings.HasSuffix(strings.ToLower(file.Path), ".go") || file.Type == analysis.FileTypeDockerfile || analysis.IsYAML(file.Path) {
 			body, err := s.githubClient.FetchFileContent(ctx, input.Repository, file.Path, input.CommitSHA)
 			if err != nil {
 				return AnalyzeResult{}, err
@@ -103,25 +176,223 @@ func (s *Service) AnalyzePR(ctx context.Context, input AnalyzeInput) (AnalyzeRes
 			contents[file.Path] = body
 		}
 	}
+	generatedBases, err := s.fetchGeneratedBases(ctx, input.Repository, pr.Base.SHA, files, contents)
+	if err != nil {
+		return AnalyzeResult{}, err
+	}
+	analysis.ClassifyByContent(files, contents, generatedBases)
+	files, generated := analysis.SplitExcluded(files)
+	for _, file := range generated {
+		delete(contents, file.Path)
+	}
+	skipped = append(skipped, generated...)
+	for i := range files {
+		if body, ok := contents[files[i].Path]; ok {
+			files[i].Type = analysis.RefineFileType(files[i], body)
+		}
+	}
+	reviewDiff := diff
+	if len(skipped) > 0 {
+		var sections []string
+		for _, file := range files {
+			sections = append(sections, file.Raw)
+		}
+		reviewDiff = strings.Join(sections, "\n")
+	}
 
-	issues := s.rulesEngine.Run(files)
-	issues = append(issues, analysis.RunStaticAnalysis(files, contents)...)
+	baseContents := map[string]string{}
+	if pr.Base.SHA != "" {
+		baseContents, err = s.fetchBaseContents(ctx, input.Repository, pr.Base.SHA, contents)
+		if err != nil {
+			return AnalyzeResult{}, err
+		}
+	}
+
+	goVersions, err := s.fetchGoVersions(ctx, input.Repository, input.CommitSHA, contents)
+	if err != nil {
+		return AnalyzeResult{}, err
+	}
+
+	testContents, err := s.fetchPackageTests(ctx, input.Repository, input.CommitSHA, files, contents)
+	if err != nil {
+		return AnalyzeResult{}, err
+	}
+	ruleContents := make(map[string]string, len(contents)+len(testContents))
+	for filePath, body := range contents {
+		ruleContents[filePath] = body
+	}
+	for filePath, body := range testContents {
+		ruleContents[filePath] = body
+	}
+
+	suppressions := analysis.BuildSuppressions(files, contents)
+	engine, err := rules.NewEngine(repoConfig)
+	if err != nil {
+		configErr = err
+		engine = rules.NewDefaultEngine()
+	}
+	issues := engine.RunContext(rules.Context{Files: files, Contents: ruleContents, Suppressions: suppressions})
+	// Secrets are flagged in generated and vendored files too, since whether
+	// a file is excluded partly depends on the PR itself.
+	for _, file := range skipped {
+		issues = append(issues, rules.SecretRule{}.Check(file)...)
+	}
+	issues = append(issues, analysis.RunStaticAnalysisWithOptions(files, contents, analysis.StaticOptions{
+		GoVersions:     goVersions,
+		SensitiveNames: repoConfig.SecretLogging.SensitiveNames,
+		Suppressions:   suppressions,
+	})...)
+
+	complexityChanges, complexityIssues := analysis.AnalyzeComplexity(files, contents, baseContents, analysis.ComplexityThresholds{
+		MaxCyclomatic: repoConfig.Complexity.MaxCyclomatic,
+		MaxCognitive:  repoConfig.Complexity.MaxCognitive,
+	})
+	issues = append(issues, suppressions.Filter(complexityIssues)...)
+
+	dependencyChanges, dependencyIssues := deps.Analyze(files, deps.Policy{
+		Allow: repoConfig.Dependencies.Allow,
+		Deny:  repoConfig.Dependencies.Deny,
+	})
+	issues = append(issues, suppressions.Filter(dependencyIssues)...)
+	if s.vulnDB != nil {
+		issues = append(issues, suppressions.Filter(s.vulnDB.Check(dependencyChanges))...)
+	}
+
+	if s.store != nil {
+		external, err := s.store.ListExternalFindings(ctx, input.Repository, input.PullNumber, input.CommitSHA)
+		if err != nil {
+			return AnalyzeResult{}, err
+		}
+		issues = append(issues, suppressions.Filter(analysis.FilterToChangedLines(external, files))...)
+	}
+	issues = append(issues, suppressions.Issues()...)
 
 	aiSummary := ""
-	if s.reviewer != nil {
-		aiIssues, summary, err := s.reviewer.Review(ctx, ai.ReviewInput{
-			Title: pr.Title,
-			Body:  pr.Body,
-			Diff:  diff,
+	var possibleConcerns []analysis.Issue
+	var aiNotes []string
+	var aiCalls []ai.Call
+	var verification ai.VerificationStats
+	skipReason, err := s.aiSkipReason(ctx, input.Repository, configErr)
+	if err != nil {
+		return AnalyzeResult{}, err
+	}
+	if skipReason != "" {
+		aiNotes = append(aiNotes, skipReason)
+	} else if s.reviewer != nil {
+		neverSend := repoConfig.AI.NeverSend
+		aiFiles, withheld := splitNeverSend(files, neverSend)
+		if len(withheld) > 0 {
+			var sections []string
+			for _, file := range aiFiles {
+				sections = append(sections, file.Raw)
+			}
+			reviewDiff = strings.Join(sections, "\n")
+			aiNotes = append(aiNotes, fmt.Sprintf("%d file(s) matching ai.never_send were not sent to the AI reviewer.", len(withheld)))
+		}
+		var aiFindings []analysis.Issue
+		for _, issue := range issues {
+			if !analysis.MatchAnyPath(neverSend, issue.File) {
+				aiFindings = append(aiFindings, issue)
+			}
+		}
+		var documentPaths []string
+		for _, documentPath := range repoConfig.AIContext.Documents {
+			if !analysis.MatchAnyPath(neverSend, documentPath) {
+				documentPaths = append(documentPaths, documentPath)
+			}
+		}
+		documents := s.fetchDocuments(ctx, input.Repository, configRef, documentPaths)
+		touched := s.fetchTouchedFiles(ctx, input.Repository, input.CommitSHA, aiFiles, contents)
+		aiResult, err := s.reviewer.Review(ctx, ai.ReviewInput{
+			Title:          pr.Title,
+			Body:           pr.Body,
+			Diff:           reviewDiff,
+			Findings:       aiFindings,
+			Documents:      documents,
+			Files:          touched,
+			MaxTokens:      repoConfig.AIContext.MaxTokens,
+			Provider:       repoConfig.AI.Provider,
+			SelfHostedOnly: repoConfig.AI.SelfHostedOnly,
+			RedactPatterns: repoConfig.AI.RedactPatterns,
+			SecretLines:    secretLines(aiFiles),
+			Prompt:         repoConfig.AI.Prompts.AI(),
+			Passes:         repoConfig.AI.ReviewPasses(),
+			Verification:   repoConfig.AI.Verification.AI(),
 		})
-		if err != nil {
+		s.meter.Verified(aiResult.Verification)
+		if stats := aiResult.Verification; stats.Failed {
+			aiNotes = append(aiNotes, "AI findings could not be verified and are posted as reviewed.")
+		} else if stats.Checked > 0 {
+			aiNotes = append(aiNotes, fmt.Sprintf("Verification: %d of %d AI finding(s) confirmed, %d downgraded, %d rejected, %d below the confidence threshold.",
+				stats.Confirmed, stats.Checked, stats.Downgraded, stats.Rejected, stats.LowConfidence))
+		}
+		possibleConcerns = aiResult.PossibleConcerns
+		if len(aiResult.Passes) > 1 {
+			aiNotes = append(aiNotes, fmt.Sprintf("AI review passes: %s.", strings.Join(aiResult.Passes, ", ")))
+		}
+		if len(aiResult.FailedPasses) > 0 && len(aiResult.Passes) > 0 {
+			aiNotes = append(aiNotes, fmt.Sprintf("AI review passes that failed and were skipped: %s.", strings.Join(aiResult.FailedPasses, ", ")))
+		}
+		if aiResult.Redactions != "" {
+			aiNotes = append(aiNotes, fmt.Sprintf("Redacted from the AI prompt: %s.", aiResult.Redactions))
+		}
+		if aiResult.Dropped > 0 {
+			aiNotes = append(aiNotes, fmt.Sprintf("Dropped %d AI finding(s) that did not point at an added line or failed validation.", aiResult.Dropped))
+		}
+		aiCalls = aiResult.Calls
+		verification = aiResult.Verification
+		switch {
+		case err == nil:
+		case ctx.Err() != nil:
 			return AnalyzeResult{}, err
+		case errors.Is(err, ai.ErrProviderUnavailable):
+			log.Printf("ai review skipped for %s: %v", input.Repository, err)
+			aiNotes = append(aiNotes, fmt.Sprintf("AI review skipped: %v.", err))
+		default:
+			// Degraded mode: the rule and static findings are still worth
+			// posting. Provider errors stay in the logs since the summary is
+			// public.
+			log.Printf("ai review failed for %s: %v", input.Repository, err)
+			aiNotes = append(aiNotes, "AI review was unavailable, so this review only contains rule and static analysis findings.")
+		}
+		issues = append(issues, aiResult.Issues...)
+		aiSummary = aiResult.Summary
+	}
+	usage := s.priceCalls(input, aiCalls)
+	if len(usage) > 0 {
+		// The review's first billed record carries its verification counts;
+		// a review served entirely from cache was already counted.
+		usage[0].FindingsChecked = verification.Checked
+		usage[0].FindingsDropped = verification.Dropped()
+		var totals ai.UsageTotals
+		for _, record := range usage {
+			totals.Add(record)
+		}
+		aiNotes = append(aiNotes, fmt.Sprintf("AI usage: %d input and %d output tokens, about $%.4f.", totals.InputTokens, totals.OutputTokens, totals.CostUSD))
+	}
+	if s.store != nil && prID != 0 {
+		// Recorded before anything else can fail: the tokens are spent
+		// whether or not the review gets posted.
+		if err := s.store.SaveAIUsage(ctx, prID, usage); err != nil {
+			log.Printf("saving ai usage for %s#%d failed: %v", input.Repository, input.PullNumber, err)
 		}
-		issues = append(issues, aiIssues...)
-		aiSummary = summary
 	}
 
 	reviewResult := review.Generate(issues)
+	reviewResult.AppendSection("Configuration", configProblems(configErr))
+	reviewResult.AppendSection("Missing tests", unanchoredLines(issues, "missing-tests"))
+	reviewResult.AppendSection("Migrations", unanchoredLines(issues, "migration-edited"))
+	reviewResult.AppendSection("AI review", aiNotes)
+	reviewResult.AppendSection("Possible prompt injection", unanchoredLines(issues, "prompt-injection"))
+	reviewResult.AppendSection("Possible concerns", concernLines(possibleConcerns))
+	reviewResult.AppendSection("Complexity changes", analysis.ComplexityDeltas(complexityChanges))
+	reviewResult.AppendSection("Dependency changes", deps.SummaryLines(dependencyChanges))
+	var suppressionLines []string
+	for _, suppression := range suppressions.Used() {
+		suppressionLines = append(suppressionLines, suppression.String())
+	}
+	reviewResult.AppendSection("Suppressed findings", suppressionLines)
+	reviewResult.AppendSection("Skipped files", skippedFiles(skipped))
 	if aiSummary != "" {
 		reviewResult.Summary = fmt.Sprintf("%s\n\n%s", reviewResult.Summary, aiSummary)
 	}
@@ -140,6 +411,9 @@ func (s *Service) AnalyzePR(ctx context.Context, input AnalyzeInput) (AnalyzeRes
 		if err := s.store.SaveAnalysisResults(ctx, prID, issues); err != nil {
 			return AnalyzeResult{}, err
 		}
+		if err := s.store.SaveFunctionMetrics(ctx, prID, input.CommitSHA, complexityChanges); err != nil {
+			return AnalyzeResult{}, err
+		}
 	}
 
 	if err := s.githubClient.CreatePullRequestReview(ctx, input.Repository, input.PullNumber, input.CommitSHA, reviewResult.Summary, comments); err != nil {
@@ -160,3 +434,445 @@ func (s *Service) AnalyzePR(ctx context.Context, input AnalyzeInput) (AnalyzeRes
 	)
 	return AnalyzeResult{Summary: summary}, nil
 }
+
+// RefreshVulnDB reloads the offline vulnerability database from disk.
+func (s *Service) RefreshVulnDB(ctx context.Context) (vulndb.Stats, error) {
+	if s.vulnDB == nil {
+		return vulndb.Stats{}, vulndb.ErrNotConfigured
+	}
+	return s.vulnDB.Refresh()
+}
+
+func (s *Service) loadRepoConfig(ctx context.Context, repo string, ref string) (config.RepoConfig, error) {
+	body, err := s.githubClient.FetchFileContent(ctx, repo, config.Path, ref)
+	if errors.Is(err, github.ErrNotFound) {
+		return config.Default(), nil
+	}
+	if err != nil {
+		return config.Default(), err
+	}
+	return config.Parse([]byte(body))
+}
+
+// aiSkipReason explains why the AI review must not run for this PR, or
+// returns "" when it may.
+func (s *Service) aiSkipReason(ctx context.Context, repo string, configErr error) (string, error) {
+	if s.reviewer == nil {
+		return "", nil
+	}
+	if configErr != nil {
+		// The config may restrict which provider can see this code, so an
+		// unreadable config must not fall back to the default provider.
+		return "AI review skipped until the repository configuration can be loaded.", nil
+	}
+	limit := s.budget.Limit(repo)
+	if limit <= 0 || s.store == nil {
+		return "", nil
+	}
+	spent, err := s.store.SumAIUsage(ctx, ai.UsageQuery{Repo: repo, From: ai.MonthStart(s.now())})
+	if err != nil {
+		return "", err
+	}
+	if spent.CostUSD < limit {
+		return "", nil
+	}
+	s.meter.BudgetExceeded()
+	return fmt.Sprintf("AI review skipped: this repository's monthly AI budget of $%.2f is used up, so this review only contains rule and static analysis findings.", limit), nil
+}
+
+// priceCalls costs each provider call and counts it in the metrics. Cache
+// hits are counted but not billed.
+func (s *Service) priceCalls(input AnalyzeInput, calls []ai.Call) []ai.UsageRecord {
+	records := make([]ai.UsageRecord, 0, len(calls))
+	for _, call := range calls {
+		if call.Cached {
+			// Served from cache: nothing was spent.
+			log.Printf("ai review for %s#%d served from cache (%s %s)", input.Repository, input.PullNumber, call.Provider, call.Model)
+			s.meter.CacheHit(call)
+			continue
+		}
+		record := ai.UsageRecord{
+			Repo:         input.Repository,
+			PullNumber:   input.PullNumber,
+			CommitSHA:    input.CommitSHA,
+			Provider:     call.Provider,
+			Model:        call.Model,
+			InputTokens:  call.Usage.InputTokens,
+			OutputTokens: call.Usage.OutputTokens,
+			CostUSD:      s.prices.Cost(call.Model, call.Usage),
+			CreatedAt:    s.now().UTC(),
+		}
+		s.meter.Record(record)
+		records = append(records, record)
+	}
+	return records
+}
+
+// ErrInvalidUsageQuery is wrapped by AIUsage errors caused by the query
+// rather than by the store.
+var ErrInvalidUsageQuery = errors.New("invalid usage query")
+
+type UsageInput struct {
+	Repository string
+	// PullNumber narrows the report to one PR; 0 covers the repository.
+	PullNumber int
+	// Month (YYYY-MM) narrows the report to one calendar month; empty covers
+	// all time.
+	Month string
+}
+
+type UsageReport struct {
+	ai.UsageTotals
+	// MonthlyBudgetUSD is the repository's limit, 0 when unlimited.
+	MonthlyBudgetUSD float64
+	// MonthToDateUSD is the spend the budget is checked against.
+	MonthToDateUSD float64
+}
+
+func (s *Service) AIUsage(ctx context.Context, input UsageInput) (UsageReport, error) {
+	if input.Repository == "" {
+		return UsageReport{}, fmt.Errorf("%w: repository is required", ErrInvalidUsageQuery)
+	}
+	if s.store == nil {
+		return UsageReport{}, fmt.Errorf("usage tracking requires a store")
+	}
+	query := ai.UsageQuery{Repo: input.Repository, PullNumber: input.PullNumber}
+	if input.Month != "" {
+		month, err := time.Parse("2006-01", input.Month)
+		if err != nil {
+			return UsageReport{}, fmt.Errorf("%w: month %q is not YYYY-MM", ErrInvalidUsageQuery, input.Month)
+		}
+		query.From = month
+		query.To = month.AddDate(0, 1, 0)
+	}
+	totals, err := s.store.SumAIUsage(ctx, query)
+	if err != nil {
+		return UsageReport{}, err
+	}
+	monthToDate, err := s.store.SumAIUsage(ctx, ai.UsageQuery{Repo: input.Repository, From: ai.MonthStart(s.now())})
+	if err != nil {
+		return UsageReport{}, err
+	}
+	return UsageReport{
+		UsageTotals:      totals,
+		MonthlyBudgetUSD: s.budget.Limit(input.Repository),
+		MonthToDateUSD:   monthToDate.CostUSD,
+	}, nil
+}
+
+// WriteMetrics writes AI usage counters in the Prometheus text format.
+func (s *Service) WriteMetrics(w io.Writer) error {
+	return s.meter.WritePrometheus(w)
+}
+
+// splitNeverSend separates files the repository never sends to the AI
+// reviewer.
+func splitNeverSend(files []analysis.FileDiff, patterns []string) ([]analysis.FileDiff, []analysis.FileDiff) {
+	if len(patterns) == 0 {
+		return files, nil
+	}
+	var kept, withheld []analysis.FileDiff
+	for _, file := range files {
+		if analysis.MatchAnyPath(patterns, file.Path) {
+			withheld = append(withheld, file)
+		} else {
+			kept = append(kept, file)
+		}
+	}
+	return kept, withheld
+}
+
+// fetchDocuments reads the configured context documents from the base
+// revision; missing documents are skipped.
+func (s *Service) fetchDocuments(ctx context.Context, repo string, ref string, paths []string) []ai.Document {
+	var documents []ai.Document
+	for _, documentPath := range paths {
+		body, err := s.githubClient.FetchFileContent(ctx, repo, documentPath, ref)
+		if errors.Is(err, github.ErrNotFound) {
+			continue
+		}
+		if err != nil {
+			// The documents are optional context; the review goes ahead
+			// without this one.
+			log.Printf("ai context document %s for %s not loaded: %v", documentPath, repo, err)
+			if ctx.Err() != nil {
+				return documents
+			}
+			continue
+		}
+		documents = append(documents, ai.Document{Path: documentPath, Content: body})
+	}
+	return documents
+}
+
+// fetchTouchedFiles returns the head content of every changed file, reusing
+// contents already fetched for the rules. Files that cannot be fetched are
+// left out; the reviewer still has their diff.
+func (s *Service) fetchTouchedFiles(ctx context.Context, repo string, ref string, files []analysis.FileDiff, contents map[string]string) []ai.Document {
+	var touched []ai.Document
+	for _, file := range files {
+		if file.Status == analysis.FileDeleted || len(file.AddedLines) == 0 {
+			continue
+		}
+		body, ok := contents[file.Path]
+		if !ok {
+			fetched, err := s.githubClient.FetchFileContent(ctx, repo, file.Path, ref)
+			if err != nil {
+				log.Printf("ai context file %s for %s not loaded: %v", file.Path, repo, err)
+				if ctx.Err() != nil {
+					return touched
+				}
+				continue
+			}
+			body = fetched
+		}
+		touched = append(touched, ai.Document{Path: file.Path, Content: body, ChangedLines: len(file.AddedLines) + len(file.RemovedLines)})
+	}
+	return touched
+}
+
+// loadGitAttributes reads linguist overrides from the base revision, like
+// the repo config, so a PR cannot mark its own code as generated.
+func (s *Service) loadGitAttributes(ctx context.Context, repo string, ref string) (analysis.GitAttributes, error) {
+	body, err := s.githubClient.FetchFileContent(ctx, repo, ".gitattributes", ref)
+	if errors.Is(err, github.ErrNotFound) {
+		return analysis.GitAttributes{}, nil
+	}
+	if err != nil {
+		return analysis.GitAttributes{}, err
+	}
+	return analysis.ParseGitAttributes(body), nil
+}
+
+// concernLines lists AI findings that verification was not confident
+// about; they go in the summary instead of on the diff.
+func concernLines(issues []analysis.Issue) []string {
+	var lines []string
+	for _, issue := range issues {
+		lines = append(lines, fmt.Sprintf("`%s:%d` (%s) %s", issue.File, issue.Line, issue.Severity, issue.Message))
+	}
+	return lines
+}
+
+// unanchoredLines returns the messages of ruleID findings without a line to
+// comment on, which review.Generate only counts.
+func unanchoredLines(issues []analysis.Issue, ruleID string) []string {
+	var lines []string
+	for _, issue := range issues {
+		if issue.RuleID == ruleID && (issue.File == "" || issue.Line == 0) {
+			lines = append(lines, issue.Message)
+		}
+	}
+	return lines
+}
+
+// secretLines returns the content of the added lines the secret rule
+// flags, so they are withheld from the AI provider. It runs the rule itself
+// rather than reading the review's findings, which suppressions have
+// already filtered.
+func secretLines(files []analysis.FileDiff) []string {
+	var lines []string
+	for _, file := range files {
+		flagged := map[int]bool{}
+		for _, issue := range (rules.SecretRule{}).Check(file) {
+			flagged[issue.Line] = true
+		}
+		for _, line := range file.AddedLines {
+			if flagged[line.Number] {
+				lines = append(lines, line.Content)
+			}
+		}
+	}
+	return lines
+}
+
+func skippedFiles(files []analysis.FileDiff) []string {
+	if len(files) == 0 {
+		return nil
+	}
+	counts := map[analysis.FileType]int{}
+	for _, file := range files {
+		counts[file.Type]++
+	}
+	return []string{fmt.Sprintf("%d file(s) not reviewed: %d generated, %d vendored.",
+		len(files), counts[analysis.FileTypeGenerated], counts[analysis.FileTypeVendored])}
+}
+
+func configProblems(err error) []string {
+	if err == nil {
+		return nil
+	}
+	lines := []string{fmt.Sprintf("`%s` could not be applied; using default settings.", config.Path)}
+	var validation *config.ValidationError
+	if errors.As(err, &validation) {
+		return append(lines, validation.Problems...)
+	}
+	return append(lines, err.Error())
+}
+
+// fetchBaseContents loads the base revision of every file fetched at head.
+// Files added by the PR have no base version and are skipped.
+func (s *Service) fetchBaseContents(ctx context.Context, repo string, ref string, contents map[string]string) (map[string]string, error) {
+	baseContents := make(map[string]string, len(contents))
+	for filePath := range contents {
+		if !strings.HasSuffix(strings.ToLower(filePath), ".go") {
+			continue
+		}
+		body, err := s.githubClient.FetchFileContent(ctx, repo, filePath, ref)
+		if errors.Is(err, github.ErrNotFound) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		baseContents[filePath] = body
+	}
+	return baseContents, nil
+}
+
+// fetchGeneratedBases loads the base revision of files whose head content
+// has a generated-code header, so the header is only trusted when it was
+// there before the PR.
+func (s *Service) fetchGeneratedBases(ctx context.Context, repo string, ref string, files []analysis.FileDiff, contents map[string]string) (map[string]string, error) {
+	bases := map[string]string{}
+	if ref == "" {
+		return bases, nil
+	}
+	for _, file := range files {
+		if file.Type.IsExcluded() || file.Status == analysis.FileAdded || !analysis.HasGeneratedHeader(file, contents) {
+			continue
+		}
+		body, err := s.githubClient.FetchFileContent(ctx, repo, file.Path, ref)
+		if errors.Is(err, github.ErrNotFound) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		bases[file.Path] = body
+	}
+	return bases, nil
+}
+
+// fetchGoVersions reads the go directive of the nearest go.mod above each
+// fetched Go file, so files in nested modules get their own semantics.
+func (s *Service) fetchGoVersions(ctx context.Context, repo string, ref string, contents map[string]string) (map[string]string, error) {
+	// modules caches the go.mod of each directory looked at, nil when it has
+	// none.
+	modules := map[string]*string{}
+	versions := map[string]string{}
+	for filePath := range contents {
+		if !strings.HasSuffix(strings.ToLower(filePath), ".go") {
+			continue
+		}
+		for dir := path.Dir(filePath); ; dir = path.Dir(dir) {
+			gomod, ok := modules[dir]
+			if !ok {
+				body, err := s.githubClient.FetchFileContent(ctx, repo, path.Join(dir, "go.mod"), ref)
+				if err != nil && !errors.Is(err, github.ErrNotFound) {
+					return nil, err
+				}
+				if err == nil {
+					gomod = &body
+				}
+				modules[dir] = gomod
+			}
+			if gomod != nil {
+				versions[filePath] = analysis.ParseGoDirective(*gomod)
+				break
+			}
+			if dir == "." || dir == "/" {
+				break
+			}
+		}
+	}
+	return versions, nil
+}
+
+// fetchPackageTests loads the existing _test.go files of every Go package
+// where the PR adds exported functions, so the missing-tests rule can look
+// for references that the diff itself does not show.
+func (s *Service) fetchPackageTests(ctx context.Context, repo string, ref string, files []analysis.FileDiff, contents map[string]string) (map[string]string, error) {
+	dirs := map[string]bool{}
+	for _, file := range files {
+		if len(analysis.AddedExportedFuncs(file)) > 0 {
+			dirs[path.Dir(file.Path)] = true
+		}
+	}
+
+	tests := map[string]string{}
+	for dir := range dirs {
+		entries, err := s.githubClient.ListDirectory(ctx, repo, dir, ref)
+		if errors.Is(err, github.ErrNotFound) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		for _, entry := range entries {
+			if !strings.HasSuffix(entry, "_test.go") {
+				continue
+			}
+			if _, ok := contents[entry]; ok {
+				continue
+			}
+			body, err := s.githubClient.FetchFileContent(ctx, repo, entry, ref)
+			if err != nil {
+				return nil, err
+			}
+			tests[entry] = body
+		}
+	}
+	return tests, nil
+}
+
+// ErrInvalidFindings is wrapped by IngestFindings errors caused by the
+// request or the report rather than by the store.
+var ErrInvalidFindings = errors.New("invalid findings report")
+
+type IngestInput struct {
+	Repository string
+	PullNumber int
+	CommitSHA  string
+	Format     string
+	Tool       string
+	Report     []byte
+}
+
+type IngestResult struct {
+	Source   string
+	Accepted int
+}
+
+func (s *Service) IngestFindings(ctx context.Context, input IngestInput) (IngestResult, error) {
+	if input.Repository == "" {
+		return IngestResult{}, fmt.Errorf("%w: repository is required", ErrInvalidFindings)
+	}
+	if input.PullNumber == 0 {
+		return IngestResult{}, fmt.Errorf("%w: pull number is required", ErrInvalidFindings)
+	}
+	if input.CommitSHA == "" {
+		return IngestResult{}, fmt.Errorf("%w: commit SHA is required", ErrInvalidFindings)
+	}
+	if s.store == nil {
+		return IngestResult{}, fmt.Errorf("no store configured for external findings")
+	}
+
+	format, err := linters.ParseFormat(input.Format)
+	if err != nil {
+		return IngestResult{}, fmt.Errorf("%w: %v", ErrInvalidFindings, err)
+	}
+	issues, err := linters.Parse(format, input.Tool, input.Report)
+	if err != nil {
+		return IngestResult{}, fmt.Errorf("%w: %v", ErrInvalidFindings, err)
+	}
+
+	source := strings.TrimSpace(input.Tool)
+	if source == "" {
+		source = string(format)
+	}
+	if err := s.store.SaveExternalFindings(ctx, input.Repository, input.PullNumber, input.CommitSHA, source, issues); err != nil {
+		return IngestResult{}, err
+	}
+	return IngestResult{Source: source, Accepted: len(issues)}, nil
+}
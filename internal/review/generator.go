@@ -48,6 +48,12 @@ func Generate(issues []analysis.Issue) Result {
 			continue
 		}
 		body := fmt.Sprintf("**%s**: %s", issue.RuleID, issue.Message)
+		if issue.Source != "" {
+			body = fmt.Sprintf("**%s** (%s): %s", issue.RuleID, issue.Source, issue.Message)
+		}
+		if issue.Suggestion != "" {
+			body = fmt.Sprintf("%s\n\n💡 Suggested fix: %s", body, issue.Suggestion)
+		}
 		comments = append(comments, Comment{
 			Path: issue.File,
 			Line: issue.Line,
@@ -68,6 +74,22 @@ func Generate(issues []analysis.Issue) Result {
 	}
 }
 
+func (r *Result) AppendSection(title string, lines []string) {
+	if len(lines) == 0 {
+		return
+	}
+	var builder strings.Builder
+	builder.WriteString(r.Summary)
+	builder.WriteString("\n\n### ")
+	builder.WriteString(title)
+	builder.WriteString("\n")
+	for _, line := range lines {
+		builder.WriteString("\n- ")
+		builder.WriteString(line)
+	}
+	r.Summary = builder.String()
+}
+
 func capitalize(value string) string {
 	if value == "" {
 		return value
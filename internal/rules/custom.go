@@ -0,0 +1,228 @@
+package rules
+
+import (
+	"bytes"
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"path"
+	"path/filepath"
+	"regexp"
+	"text/template"
+
+	"github.com/example/pr-ai-teammate/internal/analysis"
+	"github.com/example/pr-ai-teammate/internal/config"
+)
+
+type customScope struct {
+	paths     []string
+	fileTypes map[analysis.FileType]bool
+}
+
+func (s customScope) applies(file analysis.FileDiff) bool {
+	if len(s.paths) > 0 && !analysis.MatchAnyPath(s.paths, file.Path) {
+		return false
+	}
+	return len(s.fileTypes) == 0 || s.fileTypes[file.Type]
+}
+
+type customFinding struct {
+	ruleID   string
+	message  string
+	severity string
+	fix      *template.Template
+}
+
+type fixData struct {
+	File  string
+	Line  int
+	Match string
+	Call  string
+	Arg   string
+}
+
+func (f customFinding) issue(data fixData) analysis.Issue {
+	issue := analysis.Issue{
+		File:     data.File,
+		Line:     data.Line,
+		RuleID:   f.ruleID,
+		Severity: f.severity,
+		Message:  f.message,
+		Source:   "custom",
+	}
+	if f.fix != nil {
+		var buf bytes.Buffer
+		if err := f.fix.Execute(&buf, data); err == nil {
+			issue.Suggestion = buf.String()
+		}
+	}
+	return issue
+}
+
+type RegexRule struct {
+	customFinding
+	scope   customScope
+	pattern *regexp.Regexp
+}
+
+func (r RegexRule) ID() string          { return r.ruleID }
+func (r RegexRule) Description() string { return r.message }
+
+func (r RegexRule) Check(file analysis.FileDiff) []analysis.Issue {
+	if !r.scope.applies(file) {
+		return nil
+	}
+	var issues []analysis.Issue
+	for _, line := range file.AddedLines {
+		loc := r.pattern.FindStringIndex(line.Content)
+		if loc == nil {
+			continue
+		}
+		issues = append(issues, r.issue(fixData{File: file.Path, Line: line.Number, Match: line.Content[loc[0]:loc[1]]}))
+	}
+	return issues
+}
+
+// ASTRule flags Go calls by callee and argument name, e.g. log.Printf
+// called with an argument named token.
+type ASTRule struct {
+	customFinding
+	scope   customScope
+	call    string
+	argName *regexp.Regexp
+}
+
+func (r ASTRule) ID() string          { return r.ruleID }
+func (r ASTRule) Description() string { return r.message }
+
+func (r ASTRule) CheckPullRequest(pr Context) []analysis.Issue {
+	var issues []analysis.Issue
+	for _, file := range pr.Files {
+		if filepath.Ext(file.Path) != ".go" || !r.scope.applies(file) {
+			continue
+		}
+		source, ok := pr.Contents[file.Path]
+		if !ok {
+			continue
+		}
+		fset := token.NewFileSet()
+		parsed, err := parser.ParseFile(fset, file.Path, source, 0)
+		if err != nil {
+			continue
+		}
+
+		changed := map[int]bool{}
+		for _, line := range file.AddedLines {
+			changed[line.Number] = true
+		}
+		ast.Inspect(parsed, func(node ast.Node) bool {
+			call, ok := node.(*ast.CallExpr)
+			if !ok {
+				return true
+			}
+			line := fset.Position(call.Pos()).Line
+			if !changed[line] {
+				return true
+			}
+			callee := calleeName(call.Fun)
+			if matched, _ := path.Match(r.call, callee); !matched {
+				return true
+			}
+			arg, ok := r.matchingArg(call)
+			if !ok {
+				return true
+			}
+			issues = append(issues, r.issue(fixData{File: file.Path, Line: line, Match: callee, Call: callee, Arg: arg}))
+			return true
+		})
+	}
+	return issues
+}
+
+func (r ASTRule) matchingArg(call *ast.CallExpr) (string, bool) {
+	if r.argName == nil {
+		return "", true
+	}
+	for _, arg := range call.Args {
+		var name string
+		switch a := arg.(type) {
+		case *ast.Ident:
+			name = a.Name
+		case *ast.SelectorExpr:
+			name = a.Sel.Name
+		default:
+			continue
+		}
+		if r.argName.MatchString(name) {
+			return name, true
+		}
+	}
+	return "", false
+}
+
+// calleeName renders a call target as "pkg.Func", "recv.Method" or "Func",
+// keeping only the last selector so "s.db.Exec" becomes "db.Exec".
+func calleeName(fun ast.Expr) string {
+	switch f := fun.(type) {
+	case *ast.Ident:
+		return f.Name
+	case *ast.SelectorExpr:
+		switch x := f.X.(type) {
+		case *ast.Ident:
+			return x.Name + "." + f.Sel.Name
+		case *ast.SelectorExpr:
+			return x.Sel.Name + "." + f.Sel.Name
+		}
+		return "*." + f.Sel.Name
+	}
+	return ""
+}
+
+func newCustomRules(definitions []config.CustomRule) ([]Rule, []PullRequestRule, error) {
+	var lineRules []Rule
+	var prRules []PullRequestRule
+	for i, definition := range definitions {
+		finding := customFinding{
+			ruleID:   definition.ID,
+			message:  definition.Message,
+			severity: definition.Severity,
+		}
+		if definition.Fix != "" {
+			fix, err := template.New(definition.ID).Parse(definition.Fix)
+			if err != nil {
+				return nil, nil, fmt.Errorf("custom rule %d (%s): invalid fix template: %w", i, definition.ID, err)
+			}
+			finding.fix = fix
+		}
+		scope := customScope{paths: definition.Paths}
+		if len(definition.FileTypes) > 0 {
+			scope.fileTypes = map[analysis.FileType]bool{}
+			for _, fileType := range definition.FileTypes {
+				scope.fileTypes[analysis.FileType(fileType)] = true
+			}
+		}
+
+		switch definition.Type {
+		case config.CustomRuleRegex:
+			pattern, err := regexp.Compile(definition.Pattern)
+			if err != nil {
+				return nil, nil, fmt.Errorf("custom rule %d (%s): invalid pattern: %w", i, definition.ID, err)
+			}
+			lineRules = append(lineRules, RegexRule{customFinding: finding, scope: scope, pattern: pattern})
+		case config.CustomRuleAST:
+			rule := ASTRule{customFinding: finding, scope: scope, call: definition.Call}
+			if definition.ArgName != "" {
+				argName, err := regexp.Compile(definition.ArgName)
+				if err != nil {
+					return nil, nil, fmt.Errorf("custom rule %d (%s): invalid arg_name: %w", i, definition.ID, err)
+				}
+				rule.argName = argName
+			}
+			prRules = append(prRules, rule)
+		default:
+			return nil, nil, fmt.Errorf("custom rule %d (%s): unknown type %q", i, definition.ID, definition.Type)
+		}
+	}
+	return lineRules, prRules, nil
+}
@@ -0,0 +1,60 @@
+package rules
+
+import (
+	"testing"
+
+	"github.com/example/pr-ai-teammate/internal/analysis"
+	"github.com/example/pr-ai-teammate/internal/config"
+)
+
+func TestCustomRules(t *testing.T) {
+	cfg := config.Default()
+	cfg.MissingTests.Disabled = true
+	cfg.CustomRules = []config.CustomRule{
+		{ID: "no-token-log", Type: config.CustomRuleAST, Call: "log.Printf", ArgName: "(?i)token", Message: "Tokens must not be logged.", Severity: "high", Fix: "Remove {{.Arg}} from the {{.Call}} call."},
+		{ID: "no-fmt-println", Type: config.CustomRuleRegex, Pattern: `fmt\.Println\(`, Paths: []string{"internal/**"}, FileTypes: []string{"prod"}, Message: "Use the logger.", Severity: "low"},
+	}
+	engine, err := NewEngine(cfg)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	source := "package auth\n\nfunc login(token string) {\n\tlog.Printf(\"login %s\", token)\n\tfmt.Println(\"done\")\n}\n"
+	files := []analysis.FileDiff{{
+		Path: "internal/auth/login.go",
+		Type: analysis.FileTypeProd,
+		AddedLines: []analysis.Line{
+			{Number: 4, Content: "\tlog.Printf(\"login %s\", token)"},
+			{Number: 5, Content: "\tfmt.Println(\"done\")"},
+		},
+	}}
+
+	issues := engine.RunContext(Context{Files: files, Contents: map[string]string{files[0].Path: source}})
+	found := map[string]analysis.Issue{}
+	for _, issue := range issues {
+		found[issue.RuleID] = issue
+	}
+	if issue, ok := found["no-token-log"]; !ok || issue.Line != 4 || issue.Suggestion != "Remove token from the log.Printf call." {
+		t.Fatalf("unexpected no-token-log finding: %+v", found["no-token-log"])
+	}
+	if issue, ok := found["no-fmt-println"]; !ok || issue.Line != 5 {
+		t.Fatalf("unexpected no-fmt-println finding: %+v", found["no-fmt-println"])
+	}
+}
+
+func TestBuiltinRuleIDsAreReserved(t *testing.T) {
+	engine, err := NewEngine(config.Default())
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, rule := range engine.rules {
+		if !config.IsBuiltinRuleID(rule.ID()) {
+			t.Fatalf("built-in rule %q is not reserved for custom rules", rule.ID())
+		}
+	}
+	for _, rule := range engine.prRules {
+		if !config.IsBuiltinRuleID(rule.ID()) {
+			t.Fatalf("built-in rule %q is not reserved for custom rules", rule.ID())
+		}
+	}
+}
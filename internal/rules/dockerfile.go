@@ -0,0 +1,187 @@
+package rules
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/example/pr-ai-teammate/internal/analysis"
+)
+
+// DockerfileRule parses the head revision of changed Dockerfiles, falling
+// back to the added lines for new files whose content was not fetched.
+// Instruction findings are reported only for changed instructions; findings
+// about missing instructions are anchored to the final stage's FROM line and
+// reported only when that stage changed.
+type DockerfileRule struct {
+	SensitiveNames []string
+}
+
+func (DockerfileRule) ID() string { return "dockerfile" }
+func (DockerfileRule) Description() string {
+	return "Flags risky Dockerfile instructions such as unpinned base images, root users and leaked secrets."
+}
+
+func (r DockerfileRule) CheckPullRequest(pr Context) []analysis.Issue {
+	var issues []analysis.Issue
+	for _, file := range pr.Files {
+		if file.Type != analysis.FileTypeDockerfile || file.Status == analysis.FileDeleted || len(file.AddedLines) == 0 {
+			continue
+		}
+		issues = append(issues, r.checkFile(file, analysis.ParseDockerfile(fileSource(pr, file)))...)
+	}
+	return issues
+}
+
+func (r DockerfileRule) checkFile(file analysis.FileDiff, instructions []analysis.DockerInstruction) []analysis.Issue {
+	changed := changedLines(file)
+	changedLine := func(instruction analysis.DockerInstruction) int {
+		return firstChangedLine(instruction.StartLine, instruction.EndLine, changed)
+	}
+
+	var issues []analysis.Issue
+	report := func(line int, ruleID string, severity string, message string, suggestion string) {
+		issues = append(issues, analysis.Issue{
+			File:       file.Path,
+			Line:       line,
+			RuleID:     ruleID,
+			Severity:   severity,
+			Message:    message,
+			Suggestion: suggestion,
+		})
+	}
+
+	stages := map[string]bool{}
+	finalStage := -1
+	var finalFrom analysis.DockerInstruction
+	user, hasHealthcheck, finalChanged := "", false, false
+	for _, instruction := range instructions {
+		if instruction.Command == "FROM" {
+			finalStage = instruction.Stage
+			finalFrom = instruction
+			user, hasHealthcheck, finalChanged = "", false, false
+		}
+		switch instruction.Command {
+		case "USER":
+			user = strings.TrimSpace(instruction.Args)
+		case "HEALTHCHECK":
+			hasHealthcheck = true
+		}
+
+		line := changedLine(instruction)
+		finalChanged = finalChanged || line != 0
+		if line == 0 {
+			if instruction.Command == "FROM" {
+				stages[fromStageName(instruction.Args)] = true
+			}
+			continue
+		}
+		switch instruction.Command {
+		case "FROM":
+			image := fromImage(instruction.Args)
+			if image != "" && !stages[strings.ToLower(image)] && unpinnedImage(image) {
+				report(line, "docker-latest-tag", "medium",
+					fmt.Sprintf("Base image %s is not pinned to a version, so rebuilds silently pick up whatever `latest` points to.", image),
+					"Pin a specific tag, ideally with a digest (image:1.2.3@sha256:...).")
+			}
+			stages[fromStageName(instruction.Args)] = true
+		case "ADD":
+			for _, source := range strings.Fields(instruction.Args) {
+				if strings.HasPrefix(source, "http://") || strings.HasPrefix(source, "https://") {
+					report(line, "docker-add-remote", "medium",
+						fmt.Sprintf("ADD downloads %s at build time without verifying its contents.", source),
+						"Use ADD --checksum=sha256:... or RUN curl with a checksum check, and prefer COPY for local files.")
+					break
+				}
+			}
+		case "ENV", "ARG":
+			for _, name := range dockerVariableNames(instruction.Command, instruction.Args) {
+				if analysis.IsSensitiveName(name, r.SensitiveNames) {
+					report(line, "docker-secret-in-env", "high",
+						fmt.Sprintf("%s %s bakes a secret into the image metadata or build history.", instruction.Command, name),
+						"Pass secrets with RUN --mount=type=secret or inject them at runtime instead.")
+				}
+			}
+		case "RUN":
+			args := instruction.Args
+			if strings.Contains(args, "apt-get install") && !strings.Contains(args, "/var/lib/apt/lists") {
+				report(line, "docker-apt-no-cleanup", "low",
+					"apt-get install runs without removing /var/lib/apt/lists, leaving the package index in the image layer.",
+					"Finish the same RUN with && rm -rf /var/lib/apt/lists/*.")
+			}
+		case "USER":
+			if isRootUser(instruction.Args) && instruction.Stage == finalStage {
+				report(line, "docker-root-user", "high",
+					"The image switches to the root user.",
+					"Create an unprivileged user and switch to it with USER before the entrypoint.")
+			}
+		}
+	}
+
+	if finalStage < 0 || !finalChanged {
+		return issues
+	}
+	anchor := finalFrom.StartLine
+	if user == "" {
+		report(anchor, "docker-root-user", "high",
+			"The final stage never sets USER, so the container runs as root.",
+			"Create an unprivileged user and switch to it with USER before the entrypoint.")
+	}
+	if !hasHealthcheck {
+		report(anchor, "docker-missing-healthcheck", "low",
+			"The final image has no HEALTHCHECK, so orchestrators cannot tell a hung container from a healthy one.",
+			"Add a HEALTHCHECK (or HEALTHCHECK NONE when the platform probes the container itself).")
+	}
+	return issues
+}
+
+func fromImage(args string) string {
+	fields := strings.Fields(args)
+	if len(fields) == 0 {
+		return ""
+	}
+	return fields[0]
+}
+
+func fromStageName(args string) string {
+	fields := strings.Fields(args)
+	if len(fields) == 3 && strings.EqualFold(fields[1], "as") {
+		return strings.ToLower(fields[2])
+	}
+	return ""
+}
+
+// unpinnedImage reports images with no tag or the latest tag. Digests,
+// scratch and images built from ARGs are left alone.
+func unpinnedImage(image string) bool {
+	if image == "scratch" || strings.Contains(image, "@") || strings.Contains(image, "$") {
+		return false
+	}
+	name := image[strings.LastIndex(image, "/")+1:]
+	idx := strings.LastIndex(name, ":")
+	return idx < 0 || name[idx+1:] == "latest"
+}
+
+func isRootUser(args string) bool {
+	user := strings.SplitN(strings.TrimSpace(args), ":", 2)[0]
+	return user == "root" || user == "0"
+}
+
+// dockerVariableNames returns the names declared by ENV or ARG, accepting
+// both the "KEY=value ..." and legacy "ENV KEY value" forms.
+func dockerVariableNames(command string, args string) []string {
+	fields := strings.Fields(args)
+	if len(fields) == 0 {
+		return nil
+	}
+	if command == "ENV" && !strings.Contains(fields[0], "=") {
+		return []string{fields[0]}
+	}
+	var names []string
+	for _, field := range fields {
+		name, _, _ := strings.Cut(field, "=")
+		if name != "" && !strings.ContainsAny(name, `"'`) {
+			names = append(names, name)
+		}
+	}
+	return names
+}
@@ -0,0 +1,108 @@
+package rules
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/example/pr-ai-teammate/internal/analysis"
+)
+
+func TestDockerfileRule(t *testing.T) {
+	source := strings.Join([]string{
+		"FROM node:20 AS build",
+		"ARG NPM_TOKEN",
+		"RUN npm ci",
+		"FROM ubuntu",
+		"ENV APP_ENV=prod DB_PASSWORD=hunter2",
+		"RUN apt-get update && apt-get install -y curl",
+		"ADD https://example.com/tool.tar.gz /opt/",
+		"COPY --from=build /app /app",
+		"CMD [\"/app/server\"]",
+	}, "\n")
+	file := analysis.FileDiff{Path: "Dockerfile", Type: analysis.FileTypeDockerfile, Status: analysis.FileAdded}
+	for i, line := range strings.Split(source, "\n") {
+		file.AddedLines = append(file.AddedLines, analysis.Line{Number: i + 1, Content: line})
+	}
+
+	issues := DockerfileRule{}.CheckPullRequest(Context{Files: []analysis.FileDiff{file}})
+	got := map[string][]int{}
+	for _, issue := range issues {
+		got[issue.RuleID] = append(got[issue.RuleID], issue.Line)
+	}
+	want := map[string][]int{
+		"docker-latest-tag":          {4},
+		"docker-secret-in-env":       {2, 5},
+		"docker-apt-no-cleanup":      {6},
+		"docker-add-remote":          {7},
+		"docker-root-user":           {4},
+		"docker-missing-healthcheck": {4},
+	}
+	if len(got) != len(want) {
+		t.Fatalf("unexpected findings: %v", got)
+	}
+	for ruleID, lines := range want {
+		if !equalInts(got[ruleID], lines) {
+			t.Fatalf("%s: got lines %v, want %v", ruleID, got[ruleID], lines)
+		}
+	}
+}
+
+func TestDockerfileRuleOnlyReportsChangedInstructions(t *testing.T) {
+	source := strings.Join([]string{
+		"FROM alpine",
+		"RUN adduser -D app",
+		"HEALTHCHECK CMD wget -q localhost:8080/health",
+		"USER app",
+		"ENV LOG_LEVEL=debug",
+	}, "\n")
+	file := analysis.FileDiff{
+		Path:       "deploy/api.Dockerfile",
+		Type:       analysis.FileTypeDockerfile,
+		Status:     analysis.FileModified,
+		AddedLines: []analysis.Line{{Number: 5, Content: "ENV LOG_LEVEL=debug"}},
+	}
+
+	issues := DockerfileRule{}.CheckPullRequest(Context{
+		Files:    []analysis.FileDiff{file},
+		Contents: map[string]string{file.Path: source},
+	})
+	if len(issues) != 0 {
+		t.Fatalf("expected no findings for an unchanged FROM, got %+v", issues)
+	}
+}
+
+func TestDockerfileRuleAnchorsMissingInstructionsToTheFinalStage(t *testing.T) {
+	source := strings.Join([]string{
+		"FROM golang:1.21 AS build",
+		"RUN go build -o /server ./cmd/server",
+		"",
+		"FROM gcr.io/distroless/static:1.0",
+		"COPY --from=build /server /server",
+		"CMD [\"/server\"]",
+	}, "\n")
+	check := func(line analysis.Line) []analysis.Issue {
+		file := analysis.FileDiff{
+			Path:       "Dockerfile",
+			Type:       analysis.FileTypeDockerfile,
+			Status:     analysis.FileModified,
+			AddedLines: []analysis.Line{line},
+		}
+		return DockerfileRule{}.CheckPullRequest(Context{
+			Files:    []analysis.FileDiff{file},
+			Contents: map[string]string{file.Path: source},
+		})
+	}
+
+	if issues := check(analysis.Line{Number: 2, Content: "RUN go build -o /server ./cmd/server"}); len(issues) != 0 {
+		t.Fatalf("expected no findings for a change to the build stage, got %+v", issues)
+	}
+	issues := check(analysis.Line{Number: 6, Content: "CMD [\"/server\"]"})
+	if len(issues) != 2 {
+		t.Fatalf("expected missing USER and HEALTHCHECK findings, got %+v", issues)
+	}
+	for _, issue := range issues {
+		if issue.Line != 4 {
+			t.Fatalf("expected %s on the final FROM line, got line %d", issue.RuleID, issue.Line)
+		}
+	}
+}
@@ -1,6 +1,9 @@
 package rules
 
-import "github.com/example/pr-ai-teammate/internal/analysis"
+import (
+	"github.com/example/pr-ai-teammate/internal/analysis"
+	"github.com/example/pr-ai-teammate/internal/config"
+)
 
 type Rule interface {
 	ID() string
@@ -8,26 +11,88 @@ type Rule interface {
 	Check(file analysis.FileDiff) []analysis.Issue
 }
 
+// PullRequestRule looks at the whole change set instead of one file at a time.
+type PullRequestRule interface {
+	ID() string
+	Description() string
+	CheckPullRequest(pr Context) []analysis.Issue
+}
+
+// Context is the input for pull-request-wide rules. Contents holds the head
+// revision of fetched files, including existing tests next to changed code.
+// Suppressions are built from Files and Contents when nil.
+type Context struct {
+	Files        []analysis.FileDiff
+	Contents     map[string]string
+	Suppressions *analysis.Suppressions
+}
+
 type Engine struct {
-	rules []Rule
+	rules   []Rule
+	prRules []PullRequestRule
 }
 
 func NewDefaultEngine() *Engine {
-	return &Engine{
+	engine, err := NewEngine(config.Default())
+	if err != nil {
+		panic(err)
+	}
+	return engine
+}
+
+// NewEngine builds the built-in rules plus the repo's custom rules. Configs
+// from config.Parse are already validated; the error covers configs built
+// any other way.
+func NewEngine(cfg config.RepoConfig) (*Engine, error) {
+	engine := &Engine{
 		rules: []Rule{
 			TodoRule{},
 			SecretRule{},
 			LargeDiffRule{Threshold: 200},
 		},
 	}
+	if !cfg.Migrations.Disabled {
+		engine.rules = append(engine.rules, MigrationRule{
+			Paths:       cfg.Migrations.Paths,
+			LargeTables: cfg.Migrations.LargeTables,
+			Dialect:     cfg.Migrations.Dialect,
+		})
+	}
+	engine.prRules = append(engine.prRules, DockerfileRule{SensitiveNames: cfg.SecretLogging.SensitiveNames}, KubernetesRule{}, WorkflowRule{})
+	if !cfg.MissingTests.Disabled {
+		engine.prRules = append(engine.prRules, MissingTestsRule{
+			MinChangedLines: cfg.MissingTests.MinChangedLines,
+			ExemptPaths:     cfg.MissingTests.ExemptPaths,
+		})
+	}
+
+	lineRules, prRules, err := newCustomRules(cfg.CustomRules)
+	if err != nil {
+		return nil, err
+	}
+	engine.rules = append(engine.rules, lineRules...)
+	engine.prRules = append(engine.prRules, prRules...)
+	return engine, nil
 }
 
 func (e *Engine) Run(files []analysis.FileDiff) []analysis.Issue {
+	return e.RunContext(Context{Files: files})
+}
+
+func (e *Engine) RunContext(pr Context) []analysis.Issue {
 	var issues []analysis.Issue
-	for _, file := range files {
+	for _, file := range pr.Files {
 		for _, rule := range e.rules {
 			issues = append(issues, rule.Check(file)...)
 		}
 	}
-	return issues
+	for _, rule := range e.prRules {
+		issues = append(issues, rule.CheckPullRequest(pr)...)
+	}
+
+	suppressions := pr.Suppressions
+	if suppressions == nil {
+		suppressions = analysis.BuildSuppressions(pr.Files, pr.Contents)
+	}
+	return suppressions.Filter(issues)
 }
@@ -0,0 +1,181 @@
+package rules
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/example/pr-ai-teammate/internal/analysis"
+)
+
+// podSpecPaths locates the pod template of each workload kind.
+var podSpecPaths = map[string][]string{
+	"Pod":                   {"spec"},
+	"Deployment":            {"spec", "template", "spec"},
+	"StatefulSet":           {"spec", "template", "spec"},
+	"DaemonSet":             {"spec", "template", "spec"},
+	"ReplicaSet":            {"spec", "template", "spec"},
+	"ReplicationController": {"spec", "template", "spec"},
+	"Job":                   {"spec", "template", "spec"},
+	"CronJob":               {"spec", "jobTemplate", "spec", "template", "spec"},
+}
+
+// longRunningKinds are the workloads expected to define health probes.
+var longRunningKinds = map[string]bool{
+	"Deployment":            true,
+	"StatefulSet":           true,
+	"DaemonSet":             true,
+	"ReplicaSet":            true,
+	"ReplicationController": true,
+}
+
+// KubernetesRule checks workload manifests, including Helm templates. A
+// finding about a missing field is reported when the container it belongs
+// to changed, on the container's first changed line.
+type KubernetesRule struct{}
+
+func (KubernetesRule) ID() string { return "kubernetes" }
+func (KubernetesRule) Description() string {
+	return "Flags Kubernetes workloads without resource limits or probes, privileged containers, hostPath mounts and latest image tags."
+}
+
+func (r KubernetesRule) CheckPullRequest(pr Context) []analysis.Issue {
+	var issues []analysis.Issue
+	for _, file := range pr.Files {
+		if file.Type != analysis.FileTypeKubernetes || file.Status == analysis.FileDeleted || len(file.AddedLines) == 0 {
+			continue
+		}
+		changed := changedLines(file)
+		for _, document := range analysis.ParseYAMLDocuments(fileSource(pr, file)) {
+			issues = append(issues, r.checkDocument(file.Path, document, changed)...)
+		}
+	}
+	return issues
+}
+
+func (r KubernetesRule) checkDocument(filePath string, document *analysis.YAMLNode, changed map[int]bool) []analysis.Issue {
+	kind := document.Get("kind").Text()
+	path, ok := podSpecPaths[kind]
+	if !ok {
+		return nil
+	}
+	podSpec := document.Path(path...)
+	if podSpec == nil {
+		return nil
+	}
+
+	var issues []analysis.Issue
+	report := func(line int, ruleID string, severity string, message string, suggestion string) {
+		issues = append(issues, analysis.Issue{
+			File:       filePath,
+			Line:       line,
+			RuleID:     ruleID,
+			Severity:   severity,
+			Message:    message,
+			Suggestion: suggestion,
+		})
+	}
+
+	for _, volume := range podSpec.Get("volumes").List() {
+		if pair, ok := volume.Pair("hostPath"); ok && changed[pair.Line] {
+			report(pair.Line, "k8s-hostpath", "high",
+				fmt.Sprintf("Volume %s mounts a hostPath, giving the pod access to the node's filesystem.", volumeName(volume)),
+				"Use a PersistentVolumeClaim, emptyDir, ConfigMap or Secret volume instead.")
+		}
+	}
+
+	containers := podSpec.Get("containers").List()
+	initContainers := podSpec.Get("initContainers").List()
+	for i, container := range append(append([]*analysis.YAMLNode{}, containers...), initContainers...) {
+		isInit := i >= len(containers)
+		name := container.Get("name").Text()
+		if name == "" {
+			name = "(unnamed)"
+		}
+
+		if image, ok := container.Pair("image"); ok && changed[image.Line] && !image.Value.Template && unpinnedImage(image.Value.Text()) {
+			report(image.Line, "k8s-latest-tag", "medium",
+				fmt.Sprintf("Container %s uses image %s without a pinned tag, so rollouts are not reproducible.", name, image.Value.Text()),
+				"Pin a version tag, ideally with a digest (image:1.2.3@sha256:...).")
+		}
+		if privileged, ok := container.Path("securityContext").Pair("privileged"); ok && changed[privileged.Line] && privileged.Value.Text() == "true" {
+			report(privileged.Line, "k8s-privileged", "high",
+				fmt.Sprintf("Container %s runs privileged, which gives it root access to the node.", name),
+				"Drop privileged: true and grant only the specific capabilities the container needs.")
+		}
+
+		anchor := firstChangedLine(container.Line, container.EndLine, changed)
+		if anchor == 0 {
+			continue
+		}
+		resources := container.Get("resources")
+		if resources == nil || !resources.Template {
+			var missing []string
+			if resources.Get("requests").IsEmpty() {
+				missing = append(missing, "requests")
+			}
+			if resources.Get("limits").IsEmpty() {
+				missing = append(missing, "limits")
+			}
+			if len(missing) > 0 {
+				report(anchor, "k8s-missing-resources", "medium",
+					fmt.Sprintf("Container %s has no resource %s, so the scheduler cannot place it reliably and it can starve its neighbours.", name, strings.Join(missing, " or ")),
+					"Set resources.requests and resources.limits for cpu and memory.")
+			}
+		}
+		if !isInit && longRunningKinds[kind] {
+			var missing []string
+			if container.Get("livenessProbe") == nil {
+				missing = append(missing, "livenessProbe")
+			}
+			if container.Get("readinessProbe") == nil {
+				missing = append(missing, "readinessProbe")
+			}
+			if len(missing) > 0 {
+				report(anchor, "k8s-missing-probes", "low",
+					fmt.Sprintf("Container %s has no %s; Kubernetes cannot restart it when it hangs or hold traffic until it is ready.", name, strings.Join(missing, " or ")),
+					"Add livenessProbe and readinessProbe checks against a health endpoint.")
+			}
+		}
+	}
+	return issues
+}
+
+func volumeName(volume *analysis.YAMLNode) string {
+	if name := volume.Get("name").Text(); name != "" {
+		return name
+	}
+	return "(unnamed)"
+}
+
+func changedLines(file analysis.FileDiff) map[int]bool {
+	changed := make(map[int]bool, len(file.AddedLines))
+	for _, line := range file.AddedLines {
+		changed[line.Number] = true
+	}
+	return changed
+}
+
+func firstChangedLine(start int, end int, changed map[int]bool) int {
+	for line := start; line <= end; line++ {
+		if changed[line] {
+			return line
+		}
+	}
+	return 0
+}
+
+// fileSource returns the head content of a file, rebuilt from the added
+// lines when it was not fetched (for new files that is the whole file).
+func fileSource(pr Context, file analysis.FileDiff) string {
+	if source, ok := pr.Contents[file.Path]; ok {
+		return source
+	}
+	var lines []string
+	for _, line := range file.AddedLines {
+		for len(lines) < line.Number-1 {
+			lines = append(lines, "")
+		}
+		lines = append(lines, line.Content)
+	}
+	return strings.Join(lines, "\n")
+}
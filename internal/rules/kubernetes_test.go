@@ -0,0 +1,103 @@
+package rules
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/example/pr-ai-teammate/internal/analysis"
+)
+
+const deploymentManifest = `apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: api
+spec:
+  template:
+    spec:
+      volumes:
+        - name: docker
+          hostPath:
+            path: /var/run/docker.sock
+      initContainers:
+        - name: migrate
+          image: ghcr.io/acme/migrate:2.1.0
+          resources:
+            requests: {cpu: 100m, memory: 64Mi}
+            limits: {cpu: 500m, memory: 128Mi}
+      containers:
+        - name: api
+          image: ghcr.io/acme/api:latest
+          securityContext:
+            privileged: true
+          readinessProbe:
+            httpGet: {path: /ready, port: 8080}
+        - name: metrics
+          image: prom/exporter:0.9.1
+          resources:
+            {{- toYaml .Values.metrics.resources | nindent 12 }}
+          livenessProbe:
+            httpGet: {path: /health, port: 9100}
+          readinessProbe:
+            httpGet: {path: /health, port: 9100}
+---
+apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: api-config
+`
+
+func TestKubernetesRule(t *testing.T) {
+	file := analysis.FileDiff{Path: "deploy/api.yaml", Type: analysis.FileTypeConfig, Status: analysis.FileAdded}
+	for i, line := range strings.Split(deploymentManifest, "\n") {
+		file.AddedLines = append(file.AddedLines, analysis.Line{Number: i + 1, Content: line})
+	}
+	file.Type = analysis.RefineFileType(file, deploymentManifest)
+	if file.Type != analysis.FileTypeKubernetes {
+		t.Fatalf("expected kubernetes file type, got %s", file.Type)
+	}
+
+	got := map[string][]int{}
+	for _, issue := range (KubernetesRule{}).CheckPullRequest(Context{Files: []analysis.FileDiff{file}}) {
+		got[issue.RuleID] = append(got[issue.RuleID], issue.Line)
+	}
+	want := map[string][]int{
+		"k8s-hostpath":          {10},
+		"k8s-latest-tag":        {20},
+		"k8s-privileged":        {22},
+		"k8s-missing-resources": {19},
+		"k8s-missing-probes":    {19},
+	}
+	if len(got) != len(want) {
+		t.Fatalf("unexpected findings: %v", got)
+	}
+	for ruleID, lines := range want {
+		if !equalInts(got[ruleID], lines) {
+			t.Fatalf("%s: got lines %v, want %v", ruleID, got[ruleID], lines)
+		}
+	}
+}
+
+func TestKubernetesRuleReportsOnChangedContainerLines(t *testing.T) {
+	file := analysis.FileDiff{
+		Path:       "deploy/api.yaml",
+		Type:       analysis.FileTypeKubernetes,
+		Status:     analysis.FileModified,
+		AddedLines: []analysis.Line{{Number: 36, Content: "          image: prom/exporter:0.9.1"}, {Number: 42, Content: "            httpGet: {path: /health, port: 9100}"}},
+	}
+	issues := KubernetesRule{}.CheckPullRequest(Context{
+		Files:    []analysis.FileDiff{file},
+		Contents: map[string]string{file.Path: deploymentManifest},
+	})
+	if len(issues) != 0 {
+		t.Fatalf("expected no findings for a fully configured container, got %+v", issues)
+	}
+
+	file.AddedLines = []analysis.Line{{Number: 24, Content: "            httpGet: {path: /ready, port: 8080}"}}
+	issues = KubernetesRule{}.CheckPullRequest(Context{
+		Files:    []analysis.FileDiff{file},
+		Contents: map[string]string{file.Path: deploymentManifest},
+	})
+	if len(issues) != 2 || issues[0].Line != 24 || issues[1].Line != 24 {
+		t.Fatalf("expected resource and probe findings on line 24, got %+v", issues)
+	}
+}
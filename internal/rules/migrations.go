@@ -0,0 +1,266 @@
+package rules
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/example/pr-ai-teammate/internal/analysis"
+)
+
+var (
+	sqlLineComment     = regexp.MustCompile(`--.*$`)
+	alterTablePattern  = regexp.MustCompile(`^ALTER TABLE (?:IF EXISTS )?(?:ONLY )?([^\s(]+)`)
+	addColumnPattern   = regexp.MustCompile(`^ADD (?:COLUMN )?(?:IF NOT EXISTS )?([^\s(]+)`)
+	notNullPattern     = regexp.MustCompile(`\bNOT NULL\b`)
+	defaultPattern     = regexp.MustCompile(`\bDEFAULT\b`)
+	dropColumnPattern  = regexp.MustCompile(`^DROP (COLUMN )?(?:IF EXISTS )?([^\s,;]+)`)
+	dropTablePattern   = regexp.MustCompile(`^DROP TABLE (?:IF EXISTS )?([^\s,;]+)`)
+	renamePattern      = regexp.MustCompile(`^RENAME (?:COLUMN )?([^\s]+) TO ([^\s;]+)`)
+	renameTablePattern = regexp.MustCompile(`^RENAME (?:TO|AS) ([^\s;]+)`)
+	createIndexPattern = regexp.MustCompile(`^CREATE (?:UNIQUE )?INDEX\b`)
+	indexTablePattern  = regexp.MustCompile(`\bON (?:ONLY )?([^\s(]+)`)
+)
+
+// sqlKeywords follow ADD, DROP or RENAME when the clause is about a
+// constraint or an index rather than a column.
+var sqlKeywords = map[string]bool{
+	"CONSTRAINT": true, "PRIMARY": true, "UNIQUE": true, "FOREIGN": true, "CHECK": true,
+	"INDEX": true, "KEY": true, "FULLTEXT": true, "SPATIAL": true, "PARTITION": true,
+	"EXCLUDE": true, "DEFAULT": true, "NOT": true, "TO": true,
+}
+
+// MigrationRule reviews SQL migrations. Statements are rebuilt from added
+// lines, so a statement split across lines is checked as a whole.
+type MigrationRule struct {
+	Paths       []string
+	LargeTables []string
+	Dialect     string
+}
+
+func (MigrationRule) ID() string { return "migrations" }
+func (MigrationRule) Description() string {
+	return "Flags locking or destructive SQL migrations and edits to existing migrations."
+}
+
+type sqlStatement struct {
+	line int
+	text string
+	// starts maps offsets in text to the line that text came from.
+	starts []sqlLineStart
+}
+
+type sqlLineStart struct {
+	offset int
+	line   int
+}
+
+// lineAt returns the line the text at offset came from.
+func (s sqlStatement) lineAt(offset int) int {
+	line := s.line
+	for _, start := range s.starts {
+		if start.offset > offset {
+			break
+		}
+		line = start.line
+	}
+	return line
+}
+
+func (r MigrationRule) Check(file analysis.FileDiff) []analysis.Issue {
+	if !analysis.MatchAnyPath(r.Paths, file.Path) {
+		return nil
+	}
+
+	var issues []analysis.Issue
+	report := func(line int, ruleID string, severity string, message string, suggestion string) {
+		issues = append(issues, analysis.Issue{
+			File:       file.Path,
+			Line:       line,
+			RuleID:     ruleID,
+			Severity:   severity,
+			Message:    message,
+			Suggestion: suggestion,
+		})
+	}
+
+	if file.Status != analysis.FileAdded && (len(file.AddedLines) > 0 || len(file.RemovedLines) > 0) {
+		// A deleted migration has no line to comment on; the review lists
+		// it in the summary instead.
+		line := 0
+		switch {
+		case len(file.AddedLines) > 0:
+			line = file.AddedLines[0].Number
+		case file.Status != analysis.FileDeleted:
+			// The unchanged line before the removal.
+			line = max(1, file.RemovedLines[0].Number-1)
+		}
+		report(line, "migration-edited", "high",
+			fmt.Sprintf("%s already exists on the base branch; environments that ran it will not pick up the change.", file.Path),
+			"Leave merged migrations untouched and add a new migration with the follow-up change.")
+	}
+
+	for _, statement := range sqlStatements(file.AddedLines) {
+		r.checkStatement(statement, report)
+	}
+	return issues
+}
+
+func (r MigrationRule) checkStatement(statement sqlStatement, report func(int, string, string, string, string)) {
+	text := statement.text
+	if match := dropTablePattern.FindStringSubmatch(text); match != nil {
+		report(statement.line, "migration-drop", "high",
+			fmt.Sprintf("DROP TABLE %s deletes data and breaks any running code that still reads it.", tableName(match[1])),
+			"Stop using the table in a release first, then drop it in a later migration once no deployed version depends on it.")
+		return
+	}
+
+	if createIndexPattern.MatchString(text) {
+		if r.postgres() && !strings.Contains(text, " CONCURRENTLY ") {
+			table := ""
+			if match := indexTablePattern.FindStringSubmatch(text); match != nil {
+				table = " on " + tableName(match[1])
+			}
+			report(statement.line, "migration-index-not-concurrent", "medium",
+				fmt.Sprintf("CREATE INDEX%s without CONCURRENTLY blocks writes to the table until the index is built.", table),
+				"Use CREATE INDEX CONCURRENTLY (outside a transaction) so writes keep flowing while the index builds.")
+		}
+		return
+	}
+
+	match := alterTablePattern.FindStringSubmatchIndex(text)
+	if match == nil {
+		return
+	}
+	table := tableName(text[match[2]:match[3]])
+	for _, clause := range alterClauses(text, match[1]) {
+		line := statement.lineAt(clause.offset)
+		if add := addColumnPattern.FindStringSubmatch(clause.text); add != nil && !sqlKeywords[add[1]] &&
+			notNullPattern.MatchString(clause.text) && !defaultPattern.MatchString(clause.text) {
+			report(line, "migration-not-null-without-default", "high",
+				fmt.Sprintf("Adding a NOT NULL column to %s without a DEFAULT fails on existing rows and breaks inserts from code that does not set it yet.", table),
+				"Add the column as nullable (or with a DEFAULT), backfill it, then add the NOT NULL constraint in a later migration.")
+		}
+		if drop := dropColumnPattern.FindStringSubmatch(clause.text); drop != nil && (drop[1] != "" || !sqlKeywords[drop[2]]) {
+			report(line, "migration-drop", "high",
+				fmt.Sprintf("Dropping column %s from %s deletes data and breaks running code that still selects it.", tableName(drop[2]), table),
+				"Remove all reads and writes of the column in an earlier release, then drop it.")
+		}
+		if rename := renamePattern.FindStringSubmatch(clause.text); rename != nil && !sqlKeywords[rename[1]] {
+			report(line, "migration-rename", "medium",
+				fmt.Sprintf("Renaming %s to %s on %s breaks the currently deployed code during rollout.", tableName(rename[1]), tableName(rename[2]), table),
+				"Add the new column, write to both, backfill, switch reads, and drop the old column in later releases.")
+		}
+		if rename := renameTablePattern.FindStringSubmatch(clause.text); rename != nil {
+			report(line, "migration-rename", "high",
+				fmt.Sprintf("Renaming table %s to %s breaks every running query that still uses the old name.", table, tableName(rename[1])),
+				"Create the new table (or a view under the new name), move readers and writers over in a release, then drop the old name later.")
+		}
+	}
+	if r.isLargeTable(table) {
+		report(statement.line, "migration-large-table-lock", "high",
+			fmt.Sprintf("ALTER TABLE on %s, which is listed as a large table; the lock or rewrite can stall traffic for a long time.", table),
+			"Run the change with an online schema change tool or in small batches, and set a lock_timeout.")
+	}
+}
+
+type sqlClause struct {
+	offset int
+	text   string
+}
+
+// alterClauses splits the actions of an ALTER TABLE statement, starting at
+// offset, on commas outside parentheses.
+func alterClauses(text string, offset int) []sqlClause {
+	var clauses []sqlClause
+	depth, start := 0, offset
+	add := func(end int) {
+		clause := text[start:end]
+		trimmed := strings.TrimLeft(clause, " ")
+		if strings.TrimSpace(trimmed) != "" {
+			clauses = append(clauses, sqlClause{offset: start + len(clause) - len(trimmed), text: strings.TrimSpace(trimmed) + " "})
+		}
+	}
+	for i := offset; i < len(text); i++ {
+		switch text[i] {
+		case '(':
+			depth++
+		case ')':
+			depth--
+		case ',':
+			if depth == 0 {
+				add(i)
+				start = i + 1
+			}
+		}
+	}
+	add(len(text))
+	return clauses
+}
+
+func (r MigrationRule) postgres() bool {
+	return r.Dialect == "" || r.Dialect == "postgres"
+}
+
+func (r MigrationRule) isLargeTable(table string) bool {
+	for _, large := range r.LargeTables {
+		large = strings.ToLower(large)
+		if large == table || (!strings.Contains(large, ".") && strings.HasSuffix(table, "."+large)) {
+			return true
+		}
+	}
+	return false
+}
+
+// sqlStatements joins consecutive added lines into statements ending at ";".
+// Text is upper-cased with comments stripped and whitespace collapsed.
+func sqlStatements(lines []analysis.Line) []sqlStatement {
+	var statements []sqlStatement
+	var current sqlStatement
+	var text strings.Builder
+	previous := 0
+	flush := func() {
+		if text.Len() > 0 {
+			current.text = strings.ToUpper(text.String()) + " "
+			statements = append(statements, current)
+		}
+		current = sqlStatement{}
+		text.Reset()
+	}
+	appendText := func(line int, content string) {
+		fields := strings.Fields(content)
+		if len(fields) == 0 {
+			return
+		}
+		if text.Len() == 0 {
+			current.line = line
+		} else {
+			text.WriteString(" ")
+		}
+		current.starts = append(current.starts, sqlLineStart{offset: text.Len(), line: line})
+		text.WriteString(strings.Join(fields, " "))
+	}
+	for _, line := range lines {
+		if previous != 0 && line.Number != previous+1 {
+			flush()
+		}
+		previous = line.Number
+		content := sqlLineComment.ReplaceAllString(line.Content, "")
+		for {
+			idx := strings.Index(content, ";")
+			if idx < 0 {
+				appendText(line.Number, content)
+				break
+			}
+			appendText(line.Number, content[:idx])
+			flush()
+			content = content[idx+1:]
+		}
+	}
+	flush()
+	return statements
+}
+
+func tableName(raw string) string {
+	return strings.ToLower(strings.NewReplacer(`"`, "", "`", "").Replace(raw))
+}
@@ -0,0 +1,116 @@
+package rules
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/example/pr-ai-teammate/internal/analysis"
+	"github.com/example/pr-ai-teammate/internal/config"
+)
+
+func TestMigrationRule(t *testing.T) {
+	cfg := config.Default()
+	rule := MigrationRule{Paths: cfg.Migrations.Paths, LargeTables: []string{"events"}, Dialect: "postgres"}
+
+	lines := []string{
+		"-- add billing columns",
+		"ALTER TABLE accounts ADD COLUMN plan text NOT NULL;",
+		"ALTER TABLE accounts ADD COLUMN region text NOT NULL DEFAULT 'eu';",
+		"ALTER TABLE accounts",
+		"  DROP COLUMN legacy_id;",
+		"ALTER TABLE accounts ALTER COLUMN email DROP NOT NULL;",
+		"ALTER TABLE accounts RENAME COLUMN name TO display_name;",
+		"CREATE INDEX idx_accounts_plan ON accounts (plan);",
+		"CREATE INDEX CONCURRENTLY idx_accounts_region ON accounts (region);",
+		"DROP TABLE IF EXISTS old_invoices;",
+		"ALTER TABLE public.events ADD COLUMN source text;",
+		"ALTER TABLE accounts ADD CONSTRAINT plan_present CHECK (plan IS NOT NULL);",
+		"ALTER TABLE accounts ADD COLUMN tier text DEFAULT 'free',",
+		"  ADD COLUMN owner_id bigint NOT NULL;",
+		"ALTER TABLE accounts DROP PRIMARY KEY, DROP INDEX idx_plan, DROP CONSTRAINT fk_owner;",
+		"ALTER TABLE IF EXISTS invoices RENAME TO billing_invoices;",
+	}
+	file := analysis.FileDiff{Path: "db/migrations/0042_billing.sql", Status: analysis.FileAdded}
+	for i, content := range lines {
+		file.AddedLines = append(file.AddedLines, analysis.Line{Number: i + 1, Content: content})
+	}
+
+	got := map[string][]int{}
+	for _, issue := range rule.Check(file) {
+		got[issue.RuleID] = append(got[issue.RuleID], issue.Line)
+	}
+	want := map[string][]int{
+		"migration-not-null-without-default": {2, 14},
+		"migration-drop":                     {5, 10},
+		"migration-rename":                   {7, 16},
+		"migration-index-not-concurrent":     {8},
+		"migration-large-table-lock":         {11},
+	}
+	if len(got) != len(want) {
+		t.Fatalf("unexpected findings: %v", got)
+	}
+	for ruleID, wantLines := range want {
+		if !equalInts(got[ruleID], wantLines) {
+			t.Fatalf("%s: got lines %v, want %v", ruleID, got[ruleID], wantLines)
+		}
+	}
+
+	mysql := MigrationRule{Paths: cfg.Migrations.Paths, Dialect: "mysql"}
+	if issues := mysql.Check(analysis.FileDiff{Path: file.Path, Status: analysis.FileAdded, AddedLines: file.AddedLines[7:8]}); len(issues) != 0 {
+		t.Fatalf("expected no CONCURRENTLY finding for mysql, got %+v", issues)
+	}
+}
+
+func TestMigrationRuleFlagsEditedMigration(t *testing.T) {
+	diff := strings.Join([]string{
+		"diff --git a/db/migrations/0001_init.sql b/db/migrations/0001_init.sql",
+		"index 111..222 100644",
+		"--- a/db/migrations/0001_init.sql",
+		"+++ b/db/migrations/0001_init.sql",
+		"@@ -1,2 +1,2 @@",
+		" CREATE TABLE users (id bigint);",
+		"-CREATE TABLE teams (id int);",
+		"+CREATE TABLE teams (id bigint);",
+		"diff --git a/internal/migrations/readme.sql b/internal/migrations/readme.sql",
+		"new file mode 100644",
+		"--- /dev/null",
+		"+++ b/internal/migrations/readme.sql",
+		"@@ -0,0 +1 @@",
+		"+SELECT 1;",
+	}, "\n")
+	files, err := analysis.ParseUnifiedDiff(diff)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if files[0].Status != analysis.FileModified || files[1].Status != analysis.FileAdded {
+		t.Fatalf("unexpected statuses: %s, %s", files[0].Status, files[1].Status)
+	}
+
+	engine := NewDefaultEngine()
+	var edited []analysis.Issue
+	for _, issue := range engine.Run(files) {
+		if issue.RuleID == "migration-edited" {
+			edited = append(edited, issue)
+		}
+	}
+	if len(edited) != 1 || edited[0].File != "db/migrations/0001_init.sql" || edited[0].Line != 2 {
+		t.Fatalf("unexpected migration-edited findings: %+v", edited)
+	}
+
+	removed := analysis.FileDiff{Path: "db/migrations/0001_init.sql", Status: analysis.FileModified, RemovedLines: []analysis.Line{{Number: 7, Content: "DROP TABLE teams;"}}}
+	if issues := (MigrationRule{Paths: config.Default().Migrations.Paths}).Check(removed); len(issues) != 1 || issues[0].Line != 6 {
+		t.Fatalf("expected a removal to be anchored to the line before it, got %+v", issues)
+	}
+}
+
+func equalInts(a []int, b []int) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
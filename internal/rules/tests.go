@@ -0,0 +1,103 @@
+package rules
+
+import (
+	"fmt"
+	"path"
+	"regexp"
+	"strings"
+
+	"github.com/example/pr-ai-teammate/internal/analysis"
+)
+
+// MissingTestsRule flags pull requests that change enough production code
+// without touching a test, and new exported Go functions that no test in
+// their directory references. Files under ExemptPaths count neither as
+// production changes nor as test changes.
+type MissingTestsRule struct {
+	MinChangedLines int
+	ExemptPaths     []string
+}
+
+func (MissingTestsRule) ID() string { return "missing-tests" }
+func (MissingTestsRule) Description() string {
+	return "Flags production changes without test changes and new exported Go functions without tests."
+}
+
+func (r MissingTestsRule) CheckPullRequest(pr Context) []analysis.Issue {
+	var (
+		prodFiles    int
+		prodLines    int
+		testsByDir   = map[string][]string{}
+		touchedTests bool
+		issues       []analysis.Issue
+	)
+	for _, file := range pr.Files {
+		if analysis.MatchAnyPath(r.ExemptPaths, file.Path) {
+			continue
+		}
+		switch file.Type {
+		case analysis.FileTypeTest:
+			touchedTests = true
+			testsByDir[path.Dir(file.Path)] = append(testsByDir[path.Dir(file.Path)], addedText(file))
+		case analysis.FileTypeProd:
+			prodFiles++
+			prodLines += len(file.AddedLines)
+		}
+	}
+	for filePath, content := range pr.Contents {
+		if strings.HasSuffix(filePath, "_test.go") {
+			testsByDir[path.Dir(filePath)] = append(testsByDir[path.Dir(filePath)], content)
+		}
+	}
+
+	if !touchedTests && prodFiles > 0 && prodLines >= r.MinChangedLines {
+		issues = append(issues, analysis.Issue{
+			RuleID:   "missing-tests",
+			Severity: "medium",
+			Message:  fmt.Sprintf("%d production file(s) changed (%d added lines) without any test changes.", prodFiles, prodLines),
+		})
+	}
+
+	patterns := map[string]*regexp.Regexp{}
+	for _, file := range pr.Files {
+		if analysis.MatchAnyPath(r.ExemptPaths, file.Path) {
+			continue
+		}
+		tests := testsByDir[path.Dir(file.Path)]
+		for _, fn := range analysis.AddedExportedFuncs(file) {
+			pattern, ok := patterns[fn.Name]
+			if !ok {
+				pattern = regexp.MustCompile(`\b` + regexp.QuoteMeta(fn.Name) + `\b`)
+				patterns[fn.Name] = pattern
+			}
+			if referencedIn(pattern, tests) {
+				continue
+			}
+			issues = append(issues, analysis.Issue{
+				File:     file.Path,
+				Line:     fn.Line,
+				RuleID:   "missing-tests",
+				Severity: "low",
+				Message:  fmt.Sprintf("New exported function %s is not referenced by any test in %s.", fn.Name, path.Dir(file.Path)),
+			})
+		}
+	}
+	return issues
+}
+
+func referencedIn(pattern *regexp.Regexp, sources []string) bool {
+	for _, source := range sources {
+		if pattern.MatchString(source) {
+			return true
+		}
+	}
+	return false
+}
+
+func addedText(file analysis.FileDiff) string {
+	lines := make([]string, 0, len(file.AddedLines))
+	for _, line := range file.AddedLines {
+		lines = append(lines, line.Content)
+	}
+	return strings.Join(lines, "\n")
+}
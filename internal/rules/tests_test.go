@@ -0,0 +1,62 @@
+package rules
+
+import (
+	"testing"
+
+	"github.com/example/pr-ai-teammate/internal/analysis"
+)
+
+func TestMissingTestsRule(t *testing.T) {
+	rule := MissingTestsRule{MinChangedLines: 2, ExemptPaths: []string{"docs/**"}}
+	files := []analysis.FileDiff{
+		{
+			Path: "internal/auth/login.go",
+			Type: analysis.FileTypeProd,
+			AddedLines: []analysis.Line{
+				{Number: 10, Content: "func HandleLogin(w http.ResponseWriter, r *http.Request) {"},
+				{Number: 20, Content: "func (s *Service) Logout(ctx context.Context) error {"},
+				{Number: 30, Content: "func helper() {}"},
+			},
+		},
+		{Path: "docs/guide.go", Type: analysis.FileTypeProd, AddedLines: []analysis.Line{{Number: 1, Content: "func Documented() {}"}}},
+	}
+	contents := map[string]string{
+		"internal/auth/session_test.go": "func TestLogout(t *testing.T) { svc.Logout(ctx) }",
+	}
+
+	issues := rule.CheckPullRequest(Context{Files: files, Contents: contents})
+	if len(issues) != 2 {
+		t.Fatalf("expected PR-level and HandleLogin issues, got %+v", issues)
+	}
+	if issues[0].File != "" || issues[0].Line != 0 {
+		t.Fatalf("expected PR-level issue first, got %+v", issues[0])
+	}
+	if issues[1].Line != 10 {
+		t.Fatalf("expected HandleLogin flagged on line 10, got %+v", issues[1])
+	}
+}
+
+func TestMissingTestsRuleSatisfiedByTestChanges(t *testing.T) {
+	rule := MissingTestsRule{MinChangedLines: 1}
+	files := []analysis.FileDiff{
+		{Path: "pkg/math.go", Type: analysis.FileTypeProd, AddedLines: []analysis.Line{{Number: 3, Content: "func Add(a, b int) int {"}}},
+		{Path: "pkg/math_test.go", Type: analysis.FileTypeTest, AddedLines: []analysis.Line{{Number: 5, Content: "\tif Add(1, 2) != 3 {"}}},
+	}
+
+	if issues := rule.CheckPullRequest(Context{Files: files}); len(issues) != 0 {
+		t.Fatalf("expected no issues, got %+v", issues)
+	}
+}
+
+func TestMissingTestsRuleIgnoresExemptTestChanges(t *testing.T) {
+	rule := MissingTestsRule{MinChangedLines: 1, ExemptPaths: []string{"examples/**"}}
+	files := []analysis.FileDiff{
+		{Path: "pkg/math.go", Type: analysis.FileTypeProd, AddedLines: []analysis.Line{{Number: 3, Content: "\treturn a + b"}}},
+		{Path: "examples/math_test.go", Type: analysis.FileTypeTest, AddedLines: []analysis.Line{{Number: 5, Content: "\t_ = 1"}}},
+	}
+
+	issues := rule.CheckPullRequest(Context{Files: files})
+	if len(issues) != 1 || issues[0].File != "" {
+		t.Fatalf("expected the PR-level issue despite the exempt test change, got %+v", issues)
+	}
+}
@@ -0,0 +1,176 @@
+package rules
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/example/pr-ai-teammate/internal/analysis"
+)
+
+var (
+	workflowExpression = regexp.MustCompile(`\$\{\{([^}]*)\}\}`)
+	// untrustedContext matches the context fields a PR, issue or comment
+	// author controls as free text; numbers, SHAs and IDs are safe.
+	untrustedContext = regexp.MustCompile(`\bgithub\.(head_ref|event\.(` +
+		`(issue|pull_request|discussion)\.(title|body)|` +
+		`(comment|review|review_comment)\.body|` +
+		`pull_request\.head\.(ref|label|repo\.default_branch)|` +
+		`(commits(\[[^\]]*\]|\.\*)?|head_commit|workflow_run\.head_commit)\.(message|author\.(email|name))|` +
+		`workflow_run\.(head_branch|display_title)|` +
+		`pages(\[[^\]]*\]|\.\*)?\.page_name))\b`)
+	commitSHA = regexp.MustCompile(`^[0-9a-f]{40}$`)
+	prHeadRef = regexp.MustCompile(`github\.(event\.pull_request\.head\.|head_ref)`)
+)
+
+// firstPartyActionOwners publish actions maintained by GitHub itself.
+var firstPartyActionOwners = map[string]bool{
+	"actions": true,
+	"github":  true,
+}
+
+// WorkflowRule reviews GitHub Actions workflows. Findings are reported only
+// on changed lines.
+type WorkflowRule struct{}
+
+func (WorkflowRule) ID() string { return "workflow" }
+func (WorkflowRule) Description() string {
+	return "Flags risky GitHub Actions workflows: PR head checkouts under pull_request_target, script injection, unpinned actions and write-all permissions."
+}
+
+func (r WorkflowRule) CheckPullRequest(pr Context) []analysis.Issue {
+	var issues []analysis.Issue
+	for _, file := range pr.Files {
+		if file.Type != analysis.FileTypeWorkflow || file.Status == analysis.FileDeleted || len(file.AddedLines) == 0 {
+			continue
+		}
+		changed := changedLines(file)
+		for _, document := range analysis.ParseYAMLDocuments(fileSource(pr, file)) {
+			issues = append(issues, r.checkWorkflow(file.Path, document, changed)...)
+		}
+	}
+	return issues
+}
+
+func (r WorkflowRule) checkWorkflow(filePath string, workflow *analysis.YAMLNode, changed map[int]bool) []analysis.Issue {
+	var issues []analysis.Issue
+	report := func(line int, ruleID string, severity string, message string, suggestion string) {
+		if !changed[line] {
+			return
+		}
+		issues = append(issues, analysis.Issue{
+			File:       filePath,
+			Line:       line,
+			RuleID:     ruleID,
+			Severity:   severity,
+			Message:    message,
+			Suggestion: suggestion,
+		})
+	}
+	checkPermissions := func(node *analysis.YAMLNode, scope string) {
+		if pair, ok := node.Pair("permissions"); ok && pair.Value.Text() == "write-all" {
+			report(pair.Line, "workflow-write-all", "high",
+				fmt.Sprintf("%s grants permissions: write-all, so any compromised step can push code, publish releases or change settings.", scope),
+				"List only the scopes the job needs, e.g. permissions: {contents: read, pull-requests: write}.")
+		}
+	}
+	checkUses := func(pair analysis.YAMLPair) {
+		action := pair.Value.Text()
+		if unpinnedAction(action) {
+			report(pair.Line, "workflow-unpinned-action", "medium",
+				fmt.Sprintf("Third-party action %s is referenced by a mutable tag or branch; whoever controls the tag controls this job.", action),
+				"Pin the action to a full commit SHA and keep the tag in a comment, e.g. uses: owner/action@<sha> # v1.2.3.")
+		}
+	}
+
+	prTarget := workflowTriggers(workflow.Get("on"))["pull_request_target"]
+	checkPermissions(workflow, "The workflow")
+	for _, job := range workflow.Get("jobs").Entries() {
+		checkPermissions(job.Value, fmt.Sprintf("Job %s", job.Key))
+		if uses, ok := job.Value.Pair("uses"); ok {
+			checkUses(uses)
+		}
+		for _, step := range job.Value.Get("steps").List() {
+			uses, hasUses := step.Pair("uses")
+			if hasUses {
+				checkUses(uses)
+			}
+			action := uses.Value.Text()
+
+			if prTarget && strings.HasPrefix(action, "actions/checkout@") {
+				if ref, ok := step.Get("with").Pair("ref"); ok && prHeadRef.MatchString(ref.Value.Text()) {
+					report(ref.Line, "workflow-pr-target-checkout", "high",
+						"pull_request_target runs with repository secrets and a write token, and this step checks out the untrusted PR head.",
+						"Use the pull_request trigger for building PR code, or keep pull_request_target jobs away from the PR's code.")
+				}
+			}
+
+			scripts := []*analysis.YAMLNode{step.Get("run")}
+			if strings.HasPrefix(action, "actions/github-script@") {
+				scripts = append(scripts, step.Get("with").Get("script"))
+			}
+			for _, script := range scripts {
+				for _, injection := range scriptInjections(script) {
+					report(injection.line, "workflow-script-injection", "high",
+						fmt.Sprintf("${{ %s }} is expanded into the script before it runs, so attacker-controlled text can inject commands.", injection.expression),
+						fmt.Sprintf("Pass the value through an environment variable (env: VALUE: ${{ %s }}) and reference \"$VALUE\" in the script.", injection.expression))
+				}
+			}
+		}
+	}
+	return issues
+}
+
+// workflowTriggers reads the event names from the scalar, list and mapping
+// forms of `on`.
+func workflowTriggers(on *analysis.YAMLNode) map[string]bool {
+	triggers := map[string]bool{}
+	if on == nil {
+		return triggers
+	}
+	switch on.Kind {
+	case analysis.YAMLScalar:
+		triggers[on.Value] = true
+	case analysis.YAMLSequence:
+		for _, item := range on.Items {
+			triggers[item.Text()] = true
+		}
+	case analysis.YAMLMapping:
+		for _, pair := range on.Pairs {
+			triggers[pair.Key] = true
+		}
+	}
+	return triggers
+}
+
+func unpinnedAction(action string) bool {
+	if action == "" || strings.HasPrefix(action, "./") || strings.HasPrefix(action, "docker://") || strings.Contains(action, "${{") {
+		return false
+	}
+	name, ref, ok := strings.Cut(action, "@")
+	if !ok {
+		return true
+	}
+	owner := strings.SplitN(name, "/", 2)[0]
+	return !firstPartyActionOwners[strings.ToLower(owner)] && !commitSHA.MatchString(ref)
+}
+
+type scriptInjection struct {
+	line       int
+	expression string
+}
+
+func scriptInjections(script *analysis.YAMLNode) []scriptInjection {
+	if script == nil || script.Kind != analysis.YAMLScalar {
+		return nil
+	}
+	var injections []scriptInjection
+	for i, line := range strings.Split(script.Value, "\n") {
+		for _, match := range workflowExpression.FindAllStringSubmatch(line, -1) {
+			if untrustedContext.MatchString(match[1]) {
+				injections = append(injections, scriptInjection{line: script.Line + i, expression: strings.TrimSpace(match[1])})
+			}
+		}
+	}
+	return injections
+}
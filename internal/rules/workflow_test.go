@@ -0,0 +1,105 @@
+package rules
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/example/pr-ai-teammate/internal/analysis"
+)
+
+const labelWorkflow = `name: label
+on:
+  pull_request_target:
+    types: [opened, synchronize]
+permissions: write-all
+jobs:
+  label:
+    runs-on: ubuntu-latest
+    permissions:
+      contents: read
+    steps:
+      - uses: actions/checkout@v4
+        with:
+          ref: ${{ github.event.pull_request.head.sha }}
+      - uses: tj-actions/changed-files@v44
+      - uses: docker/login-action@0d4c9c5ea7693da7b068278f7b52bda2a190a446
+      - name: Greet
+        env:
+          TITLE: ${{ github.event.pull_request.title }}
+        run: |
+          echo "PR number ${{ github.event.number }}"
+          echo "Title: $TITLE"
+          echo "Branch ${{ github.head_ref }} by ${{ github.actor }}"
+      - uses: actions/github-script@v7
+        with:
+          script: |
+            console.log("${{ github.event.comment.body }}")
+  reuse:
+    uses: acme/workflows/.github/workflows/deploy.yml@main
+`
+
+func TestWorkflowRule(t *testing.T) {
+	file := analysis.FileDiff{Path: ".github/workflows/label.yml", Status: analysis.FileAdded}
+	file.Type = analysis.ClassifyPath(file.Path)
+	if file.Type != analysis.FileTypeWorkflow {
+		t.Fatalf("expected workflow file type, got %s", file.Type)
+	}
+	for i, line := range strings.Split(labelWorkflow, "\n") {
+		file.AddedLines = append(file.AddedLines, analysis.Line{Number: i + 1, Content: line})
+	}
+
+	got := map[string][]int{}
+	for _, issue := range (WorkflowRule{}).CheckPullRequest(Context{Files: []analysis.FileDiff{file}}) {
+		got[issue.RuleID] = append(got[issue.RuleID], issue.Line)
+	}
+	want := map[string][]int{
+		"workflow-write-all":          {5},
+		"workflow-pr-target-checkout": {14},
+		"workflow-unpinned-action":    {15, 29},
+		"workflow-script-injection":   {23, 27},
+	}
+	if len(got) != len(want) {
+		t.Fatalf("unexpected findings: %v", got)
+	}
+	for ruleID, lines := range want {
+		if !equalInts(got[ruleID], lines) {
+			t.Fatalf("%s: got lines %v, want %v", ruleID, got[ruleID], lines)
+		}
+	}
+}
+
+func TestWorkflowRuleIgnoresCheckoutOutsidePullRequestTarget(t *testing.T) {
+	source := strings.Replace(labelWorkflow, "pull_request_target:", "pull_request:", 1)
+	file := analysis.FileDiff{
+		Path:       ".github/workflows/label.yml",
+		Type:       analysis.FileTypeWorkflow,
+		Status:     analysis.FileModified,
+		AddedLines: []analysis.Line{{Number: 14, Content: "          ref: ${{ github.event.pull_request.head.sha }}"}},
+	}
+	issues := WorkflowRule{}.CheckPullRequest(Context{
+		Files:    []analysis.FileDiff{file},
+		Contents: map[string]string{file.Path: source},
+	})
+	if len(issues) != 0 {
+		t.Fatalf("expected no findings, got %+v", issues)
+	}
+}
+
+func TestUntrustedContext(t *testing.T) {
+	for expression, want := range map[string]bool{
+		"github.event.pull_request.title":        true,
+		"github.event.issue.body":                true,
+		"github.event.pull_request.head.ref":     true,
+		"github.event.commits[0].message":        true,
+		"github.event.head_commit.author.email":  true,
+		"github.head_ref":                        true,
+		"github.event.number":                    false,
+		"github.event.pull_request.head.sha":     false,
+		"github.event.pull_request.user.login":   false,
+		"github.event.pull_request.titles_count": false,
+	} {
+		if got := untrustedContext.MatchString(expression); got != want {
+			t.Errorf("%s: got %v, want %v", expression, got, want)
+		}
+	}
+}
@@ -3,10 +3,13 @@ package storage
 import (
 	"context"
 	"database/sql"
+	"errors"
 	"fmt"
+	"sort"
 	"sync"
 	"time"
 
+	"github.com/example/pr-ai-teammate/internal/ai"
 	"github.com/example/pr-ai-teammate/internal/analysis"
 	_ "github.com/lib/pq"
 )
@@ -15,6 +18,13 @@ type Store interface {
 	UpsertPullRequest(ctx context.Context, repo string, number int, sha string, title string, status string) (int64, error)
 	UpdatePullRequestStatus(ctx context.Context, id int64, status string) error
 	SaveAnalysisResults(ctx context.Context, prID int64, issues []analysis.Issue) error
+	SaveExternalFindings(ctx context.Context, repo string, number int, sha string, source string, issues []analysis.Issue) error
+	ListExternalFindings(ctx context.Context, repo string, number int, sha string) ([]analysis.Issue, error)
+	SaveFunctionMetrics(ctx context.Context, prID int64, sha string, changes []analysis.ComplexityChange) error
+	SaveAIUsage(ctx context.Context, prID int64, records []ai.UsageRecord) error
+	SumAIUsage(ctx context.Context, query ai.UsageQuery) (ai.UsageTotals, error)
+	GetAIResponse(ctx context.Context, key string) (ai.CompletionResponse, bool, error)
+	PutAIResponse(ctx context.Context, key string, response ai.CompletionResponse, expiresAt time.Time) error
 }
 
 func NewStore(ctx context.Context, dsn string) (Store, error) {
@@ -29,6 +39,10 @@ type MemoryStore struct {
 	nextID    int64
 	pulls     map[string]*pullRequestRecord
 	analyses  map[int64][]analysis.Issue
+	external  map[string]map[string][]analysis.Issue
+	metrics   map[int64][]functionMetricsRecord
+	usage     []ai.UsageRecord
+	responses map[string]cachedResponse
 	updatedAt time.Time
 }
 
@@ -42,11 +56,25 @@ type pullRequestRecord struct {
 	CreatedAt time.Time
 }
 
+type cachedResponse struct {
+	Response  ai.CompletionResponse
+	ExpiresAt time.Time
+}
+
+type functionMetricsRecord struct {
+	SHA        string
+	Change     analysis.ComplexityChange
+	RecordedAt time.Time
+}
+
 func NewMemoryStore() *MemoryStore {
 	return &MemoryStore{
-		nextID:   1,
-		pulls:    make(map[string]*pullRequestRecord),
-		analyses: make(map[int64][]analysis.Issue),
+		nextID:    1,
+		pulls:     make(map[string]*pullRequestRecord),
+		analyses:  make(map[int64][]analysis.Issue),
+		external:  make(map[string]map[string][]analysis.Issue),
+		metrics:   make(map[int64][]functionMetricsRecord),
+		responses: make(map[string]cachedResponse),
 	}
 }
 
@@ -94,6 +122,90 @@ func (m *MemoryStore) SaveAnalysisResults(ctx context.Context, prID int64, issue
 	return nil
 }
 
+func (m *MemoryStore) SaveExternalFindings(ctx context.Context, repo string, number int, sha string, source string, issues []analysis.Issue) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	key := fmt.Sprintf("%s#%d@%s", repo, number, sha)
+	if m.external[key] == nil {
+		m.external[key] = make(map[string][]analysis.Issue)
+	}
+	m.external[key][source] = append([]analysis.Issue{}, issues...)
+	return nil
+}
+
+func (m *MemoryStore) ListExternalFindings(ctx context.Context, repo string, number int, sha string) ([]analysis.Issue, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	bySource := m.external[fmt.Sprintf("%s#%d@%s", repo, number, sha)]
+	sources := make([]string, 0, len(bySource))
+	for source := range bySource {
+		sources = append(sources, source)
+	}
+	sort.Strings(sources)
+
+	var issues []analysis.Issue
+	for _, source := range sources {
+		issues = append(issues, bySource[source]...)
+	}
+	return issues, nil
+}
+
+func (m *MemoryStore) SaveFunctionMetrics(ctx context.Context, prID int64, sha string, changes []analysis.ComplexityChange) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	now := time.Now().UTC()
+	for _, change := range changes {
+		m.metrics[prID] = append(m.metrics[prID], functionMetricsRecord{SHA: sha, Change: change, RecordedAt: now})
+	}
+	return nil
+}
+
+func (m *MemoryStore) SaveAIUsage(ctx context.Context, prID int64, records []ai.UsageRecord) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	now := time.Now().UTC()
+	for _, record := range records {
+		if record.CreatedAt.IsZero() {
+			record.CreatedAt = now
+		}
+		m.usage = append(m.usage, record)
+	}
+	return nil
+}
+
+func (m *MemoryStore) SumAIUsage(ctx context.Context, query ai.UsageQuery) (ai.UsageTotals, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	var totals ai.UsageTotals
+	for _, record := range m.usage {
+		if query.Matches(record) {
+			totals.Add(record)
+		}
+	}
+	return totals, nil
+}
+
+func (m *MemoryStore) GetAIResponse(ctx context.Context, key string) (ai.CompletionResponse, bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	entry, ok := m.responses[key]
+	if !ok || !time.Now().Before(entry.ExpiresAt) {
+		delete(m.responses, key)
+		return ai.CompletionResponse{}, false, nil
+	}
+	return entry.Response, true, nil
+}
+
+func (m *MemoryStore) PutAIResponse(ctx context.Context, key string, response ai.CompletionResponse, expiresAt time.Time) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.responses[key] = cachedResponse{Response: response, ExpiresAt: expiresAt}
+	return nil
+}
+
 type PostgresStore struct {
 	db *sql.DB
 }
@@ -134,8 +246,67 @@ func (p *PostgresStore) ensureSchema(ctx context.Context) error {
 			severity TEXT NOT NULL,
 			message TEXT NOT NULL,
 			line INTEGER NOT NULL,
+			prompt_version TEXT NOT NULL DEFAULT '',
 			created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
 		);`,
+		`ALTER TABLE analysis_results ADD COLUMN IF NOT EXISTS prompt_version TEXT NOT NULL DEFAULT '';`,
+		`CREATE TABLE IF NOT EXISTS external_findings (
+			id SERIAL PRIMARY KEY,
+			repo TEXT NOT NULL,
+			pr_number INTEGER NOT NULL,
+			commit_sha TEXT NOT NULL,
+			source TEXT NOT NULL,
+			file TEXT NOT NULL,
+			rule_id TEXT NOT NULL,
+			severity TEXT NOT NULL,
+			message TEXT NOT NULL,
+			suggestion TEXT NOT NULL DEFAULT '',
+			line INTEGER NOT NULL,
+			created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+		);`,
+		`ALTER TABLE external_findings ADD COLUMN IF NOT EXISTS suggestion TEXT NOT NULL DEFAULT '';`,
+		`CREATE INDEX IF NOT EXISTS external_findings_lookup ON external_findings (repo, pr_number, commit_sha);`,
+		`CREATE TABLE IF NOT EXISTS function_metrics (
+			id SERIAL PRIMARY KEY,
+			pr_id INTEGER NOT NULL REFERENCES pull_requests(id) ON DELETE CASCADE,
+			commit_sha TEXT NOT NULL,
+			file TEXT NOT NULL,
+			function_name TEXT NOT NULL,
+			line INTEGER NOT NULL,
+			cyclomatic INTEGER NOT NULL,
+			cognitive INTEGER NOT NULL,
+			base_cyclomatic INTEGER,
+			base_cognitive INTEGER,
+			created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+		);`,
+		`CREATE TABLE IF NOT EXISTS ai_usage (
+			id SERIAL PRIMARY KEY,
+			pr_id INTEGER NOT NULL REFERENCES pull_requests(id) ON DELETE CASCADE,
+			repo TEXT NOT NULL,
+			pr_number INTEGER NOT NULL,
+			commit_sha TEXT NOT NULL,
+			provider TEXT NOT NULL,
+			model TEXT NOT NULL,
+			input_tokens INTEGER NOT NULL,
+			output_tokens INTEGER NOT NULL,
+			cost_usd NUMERIC(12, 6) NOT NULL,
+			findings_checked INTEGER NOT NULL DEFAULT 0,
+			findings_dropped INTEGER NOT NULL DEFAULT 0,
+			created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+		);`,
+		`ALTER TABLE ai_usage ADD COLUMN IF NOT EXISTS findings_checked INTEGER NOT NULL DEFAULT 0;`,
+		`ALTER TABLE ai_usage ADD COLUMN IF NOT EXISTS findings_dropped INTEGER NOT NULL DEFAULT 0;`,
+		`CREATE INDEX IF NOT EXISTS ai_usage_repo_created ON ai_usage (repo, created_at);`,
+		`CREATE TABLE IF NOT EXISTS ai_response_cache (
+			cache_key TEXT PRIMARY KEY,
+			model TEXT NOT NULL,
+			content TEXT NOT NULL,
+			input_tokens INTEGER NOT NULL,
+			output_tokens INTEGER NOT NULL,
+			expires_at TIMESTAMPTZ NOT NULL,
+			created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+		);`,
+		`CREATE INDEX IF NOT EXISTS ai_response_cache_expires ON ai_response_cache (expires_at);`,
 		`CREATE TABLE IF NOT EXISTS review_feedback (
 			id SERIAL PRIMARY KEY,
 			repo TEXT NOT NULL,
@@ -181,16 +352,175 @@ func (p *PostgresStore) SaveAnalysisResults(ctx context.Context, prID int64, iss
 	}
 	defer tx.Rollback()
 
-	stmt, err := tx.PrepareContext(ctx, `INSERT INTO analysis_results (pr_id, file, issue_type, severity, message, line) VALUES ($1, $2, $3, $4, $5, $6)`)
+	stmt, err := tx.PrepareContext(ctx, `INSERT INTO analysis_results (pr_id, file, issue_type, severity, message, line, prompt_version) VALUES ($1, $2, $3, $4, $5, $6, $7)`)
+	if err != nil {
+		return err
+	}
+	defer stmt.Close()
+
+	for _, issue := range issues {
+		if _, err := stmt.ExecContext(ctx, prID, issue.File, issue.RuleID, issue.Severity, issue.Message, issue.Line, issue.PromptVersion); err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+func (p *PostgresStore) SaveExternalFindings(ctx context.Context, repo string, number int, sha string, source string, issues []analysis.Issue) error {
+	tx, err := p.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.ExecContext(ctx, `DELETE FROM external_findings WHERE repo = $1 AND pr_number = $2 AND commit_sha = $3 AND source = $4`, repo, number, sha, source); err != nil {
+		return err
+	}
+
+	stmt, err := tx.PrepareContext(ctx, `INSERT INTO external_findings (repo, pr_number, commit_sha, source, file, rule_id, severity, message, suggestion, line) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)`)
 	if err != nil {
 		return err
 	}
 	defer stmt.Close()
 
 	for _, issue := range issues {
-		if _, err := stmt.ExecContext(ctx, prID, issue.File, issue.RuleID, issue.Severity, issue.Message, issue.Line); err != nil {
+		if _, err := stmt.ExecContext(ctx, repo, number, sha, source, issue.File, issue.RuleID, issue.Severity, issue.Message, issue.Suggestion, issue.Line); err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+func (p *PostgresStore) ListExternalFindings(ctx context.Context, repo string, number int, sha string) ([]analysis.Issue, error) {
+	rows, err := p.db.QueryContext(ctx, `
+		SELECT source, file, rule_id, severity, message, suggestion, line
+		FROM external_findings
+		WHERE repo = $1 AND pr_number = $2 AND commit_sha = $3
+		ORDER BY source, id`, repo, number, sha)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var issues []analysis.Issue
+	for rows.Next() {
+		var issue analysis.Issue
+		if err := rows.Scan(&issue.Source, &issue.File, &issue.RuleID, &issue.Severity, &issue.Message, &issue.Suggestion, &issue.Line); err != nil {
+			return nil, err
+		}
+		issues = append(issues, issue)
+	}
+	return issues, rows.Err()
+}
+
+func (p *PostgresStore) SaveFunctionMetrics(ctx context.Context, prID int64, sha string, changes []analysis.ComplexityChange) error {
+	if len(changes) == 0 {
+		return nil
+	}
+	tx, err := p.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	stmt, err := tx.PrepareContext(ctx, `INSERT INTO function_metrics (pr_id, commit_sha, file, function_name, line, cyclomatic, cognitive, base_cyclomatic, base_cognitive) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)`)
+	if err != nil {
+		return err
+	}
+	defer stmt.Close()
+
+	for _, change := range changes {
+		var baseCyclomatic, baseCognitive sql.NullInt64
+		if !change.IsNew {
+			baseCyclomatic = sql.NullInt64{Int64: int64(change.BaseCyclomatic), Valid: true}
+			baseCognitive = sql.NullInt64{Int64: int64(change.BaseCognitive), Valid: true}
+		}
+		if _, err := stmt.ExecContext(ctx, prID, sha, change.File, change.Name, change.Line, change.Cyclomatic, change.Cognitive, baseCyclomatic, baseCognitive); err != nil {
 			return err
 		}
 	}
 	return tx.Commit()
 }
+
+func (p *PostgresStore) SaveAIUsage(ctx context.Context, prID int64, records []ai.UsageRecord) error {
+	if len(records) == 0 {
+		return nil
+	}
+	tx, err := p.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	stmt, err := tx.PrepareContext(ctx, `INSERT INTO ai_usage (pr_id, repo, pr_number, commit_sha, provider, model, input_tokens, output_tokens, cost_usd, findings_checked, findings_dropped) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11)`)
+	if err != nil {
+		return err
+	}
+	defer stmt.Close()
+
+	for _, record := range records {
+		if _, err := stmt.ExecContext(ctx, prID, record.Repo, record.PullNumber, record.CommitSHA, record.Provider, record.Model, record.InputTokens, record.OutputTokens, record.CostUSD, record.FindingsChecked, record.FindingsDropped); err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+func (p *PostgresStore) SumAIUsage(ctx context.Context, query ai.UsageQuery) (ai.UsageTotals, error) {
+	var from, to sql.NullTime
+	if !query.From.IsZero() {
+		from = sql.NullTime{Time: query.From, Valid: true}
+	}
+	if !query.To.IsZero() {
+		to = sql.NullTime{Time: query.To, Valid: true}
+	}
+	var totals ai.UsageTotals
+	err := p.db.QueryRowContext(ctx, `
+		SELECT COUNT(*), COALESCE(SUM(input_tokens), 0), COALESCE(SUM(output_tokens), 0), COALESCE(SUM(cost_usd), 0)::FLOAT8,
+			COALESCE(SUM(findings_checked), 0), COALESCE(SUM(findings_dropped), 0)
+		FROM ai_usage
+		WHERE repo = $1
+			AND ($2 = 0 OR pr_number = $2)
+			AND ($3::TIMESTAMPTZ IS NULL OR created_at >= $3)
+			AND ($4::TIMESTAMPTZ IS NULL OR created_at < $4)`,
+		query.Repo, query.PullNumber, from, to).Scan(&totals.Calls, &totals.InputTokens, &totals.OutputTokens, &totals.CostUSD, &totals.FindingsChecked, &totals.FindingsDropped)
+	return totals, err
+}
+
+func (p *PostgresStore) GetAIResponse(ctx context.Context, key string) (ai.CompletionResponse, bool, error) {
+	var response ai.CompletionResponse
+	err := p.db.QueryRowContext(ctx, `
+		SELECT model, content, input_tokens, output_tokens
+		FROM ai_response_cache
+		WHERE cache_key = $1 AND expires_at > NOW()`, key).
+		Scan(&response.Model, &response.Content, &response.Usage.InputTokens, &response.Usage.OutputTokens)
+	if errors.Is(err, sql.ErrNoRows) {
+		return ai.CompletionResponse{}, false, nil
+	}
+	if err != nil {
+		return ai.CompletionResponse{}, false, err
+	}
+	return response, true, nil
+}
+
+func (p *PostgresStore) PutAIResponse(ctx context.Context, key string, response ai.CompletionResponse, expiresAt time.Time) error {
+	tx, err := p.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.ExecContext(ctx, `DELETE FROM ai_response_cache WHERE expires_at <= NOW()`); err != nil {
+		return err
+	}
+	if _, err := tx.ExecContext(ctx, `
+		INSERT INTO ai_response_cache (cache_key, model, content, input_tokens, output_tokens, expires_at)
+		VALUES ($1, $2, $3, $4, $5, $6)
+		ON CONFLICT (cache_key)
+		DO UPDATE SET model = EXCLUDED.model, content = EXCLUDED.content, input_tokens = EXCLUDED.input_tokens,
+			output_tokens = EXCLUDED.output_tokens, expires_at = EXCLUDED.expires_at, created_at = NOW()`,
+		key, response.Model, response.Content, response.Usage.InputTokens, response.Usage.OutputTokens, expiresAt); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
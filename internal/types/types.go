@@ -1,5 +1,7 @@
 package types
 
+import "time"
+
 type AnalyzeRequest struct {
 	Repository string `json:"repository"`
 	PullNumber int    `json:"pull_number"`
@@ -18,3 +20,33 @@ type HealthResponse struct {
 type WebhookResponse struct {
 	Status string `json:"status"`
 }
+
+type IngestResponse struct {
+	Status   string `json:"status"`
+	Source   string `json:"source"`
+	Accepted int    `json:"accepted"`
+}
+
+type VulnDBRefreshResponse struct {
+	Status     string    `json:"status"`
+	Source     string    `json:"source"`
+	Advisories int       `json:"advisories"`
+	Packages   int       `json:"packages"`
+	Skipped    int       `json:"skipped"`
+	LoadedAt   time.Time `json:"loaded_at"`
+}
+
+type AIUsageResponse struct {
+	Repository       string  `json:"repository"`
+	PullNumber       int     `json:"pull_number,omitempty"`
+	Month            string  `json:"month,omitempty"`
+	Calls            int     `json:"calls"`
+	InputTokens      int     `json:"input_tokens"`
+	OutputTokens     int     `json:"output_tokens"`
+	CostUSD          float64 `json:"cost_usd"`
+	FindingsChecked  int     `json:"findings_checked"`
+	FindingsDropped  int     `json:"findings_dropped"`
+	FindingsDropRate float64 `json:"findings_drop_rate"`
+	MonthToDateUSD   float64 `json:"month_to_date_usd"`
+	MonthlyBudgetUSD float64 `json:"monthly_budget_usd,omitempty"`
+}
@@ -0,0 +1,90 @@
+package vulndb
+
+import (
+	"fmt"
+	"math"
+	"strings"
+)
+
+var (
+	cvssAttackVector      = map[string]float64{"N": 0.85, "A": 0.62, "L": 0.55, "P": 0.2}
+	cvssAttackComplexity  = map[string]float64{"L": 0.77, "H": 0.44}
+	cvssUserInteraction   = map[string]float64{"N": 0.85, "R": 0.62}
+	cvssImpact            = map[string]float64{"H": 0.56, "L": 0.22, "N": 0}
+	cvssPrivilegesUnset   = map[string]float64{"N": 0.85, "L": 0.62, "H": 0.27}
+	cvssPrivilegesChanged = map[string]float64{"N": 0.85, "L": 0.68, "H": 0.5}
+)
+
+// cvss3BaseScore computes the CVSS v3.x base score from a vector such as
+// "CVSS:3.1/AV:N/AC:L/PR:N/UI:N/S:U/C:H/I:H/A:H".
+func cvss3BaseScore(vector string) (float64, error) {
+	parts := strings.Split(vector, "/")
+	if len(parts) == 0 || !strings.HasPrefix(parts[0], "CVSS:3") {
+		return 0, fmt.Errorf("unsupported CVSS vector %q", vector)
+	}
+	metrics := map[string]string{}
+	for _, part := range parts[1:] {
+		key, value, ok := strings.Cut(part, ":")
+		if ok {
+			metrics[key] = value
+		}
+	}
+
+	lookup := func(table map[string]float64, key string) (float64, bool) {
+		value, ok := table[metrics[key]]
+		return value, ok
+	}
+	av, ok1 := lookup(cvssAttackVector, "AV")
+	ac, ok2 := lookup(cvssAttackComplexity, "AC")
+	ui, ok3 := lookup(cvssUserInteraction, "UI")
+	c, ok4 := lookup(cvssImpact, "C")
+	i, ok5 := lookup(cvssImpact, "I")
+	a, ok6 := lookup(cvssImpact, "A")
+	changed := metrics["S"] == "C"
+	privileges := cvssPrivilegesUnset
+	if changed {
+		privileges = cvssPrivilegesChanged
+	}
+	pr, ok7 := lookup(privileges, "PR")
+	if !(ok1 && ok2 && ok3 && ok4 && ok5 && ok6 && ok7) || (metrics["S"] != "U" && !changed) {
+		return 0, fmt.Errorf("incomplete CVSS vector %q", vector)
+	}
+
+	iss := 1 - (1-c)*(1-i)*(1-a)
+	impact := 6.42 * iss
+	if changed {
+		impact = 7.52*(iss-0.029) - 3.25*math.Pow(iss-0.02, 15)
+	}
+	if impact <= 0 {
+		return 0, nil
+	}
+	exploitability := 8.22 * av * ac * pr * ui
+	if changed {
+		return roundUp(math.Min(1.08*(impact+exploitability), 10)), nil
+	}
+	return roundUp(math.Min(impact+exploitability, 10)), nil
+}
+
+// roundUp is the CVSS v3.1 Roundup function: the smallest one-decimal number
+// not below the input, computed on integers to avoid float artifacts.
+func roundUp(value float64) float64 {
+	scaled := int(math.Round(value * 100000))
+	if scaled%10000 == 0 {
+		return float64(scaled) / 100000
+	}
+	return float64(scaled/10000+1) / 10
+}
+
+func cvssRating(score float64) string {
+	switch {
+	case score >= 9:
+		return "CRITICAL"
+	case score >= 7:
+		return "HIGH"
+	case score >= 4:
+		return "MEDIUM"
+	case score > 0:
+		return "LOW"
+	}
+	return "NONE"
+}
@@ -0,0 +1,166 @@
+package vulndb
+
+import (
+	"encoding/json"
+	"sort"
+	"strings"
+)
+
+// Advisory is the subset of the OSV schema (https://ossf.github.io/osv-schema/)
+// needed to match package versions.
+type Advisory struct {
+	ID               string           `json:"id"`
+	Aliases          []string         `json:"aliases"`
+	Summary          string           `json:"summary"`
+	Details          string           `json:"details"`
+	Withdrawn        string           `json:"withdrawn"`
+	Severity         []SeverityScore  `json:"severity"`
+	Affected         []Affected       `json:"affected"`
+	DatabaseSpecific DatabaseSpecific `json:"database_specific"`
+}
+
+type SeverityScore struct {
+	Type  string `json:"type"`
+	Score string `json:"score"`
+}
+
+type Affected struct {
+	Package          Package          `json:"package"`
+	Ranges           []Range          `json:"ranges"`
+	Versions         []string         `json:"versions"`
+	Severity         []SeverityScore  `json:"severity"`
+	DatabaseSpecific DatabaseSpecific `json:"database_specific"`
+}
+
+type Package struct {
+	Ecosystem string `json:"ecosystem"`
+	Name      string `json:"name"`
+}
+
+type Range struct {
+	Type   string  `json:"type"`
+	Events []Event `json:"events"`
+}
+
+type Event struct {
+	Introduced   string `json:"introduced"`
+	Fixed        string `json:"fixed"`
+	LastAffected string `json:"last_affected"`
+	Limit        string `json:"limit"`
+}
+
+// DatabaseSpecific only carries the free-form severity label that GitHub and
+// several other databases publish (e.g. "HIGH" or "MODERATE").
+type DatabaseSpecific struct {
+	Severity string `json:"severity"`
+}
+
+func (d *DatabaseSpecific) UnmarshalJSON(data []byte) error {
+	var raw map[string]json.RawMessage
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return nil
+	}
+	if severity, ok := raw["severity"]; ok {
+		_ = json.Unmarshal(severity, &d.Severity)
+	}
+	return nil
+}
+
+// decodeAdvisories accepts a single OSV document or an array of them.
+func decodeAdvisories(data []byte) ([]Advisory, error) {
+	trimmed := strings.TrimSpace(string(data))
+	if strings.HasPrefix(trimmed, "[") {
+		var advisories []Advisory
+		if err := json.Unmarshal(data, &advisories); err != nil {
+			return nil, err
+		}
+		return advisories, nil
+	}
+	var advisory Advisory
+	if err := json.Unmarshal(data, &advisory); err != nil {
+		return nil, err
+	}
+	return []Advisory{advisory}, nil
+}
+
+// FixedVersions lists the versions that close the affected ranges.
+func (a Affected) FixedVersions() []string {
+	var fixed []string
+	for _, r := range a.Ranges {
+		for _, event := range r.Events {
+			if event.Fixed != "" {
+				fixed = append(fixed, event.Fixed)
+			}
+		}
+	}
+	return fixed
+}
+
+// affects walks each range's events sorted by version, since OSV does not
+// require them to be listed in order: introduced opens a vulnerable span,
+// fixed and last_affected close it. GIT ranges are commit based and cannot
+// be compared with release versions, so they are skipped.
+func (a Affected) affects(version string, compare func(string, string) int) bool {
+	for _, listed := range a.Versions {
+		if compare(listed, version) == 0 {
+			return true
+		}
+	}
+	for _, r := range a.Ranges {
+		if r.Type == "GIT" {
+			continue
+		}
+		affected := false
+		for _, event := range sortedEvents(r.Events, compare) {
+			switch {
+			case event.Introduced != "":
+				if event.Introduced == "0" || compare(version, event.Introduced) >= 0 {
+					affected = true
+				}
+			case event.Fixed != "":
+				if compare(version, event.Fixed) >= 0 {
+					affected = false
+				}
+			case event.LastAffected != "":
+				if compare(version, event.LastAffected) > 0 {
+					affected = false
+				}
+			case event.Limit != "":
+				if compare(version, event.Limit) >= 0 {
+					affected = false
+				}
+			}
+		}
+		if affected {
+			return true
+		}
+	}
+	return false
+}
+
+// version returns the version an event refers to.
+func (e Event) version() string {
+	switch {
+	case e.Introduced != "":
+		return e.Introduced
+	case e.Fixed != "":
+		return e.Fixed
+	case e.LastAffected != "":
+		return e.LastAffected
+	}
+	return e.Limit
+}
+
+// sortedEvents orders events by version, with an introduced of "0" (the
+// start of history) first.
+func sortedEvents(events []Event, compare func(string, string) int) []Event {
+	sorted := append([]Event(nil), events...)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		a, b := sorted[i].version(), sorted[j].version()
+		if a == "0" || b == "0" {
+			return a == "0" && b != "0"
+		}
+		return compare(a, b) < 0
+	})
+	return sorted
+}
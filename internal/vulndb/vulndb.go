@@ -0,0 +1,376 @@
+package vulndb
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/example/pr-ai-teammate/internal/analysis"
+	"github.com/example/pr-ai-teammate/internal/deps"
+)
+
+var ErrNotConfigured = errors.New("vulnerability database not configured")
+
+// ecosystems maps deps ecosystems to OSV ecosystem names.
+var ecosystems = map[string]string{
+	"go":   "Go",
+	"npm":  "npm",
+	"pypi": "PyPI",
+}
+
+type Stats struct {
+	Source     string    `json:"source"`
+	Advisories int       `json:"advisories"`
+	Packages   int       `json:"packages"`
+	Skipped    int       `json:"skipped"`
+	LoadedAt   time.Time `json:"loaded_at"`
+}
+
+// Database is an in-memory index of OSV advisories loaded from a local
+// directory, a single JSON file, or a .zip/.tar.gz archive such as the
+// per-ecosystem all.zip exports. It never touches the network.
+type Database struct {
+	source string
+
+	mu    sync.RWMutex
+	index map[string][]indexed
+	stats Stats
+}
+
+type indexed struct {
+	advisory *Advisory
+	affected Affected
+}
+
+func New(source string) *Database {
+	return &Database{source: source, index: map[string][]indexed{}}
+}
+
+// Refresh reloads the database from its source. On failure the previously
+// loaded advisories stay in place.
+func (d *Database) Refresh() (Stats, error) {
+	if d == nil || d.source == "" {
+		return Stats{}, ErrNotConfigured
+	}
+	index := map[string][]indexed{}
+	stats := Stats{Source: d.source}
+	add := func(name string, data []byte) {
+		if !strings.HasSuffix(strings.ToLower(name), ".json") {
+			return
+		}
+		advisories, err := decodeAdvisories(data)
+		if err != nil {
+			stats.Skipped++
+			return
+		}
+		for i := range advisories {
+			advisory := &advisories[i]
+			if advisory.ID == "" || advisory.Withdrawn != "" {
+				continue
+			}
+			stats.Advisories++
+			for _, affected := range advisory.Affected {
+				key := packageKey(affected.Package.Ecosystem, affected.Package.Name)
+				index[key] = append(index[key], indexed{advisory: advisory, affected: affected})
+			}
+		}
+	}
+	if err := readSource(d.source, add); err != nil {
+		return Stats{}, fmt.Errorf("load vulnerability database %s: %w", d.source, err)
+	}
+	stats.Packages = len(index)
+	stats.LoadedAt = time.Now().UTC()
+
+	d.mu.Lock()
+	d.index = index
+	d.stats = stats
+	d.mu.Unlock()
+	return stats, nil
+}
+
+func (d *Database) Stats() Stats {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+	return d.stats
+}
+
+func readSource(source string, add func(name string, data []byte)) error {
+	info, err := os.Stat(source)
+	if err != nil {
+		return err
+	}
+	if info.IsDir() {
+		return filepath.WalkDir(source, func(filePath string, entry fs.DirEntry, err error) error {
+			if err != nil || entry.IsDir() {
+				return err
+			}
+			if isArchive(filePath) {
+				return readSource(filePath, add)
+			}
+			data, err := os.ReadFile(filePath)
+			if err != nil {
+				return err
+			}
+			add(filePath, data)
+			return nil
+		})
+	}
+
+	lower := strings.ToLower(source)
+	switch {
+	case strings.HasSuffix(lower, ".zip"):
+		return readZip(source, add)
+	case strings.HasSuffix(lower, ".tar.gz"), strings.HasSuffix(lower, ".tgz"):
+		return readTarGz(source, add)
+	}
+	data, err := os.ReadFile(source)
+	if err != nil {
+		return err
+	}
+	add(source, data)
+	return nil
+}
+
+func isArchive(filePath string) bool {
+	lower := strings.ToLower(filePath)
+	return strings.HasSuffix(lower, ".zip") || strings.HasSuffix(lower, ".tar.gz") || strings.HasSuffix(lower, ".tgz")
+}
+
+func readZip(source string, add func(name string, data []byte)) error {
+	archive, err := zip.OpenReader(source)
+	if err != nil {
+		return err
+	}
+	defer archive.Close()
+	for _, file := range archive.File {
+		if file.FileInfo().IsDir() {
+			continue
+		}
+		reader, err := file.Open()
+		if err != nil {
+			return err
+		}
+		data, err := io.ReadAll(reader)
+		reader.Close()
+		if err != nil {
+			return err
+		}
+		add(file.Name, data)
+	}
+	return nil
+}
+
+func readTarGz(source string, add func(name string, data []byte)) error {
+	file, err := os.Open(source)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	gz, err := gzip.NewReader(file)
+	if err != nil {
+		return err
+	}
+	defer gz.Close()
+	archive := tar.NewReader(gz)
+	for {
+		header, err := archive.Next()
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if header.Typeflag != tar.TypeReg {
+			continue
+		}
+		data, err := io.ReadAll(archive)
+		if err != nil {
+			return err
+		}
+		add(header.Name, data)
+	}
+}
+
+type Match struct {
+	Advisory Advisory
+	Severity string
+	Fixed    []string
+}
+
+// Lookup returns advisories affecting an exact package version. The
+// ecosystem is a deps ecosystem ("go", "npm", "pypi").
+func (d *Database) Lookup(ecosystem string, name string, version string) []Match {
+	osvEcosystem, ok := ecosystems[ecosystem]
+	if !ok {
+		return nil
+	}
+	version = normalizeVersion(version)
+	if version == "" {
+		return nil
+	}
+
+	d.mu.RLock()
+	candidates := d.index[packageKey(osvEcosystem, name)]
+	d.mu.RUnlock()
+
+	var matches []Match
+	seen := map[string]bool{}
+	for _, candidate := range candidates {
+		if seen[candidate.advisory.ID] || !candidate.affected.affects(version, deps.CompareVersions) {
+			continue
+		}
+		seen[candidate.advisory.ID] = true
+		matches = append(matches, Match{
+			Advisory: *candidate.advisory,
+			Severity: severityLabel(*candidate.advisory, candidate.affected),
+			Fixed:    candidate.affected.FixedVersions(),
+		})
+	}
+	sort.Slice(matches, func(i, j int) bool { return matches[i].Advisory.ID < matches[j].Advisory.ID })
+	return matches
+}
+
+// Check reports advisories for every added or changed dependency. A change
+// is checked at the version the PR's lockfile resolves it to when there is
+// one, otherwise at its pinned version. A range without a resolved version
+// is checked at its lower bound, which the range admits; ranges without an
+// admitted lower bound, such as "<4" or "*", are not checked.
+func (d *Database) Check(changes []deps.Change) []analysis.Issue {
+	if d == nil {
+		return nil
+	}
+	var issues []analysis.Issue
+	for _, change := range changes {
+		if change.Kind == deps.ChangeRemoved || change.To == "" {
+			continue
+		}
+		version, isRange := change.Resolved, false
+		if version == "" {
+			version, isRange = specVersion(change.To)
+		}
+		for _, match := range d.Lookup(change.Ecosystem, change.Name, version) {
+			issues = append(issues, matchIssue(change, version, isRange, match))
+		}
+	}
+	return issues
+}
+
+func matchIssue(change deps.Change, version string, isRange bool, match Match) analysis.Issue {
+	advisory := match.Advisory
+	ids := append([]string{advisory.ID}, advisory.Aliases...)
+	summary := advisory.Summary
+	if summary == "" {
+		summary = firstLine(advisory.Details)
+	}
+	var message string
+	switch {
+	case isRange:
+		message = fmt.Sprintf("%s %s admits vulnerable version %s, affected by %s (severity %s)", change.Name, change.To, version, strings.Join(ids, ", "), match.Severity)
+	case change.Resolved != "" && change.Resolved != change.To:
+		message = fmt.Sprintf("%s %s (resolved to %s) is affected by %s (severity %s)", change.Name, change.To, version, strings.Join(ids, ", "), match.Severity)
+	default:
+		message = fmt.Sprintf("%s %s is affected by %s (severity %s)", change.Name, change.To, strings.Join(ids, ", "), match.Severity)
+	}
+	if summary != "" {
+		message += ": " + summary
+	}
+	suggestion := "No fixed version is published yet; consider an alternative package or mitigating the vulnerable code path."
+	switch {
+	case len(match.Fixed) > 0 && isRange:
+		suggestion = fmt.Sprintf("Raise the lower bound to a fixed version: %s.", strings.Join(match.Fixed, ", "))
+	case len(match.Fixed) > 0:
+		suggestion = fmt.Sprintf("Upgrade to a fixed version: %s.", strings.Join(match.Fixed, ", "))
+	}
+	return analysis.Issue{
+		File:       change.File,
+		Line:       change.Line,
+		RuleID:     "vulnerable-dependency",
+		Severity:   "high",
+		Message:    message,
+		Suggestion: suggestion,
+	}
+}
+
+func severityLabel(advisory Advisory, affected Affected) string {
+	scores := append(append([]SeverityScore{}, affected.Severity...), advisory.Severity...)
+	for _, score := range scores {
+		if !strings.HasPrefix(score.Type, "CVSS_V3") {
+			continue
+		}
+		if value, err := cvss3BaseScore(score.Score); err == nil {
+			return fmt.Sprintf("%s %.1f", cvssRating(value), value)
+		}
+	}
+	for _, label := range []string{affected.DatabaseSpecific.Severity, advisory.DatabaseSpecific.Severity} {
+		if label != "" {
+			return strings.ToUpper(label)
+		}
+	}
+	return "UNKNOWN"
+}
+
+func packageKey(ecosystem string, name string) string {
+	if ecosystem == "PyPI" {
+		name = strings.ToLower(strings.NewReplacer("_", "-", ".", "-").Replace(name))
+	}
+	return ecosystem + "|" + name
+}
+
+// lowerBoundOperators admit the version they are followed by.
+var lowerBoundOperators = []string{"^", "~=", "~", ">="}
+
+// specVersion returns the version a dependency spec is checked at. An exact
+// pin ("1.2.3", "==1.2.3") is returned as is. For a range it returns a lower
+// bound the range admits, such as 4.17.0 for "^4.17.0" or ">=4.17.0,<5", and
+// reports that it is a range; a range with no admitted lower bound returns
+// "".
+func specVersion(spec string) (string, bool) {
+	spec = strings.TrimSpace(spec)
+	if !strings.ContainsAny(spec, "^~<>!*, |") && !strings.HasSuffix(spec, ".x") {
+		return spec, false
+	}
+	if from, _, ok := strings.Cut(spec, " - "); ok && !strings.Contains(from, "||") {
+		// npm hyphen range: "1.2.3 - 2.0.0" includes both ends.
+		return strings.TrimSpace(from), true
+	}
+	for _, part := range strings.FieldsFunc(spec, func(r rune) bool { return r == ',' || r == '|' || r == ' ' }) {
+		for _, operator := range lowerBoundOperators {
+			if version, ok := strings.CutPrefix(part, operator); ok && !strings.ContainsAny(version, "*xX") {
+				return strings.TrimSpace(version), true
+			}
+		}
+	}
+	return "", true
+}
+
+// normalizeVersion strips an exact pin's "==" or "=" and the Go "v" prefix,
+// since OSV records Go versions without it. Anything else that is not a
+// plain version, such as a range, is not looked up.
+func normalizeVersion(version string) string {
+	version = strings.TrimSpace(version)
+	version = strings.TrimLeft(version, "= ")
+	version = strings.TrimPrefix(version, "v")
+	version = strings.TrimSuffix(version, "+incompatible")
+	if version == "" || version[0] < '0' || version[0] > '9' || strings.ContainsAny(version, "^~<>!*, |") {
+		return ""
+	}
+	return version
+}
+
+func firstLine(text string) string {
+	text = strings.TrimSpace(text)
+	if idx := strings.IndexByte(text, '\n'); idx >= 0 {
+		return strings.TrimSpace(text[:idx])
+	}
+	return text
+}
@@ -0,0 +1,213 @@
+package vulndb
+
+import (
+	"archive/zip"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/example/pr-ai-teammate/internal/deps"
+)
+
+const goAdvisory = `{
+  "id": "GO-2023-0001",
+  "aliases": ["CVE-2023-1234"],
+  "summary": "Path traversal in archive extraction",
+  "severity": [{"type": "CVSS_V3", "score": "CVSS:3.1/AV:N/AC:L/PR:N/UI:N/S:U/C:H/I:H/A:H"}],
+  "affected": [{
+    "package": {"ecosystem": "Go", "name": "github.com/acme/archive"},
+    "ranges": [{"type": "SEMVER", "events": [{"introduced": "0"}, {"fixed": "1.4.2"}, {"introduced": "2.0.0"}, {"fixed": "2.0.3"}]}]
+  }]
+}`
+
+const pypiAdvisories = `[{
+  "id": "GHSA-xxxx-yyyy-zzzz",
+  "summary": "SQL injection in QuerySet.annotate",
+  "database_specific": {"severity": "MODERATE", "cwe_ids": ["CWE-89"]},
+  "affected": [{
+    "package": {"ecosystem": "PyPI", "name": "Django"},
+    "ranges": [{"type": "ECOSYSTEM", "events": [{"introduced": "4.2"}, {"last_affected": "4.2.6"}]}]
+  }]
+}, {
+  "id": "PYSEC-WITHDRAWN",
+  "withdrawn": "2024-01-01T00:00:00Z",
+  "affected": [{"package": {"ecosystem": "PyPI", "name": "django"}, "versions": ["4.2.5"]}]
+}]`
+
+func TestDatabaseFromDirectory(t *testing.T) {
+	dir := t.TempDir()
+	writeFile(t, filepath.Join(dir, "go", "GO-2023-0001.json"), goAdvisory)
+	writeFile(t, filepath.Join(dir, "pypi.json"), pypiAdvisories)
+	writeFile(t, filepath.Join(dir, "broken.json"), "{")
+	writeFile(t, filepath.Join(dir, "README.md"), "not an advisory")
+
+	db := New(dir)
+	stats, err := db.Refresh()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if stats.Advisories != 2 || stats.Packages != 2 || stats.Skipped != 1 {
+		t.Fatalf("unexpected stats: %+v", stats)
+	}
+
+	cases := []struct {
+		ecosystem, name, version string
+		want                     int
+	}{
+		{"go", "github.com/acme/archive", "v1.4.1", 1},
+		{"go", "github.com/acme/archive", "v1.4.2", 0},
+		{"go", "github.com/acme/archive", "v2.0.1", 1},
+		{"go", "github.com/acme/archive", "v2.0.3", 0},
+		{"pypi", "django", "==4.2.5", 1},
+		{"pypi", "django", "==4.2.7", 0},
+		{"npm", "github.com/acme/archive", "1.0.0", 0},
+	}
+	for _, tc := range cases {
+		if got := db.Lookup(tc.ecosystem, tc.name, tc.version); len(got) != tc.want {
+			t.Fatalf("Lookup(%s, %s, %s) = %d matches, want %d", tc.ecosystem, tc.name, tc.version, len(got), tc.want)
+		}
+	}
+
+	issues := db.Check([]deps.Change{
+		{Ecosystem: "go", Name: "github.com/acme/archive", From: "v1.3.0", To: "v1.4.0", Kind: deps.ChangeUpgraded, File: "go.mod", Line: 7},
+		{Ecosystem: "pypi", Name: "django", From: "4.2.5", Kind: deps.ChangeRemoved, File: "requirements.txt"},
+	})
+	if len(issues) != 1 {
+		t.Fatalf("expected 1 issue, got %+v", issues)
+	}
+	issue := issues[0]
+	if issue.File != "go.mod" || issue.Line != 7 || issue.Severity != "high" || issue.RuleID != "vulnerable-dependency" {
+		t.Fatalf("unexpected issue: %+v", issue)
+	}
+	for _, want := range []string{"GO-2023-0001", "CVE-2023-1234", "CRITICAL 9.8", "Path traversal"} {
+		if !strings.Contains(issue.Message, want) {
+			t.Fatalf("expected %q in message %q", want, issue.Message)
+		}
+	}
+	if !strings.Contains(issue.Suggestion, "1.4.2") {
+		t.Fatalf("expected fixed version in suggestion, got %q", issue.Suggestion)
+	}
+}
+
+const npmAdvisory = `{
+  "id": "GHSA-35jh-r3h4-6jhm",
+  "summary": "Command injection in lodash",
+  "affected": [{
+    "package": {"ecosystem": "npm", "name": "lodash"},
+    "ranges": [{"type": "SEMVER", "events": [{"introduced": "0"}, {"fixed": "4.17.21"}]}]
+  }]
+}`
+
+func TestCheckRangesAndResolvedVersions(t *testing.T) {
+	dir := t.TempDir()
+	writeFile(t, filepath.Join(dir, "lodash.json"), npmAdvisory)
+	db := New(dir)
+	if _, err := db.Refresh(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	change := deps.Change{Ecosystem: "npm", Name: "lodash", To: "^4.17.0", Kind: deps.ChangeAdded, File: "package.json", Line: 4}
+	issues := db.Check([]deps.Change{change})
+	if len(issues) != 1 || !strings.Contains(issues[0].Message, "^4.17.0 admits vulnerable version 4.17.0") {
+		t.Fatalf("expected the range's lower bound to be reported, got %+v", issues)
+	}
+
+	change.Resolved = "4.17.21"
+	if issues := db.Check([]deps.Change{change}); len(issues) != 0 {
+		t.Fatalf("expected the locked fixed version to pass, got %+v", issues)
+	}
+	change.Resolved = "4.17.20"
+	if issues := db.Check([]deps.Change{change}); len(issues) != 1 || !strings.Contains(issues[0].Message, "(resolved to 4.17.20)") {
+		t.Fatalf("expected the locked version to be reported, got %+v", issues)
+	}
+
+	for _, spec := range []string{"<4", "*", "4.x", ">4.0.0"} {
+		change := deps.Change{Ecosystem: "npm", Name: "lodash", To: spec, Kind: deps.ChangeAdded, File: "package.json", Line: 4}
+		if issues := db.Check([]deps.Change{change}); len(issues) != 0 {
+			t.Fatalf("%s: expected a range without an admitted lower bound to be skipped, got %+v", spec, issues)
+		}
+	}
+}
+
+func TestAffectsSortsEvents(t *testing.T) {
+	// Multi-branch advisories often list every fix before the next branch's
+	// introduced event.
+	affected := Affected{Ranges: []Range{{Type: "SEMVER", Events: []Event{
+		{Fixed: "1.4.2"}, {Fixed: "2.0.3"}, {Introduced: "2.0.0"}, {Introduced: "0"},
+	}}}}
+	for version, want := range map[string]bool{
+		"1.0.0": true,
+		"1.4.2": false,
+		"1.9.0": false,
+		"2.0.1": true,
+		"2.0.3": false,
+	} {
+		if got := affected.affects(version, deps.CompareVersions); got != want {
+			t.Errorf("affects(%s) = %v, want %v", version, got, want)
+		}
+	}
+}
+
+func TestDatabaseFromZipKeepsDataOnFailedRefresh(t *testing.T) {
+	archivePath := filepath.Join(t.TempDir(), "all.zip")
+	file, err := os.Create(archivePath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	writer := zip.NewWriter(file)
+	entry, err := writer.Create("GO-2023-0001.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := entry.Write([]byte(goAdvisory)); err != nil {
+		t.Fatal(err)
+	}
+	if err := writer.Close(); err != nil {
+		t.Fatal(err)
+	}
+	file.Close()
+
+	db := New(archivePath)
+	if _, err := db.Refresh(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(db.Lookup("go", "github.com/acme/archive", "v1.0.0")) != 1 {
+		t.Fatalf("expected advisory from zip archive")
+	}
+
+	if err := os.Remove(archivePath); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := db.Refresh(); err == nil {
+		t.Fatalf("expected refresh error for missing archive")
+	}
+	if len(db.Lookup("go", "github.com/acme/archive", "v1.0.0")) != 1 {
+		t.Fatalf("expected previous advisories to survive a failed refresh")
+	}
+}
+
+func TestCVSS3BaseScore(t *testing.T) {
+	cases := map[string]float64{
+		"CVSS:3.1/AV:N/AC:L/PR:N/UI:N/S:U/C:H/I:H/A:H": 9.8,
+		"CVSS:3.1/AV:N/AC:L/PR:N/UI:R/S:C/C:L/I:L/A:N": 6.1,
+		"CVSS:3.0/AV:L/AC:H/PR:H/UI:R/S:U/C:L/I:N/A:N": 1.8,
+		"CVSS:3.1/AV:N/AC:L/PR:N/UI:N/S:U/C:N/I:N/A:N": 0,
+	}
+	for vector, want := range cases {
+		got, err := cvss3BaseScore(vector)
+		if err != nil || got != want {
+			t.Fatalf("cvss3BaseScore(%s) = %v, %v; want %v", vector, got, err, want)
+		}
+	}
+}
+
+func writeFile(t *testing.T, filePath string, content string) {
+	t.Helper()
+	if err := os.MkdirAll(filepath.Dir(filePath), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filePath, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+}